@@ -33,7 +33,7 @@ go mod tidy
 # ビルド
 Write-Host ""
 Write-Host "🔨 Building audio engine..." -ForegroundColor Yellow
-go build -o audio_engine.exe main.go
+go build -o audio_engine.exe .
 
 if ($LASTEXITCODE -eq 0) {
     Write-Host ""
@@ -44,7 +44,9 @@ try {
 }
 
 Write-Host "`n🔨 Building audio engine..." -ForegroundColor Green
-go build -o audio_engine.exe main.go
+# 💡 main.go だけでなくパッケージ全体（portaudio.go など）をビルドする
+# PortAudio のない環境（file / null 出力だけ）なら: go build -tags headless -o audio_engine.exe .
+go build -o audio_engine.exe .
 
 if ($LASTEXITCODE -eq 0) {
     Write-Host "`n✅ Build successful!" -ForegroundColor Green
@@ -28,6 +28,49 @@ function generateTone(filename, frequency, duration, sampleRate = 44100) {
     console.log(`✅ Generated: ${filepath}`);
 }
 
+// クリックトラック（BPM・ビートグリッド検出の確認用）
+// 小節頭は高く強いクリック、それ以外の拍は低く弱いクリック
+// offset: 最初の拍の位置（秒）
+function generateClickTrack(filename, bpm, duration, offset = 0, sampleRate = 44100) {
+    const numSamples = Math.floor(sampleRate * duration);
+    const samples = new Int16Array(numSamples); // モノラル
+    const interval = 60 / bpm;
+    const clickLength = Math.floor(sampleRate * 0.03);
+
+    for (let beat = 0; ; beat++) {
+        const start = Math.round((offset + beat * interval) * sampleRate);
+        if (start >= numSamples) break;
+
+        const isDownbeat = beat % 4 === 0;
+        const frequency = isDownbeat ? 1500 : 1000;
+        const amplitude = isDownbeat ? 0.8 : 0.4;
+
+        for (let i = 0; i < clickLength && start + i < numSamples; i++) {
+            const t = i / sampleRate;
+            const envelope = Math.exp(-t * 150); // 短く減衰
+            samples[start + i] = Math.floor(32767 * amplitude * envelope * Math.sin(2 * Math.PI * frequency * t));
+        }
+    }
+
+    const wav = new WaveFile();
+    wav.fromScratch(1, sampleRate, '16', samples);
+
+    const filepath = path.join(testdataDir, filename);
+    fs.writeFileSync(filepath, wav.toBuffer());
+    console.log(`✅ Generated: ${filepath}`);
+}
+
+// ビット深度ごとの確認用（モノラル5サンプル：正の最大、負の最大、0、+半分、-半分）
+// 💡 8bit は符号なし（無音が128）
+function generateBitDepth(filename, bitDepth, samples, sampleRate = 44100) {
+    const wav = new WaveFile();
+    wav.fromScratch(1, sampleRate, bitDepth, samples);
+
+    const filepath = path.join(testdataDir, filename);
+    fs.writeFileSync(filepath, wav.toBuffer());
+    console.log(`✅ Generated: ${filepath}`);
+}
+
 console.log('🎵 Generating test WAV files...\n');
 
 // テスト用の音を生成
@@ -35,4 +78,18 @@ generateTone('tone_440hz.wav', 440, 5);  // A4音、5秒
 generateTone('tone_523hz.wav', 523, 5);  // C5音、5秒
 generateTone('tone_261hz.wav', 261, 5);  // C4音、5秒
 
+// BPM検出用のクリックトラック
+generateClickTrack('click_120bpm.wav', 120, 16);          // 120 BPM、最初の拍が先頭
+generateClickTrack('click_128bpm.wav', 128, 16, 0.25);    // 128 BPM、0.25秒遅れて開始
+generateClickTrack('click_174bpm.wav', 174, 16, 0.1);     // 174 BPM（ドラムンベース）
+generateClickTrack('click_93.5bpm.wav', 93.5, 16, 0.37);  // 小数のテンポ
+
+// WAVのビット深度・フォーマットごとの確認用
+generateBitDepth('pcm_8bit.wav', '8', [255, 0, 128, 192, 64]);
+generateBitDepth('pcm_16bit.wav', '16', [32767, -32768, 0, 16384, -16384]);
+generateBitDepth('pcm_24bit.wav', '24', [8388607, -8388608, 0, 4194304, -4194304]);
+generateBitDepth('pcm_32bit.wav', '32', [2147483647, -2147483648, 0, 1073741824, -1073741824]);
+generateBitDepth('float_32bit.wav', '32f', [1, -1, 0, 0.5, -0.5]);
+generateBitDepth('float_64bit.wav', '64', [1, -1, 0, 0.5, -0.5]);
+
 console.log('\n✅ All test files created in testdata/');
\ No newline at end of file
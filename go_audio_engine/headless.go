@@ -0,0 +1,48 @@
+//go:build headless
+
+package main
+
+// PortAudio なしのビルド（go build -tags headless）
+//
+// 解説：file / null 出力だけなら cgo も libportaudio もいらないので、サウンドカードのない
+// サーバーや CI ではこちらでビルドする。サウンドカード出力（-backend portaudio）を選ぶと起動時にエラーになる
+
+import (
+	"errors"
+
+	"go_audio_engine/pkg/output"
+)
+
+var errNoPortAudio = errors.New("built without PortAudio (-tags headless): use -backend file or null")
+
+func initPortAudio() error {
+	return errNoPortAudio
+}
+
+func terminatePortAudio() {}
+
+// listDevices はデバイスを一覧できないので空の一覧を返す
+func listDevices() ([]map[string]interface{}, error) {
+	return []map[string]interface{}{}, nil
+}
+
+// portAudioBackend はサウンドカード出力の代わり（開こうとするとエラー）
+type portAudioBackend struct {
+	config StreamConfig
+}
+
+func newPortAudioBackend(config StreamConfig, mixerRate int) *portAudioBackend {
+	return &portAudioBackend{config: config}
+}
+
+func (b *portAudioBackend) Start(render output.RenderFunc) error {
+	return errNoPortAudio
+}
+
+func (b *portAudioBackend) Stop() error {
+	return nil
+}
+
+func (b *portAudioBackend) Status() map[string]interface{} {
+	return map[string]interface{}{"backend": "portaudio", "error": errNoPortAudio.Error()}
+}
@@ -2,28 +2,59 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
+	"go_audio_engine/pkg/audio"
 	"go_audio_engine/pkg/mixer"
+	"go_audio_engine/pkg/output"
+	"go_audio_engine/pkg/recorder"
 
-	"github.com/gordonklaus/portaudio"
 	"github.com/gorilla/websocket"
 )
 
 const (
-	sampleRate      = 44100
-	channels        = 2
-	framesPerBuffer = 512
+	defaultSampleRate      = 44100
+	defaultFramesPerBuffer = 512
+	channels               = 2
 )
 
+// 出力バックエンドの種類
+const (
+	backendPortAudio = "portaudio" // サウンドカード
+	backendFile      = "file"      // WAVファイル
+	backendNull      = "null"      // どこにも出さない
+)
+
+// EngineConfig は起動時の設定
+type EngineConfig struct {
+	DeckCount  int
+	SampleRate int    // ミキサーの処理レート（起動後は変えられない）
+	Backend    string // backendPortAudio, backendFile, backendNull
+
+	Output StreamConfig // サウンドカードの設定（portaudio のとき）
+
+	OutputFile string        // 書き出し先（file のとき）
+	BitDepth   int           // 16, 24 または 32（file のとき）
+	Realtime   bool          // 実時間で回す（file / null のとき。false ならできるだけ速く）
+	Duration   time.Duration // 書き出す長さ（file のとき。0 なら止めるまで）
+}
+
 type AudioEngine struct {
-	stream *portaudio.Stream
-	mixer  *mixer.DJMixer
+	mixer *mixer.DJMixer
+
+	mu        sync.Mutex     // 出力の開き直しを直列化する
+	backend   output.Backend // 今の出力先（開き直しに失敗したときは nil）
+	portAudio bool           // PortAudio を初期化したか
 }
 
 type LoadRequest struct {
@@ -68,44 +99,205 @@ func (c *Client) safeWrite(messageType int, data []byte) error {
 
 // ---------------------------------------------------------
 
-func NewAudioEngine() (*AudioEngine, error) {
-	if err := portaudio.Initialize(); err != nil {
-		return nil, fmt.Errorf("failed to initialize PortAudio: %v", err)
+func NewAudioEngine(config EngineConfig) (*AudioEngine, error) {
+	if config.SampleRate <= 0 {
+		config.SampleRate = defaultSampleRate
+	}
+	if config.Output.SampleRate <= 0 {
+		config.Output.SampleRate = config.SampleRate
+	}
+	if config.Output.FramesPerBuffer <= 0 {
+		config.Output.FramesPerBuffer = defaultFramesPerBuffer
 	}
-
-	djMixer := mixer.NewDJMixer(sampleRate)
 
 	engine := &AudioEngine{
-		mixer: djMixer,
+		mixer: mixer.NewDJMixer(config.SampleRate, config.DeckCount),
 	}
 
-	stream, err := portaudio.OpenDefaultStream(
-		0,
-		channels,
-		float64(sampleRate),
-		framesPerBuffer,
-		func(out []float32) {
-			engine.mixer.Mix(out)
-		},
-	)
-	if err != nil {
-		portaudio.Terminate()
-		return nil, fmt.Errorf("failed to open stream: %v", err)
+	clock := output.Clock{
+		SampleRate:      config.SampleRate,
+		FramesPerBuffer: config.Output.FramesPerBuffer,
+		Realtime:        config.Realtime,
 	}
 
-	engine.stream = stream
-
-	if err := stream.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start stream: %v", err)
+	switch config.Backend {
+	case backendPortAudio, "":
+		if err := validateStreamConfig(config.Output); err != nil {
+			return nil, err
+		}
+		if err := initPortAudio(); err != nil {
+			return nil, err
+		}
+		engine.portAudio = true
+		engine.backend = newPortAudioBackend(config.Output, config.SampleRate)
+	case backendFile:
+		if config.OutputFile == "" {
+			return nil, fmt.Errorf("output file required for the file backend")
+		}
+		frames := int64(config.Duration.Seconds() * float64(config.SampleRate))
+		engine.backend = output.NewFileBackend(config.OutputFile, config.BitDepth, clock, frames)
+	case backendNull:
+		engine.backend = output.NewNullBackend(clock)
+	default:
+		return nil, fmt.Errorf("unknown output backend: %q (portaudio, file or null)", config.Backend)
 	}
 
 	return engine, nil
 }
 
+// Start は出力を始める（ここからミキサーが回る）
+func (ae *AudioEngine) Start() error {
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+
+	return ae.backend.Start(ae.mixer.MixWithCue)
+}
+
+// Wait はファイル出力が指定の長さを書き終わるまで待つ（ファイル出力以外はすぐ戻る）
+func (ae *AudioEngine) Wait() error {
+	ae.mu.Lock()
+	fileBackend, ok := ae.backend.(*output.FileBackend)
+	ae.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return fileBackend.Wait()
+}
+
+// Reconfigure はサウンドカードの出力ストリームを新しい設定で開き直す
+// 解説：ミキサー（デッキ）には触らず、ストリームだけを閉じて開き直すので、
+// 読み込んだ曲・再生位置・キューポイントなどはそのまま残る。
+// 新しい設定で開けなければ、元の設定で開き直してエラーを返す
+func (ae *AudioEngine) Reconfigure(config StreamConfig) error {
+	if err := validateStreamConfig(config); err != nil {
+		return err
+	}
+
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+
+	if !ae.portAudio {
+		return fmt.Errorf("output backend cannot be reconfigured (not a sound card)")
+	}
+
+	var previous *StreamConfig
+	if current, ok := ae.backend.(*portAudioBackend); ok {
+		previous = &current.config
+		current.Stop()
+		ae.backend = nil
+	}
+
+	backend := newPortAudioBackend(config, ae.mixer.SampleRate())
+	err := backend.Start(ae.mixer.MixWithCue)
+	if err == nil {
+		ae.backend = backend
+		return nil
+	}
+
+	if previous != nil {
+		log.Printf("❌ Failed to reopen output (%v), restoring previous settings", err)
+		restored := newPortAudioBackend(*previous, ae.mixer.SampleRate())
+		if restoreErr := restored.Start(ae.mixer.MixWithCue); restoreErr == nil {
+			ae.backend = restored
+		} else {
+			log.Printf("❌ Failed to restore output: %v", restoreErr)
+		}
+	}
+	return err
+}
+
+// UsesPortAudio はサウンドカード（PortAudio）に出力するエンジンか
+// 💡 起動時に決まり、以後変わらない
+func (ae *AudioEngine) UsesPortAudio() bool {
+	return ae.portAudio
+}
+
+// OutputConfig は今のサウンドカードの設定を返す（サウンドカードに出していなければ false）
+func (ae *AudioEngine) OutputConfig() (StreamConfig, bool) {
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+
+	if backend, ok := ae.backend.(*portAudioBackend); ok {
+		return backend.config, true
+	}
+	return StreamConfig{}, false
+}
+
+// OutputStatus は出力の状態を返す
+func (ae *AudioEngine) OutputStatus() map[string]interface{} {
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+
+	status := map[string]interface{}{}
+	if ae.backend != nil {
+		status = ae.backend.Status()
+	}
+	status["running"] = ae.backend != nil
+	status["mixerSampleRate"] = ae.mixer.SampleRate()
+	return status
+}
+
 func (ae *AudioEngine) Close() {
-	ae.stream.Stop()
-	ae.stream.Close()
-	portaudio.Terminate()
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+
+	// 💡 録音中ならファイルを閉じてから止める（ヘッダーを書かないと再生できないファイルになる）
+	if ae.mixer.Recorder.IsRecording() {
+		if err := ae.mixer.Recorder.Stop(); err != nil {
+			log.Printf("❌ Failed to finish recording: %v", err)
+		}
+	}
+
+	if ae.backend != nil {
+		if err := ae.backend.Stop(); err != nil {
+			log.Printf("❌ Failed to close output: %v", err)
+		}
+		ae.backend = nil
+	}
+	if ae.portAudio {
+		terminatePortAudio()
+	}
+}
+
+// deckFiles は -play a=track.wav の形で指定されたデッキとファイル
+type deckFiles []string
+
+func (d *deckFiles) String() string {
+	return strings.Join(*d, ",")
+}
+
+func (d *deckFiles) Set(value string) error {
+	if _, _, ok := strings.Cut(value, "="); !ok {
+		return fmt.Errorf("expected deck=file, got %q", value)
+	}
+	*d = append(*d, value)
+	return nil
+}
+
+// playFiles は起動時に指定されたファイルをデッキに読み込んで再生する
+// 💡 出力を始める前に読み込むので、ファイル出力では最初のサンプルから曲が入る
+func playFiles(m *mixer.DJMixer, files deckFiles, detectBPM bool) error {
+	for _, value := range files {
+		idText, file, _ := strings.Cut(value, "=")
+		id, err := mixer.ParseDeckID(idText)
+		if err != nil {
+			return err
+		}
+		deck := m.Deck(id)
+		if deck == nil {
+			return fmt.Errorf("deck %s does not exist", id)
+		}
+		if err := deck.Load(file); err != nil {
+			return fmt.Errorf("deck %s: %v", id, err)
+		}
+		if detectBPM {
+			go deck.DetectBPMAsync()
+		}
+		deck.Play()
+		log.Printf("▶️ Deck %s: %s", id, file)
+	}
+	return nil
 }
 
 // enableCORS: 標準的な http.Handler ラッパーとして実装
@@ -127,7 +319,25 @@ func enableCORS(next http.Handler) http.Handler {
 	})
 }
 
-// 💡 リファクタリング: Deck A と B の共通ハンドラを作成
+// 💡 リファクタリング: 全デッキ共通のハンドラを作成
+
+// deckHandler は、URL の {id}（"a", "b", ...）からデッキを探してハンドラに渡します。
+// 存在しないデッキなら 404 を返します。
+func deckHandler(m *mixer.DJMixer, handle func(w http.ResponseWriter, r *http.Request, id mixer.DeckID, deck *audio.Track)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := mixer.ParseDeckID(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		deck := m.Deck(id)
+		if deck == nil {
+			http.Error(w, fmt.Sprintf("deck %s does not exist", id), http.StatusNotFound)
+			return
+		}
+		handle(w, r, id, deck)
+	}
+}
 
 // deckActionHandler は、引数なしでデッキを操作するアクション（play, pause, stop）のためのハンドラを生成します。
 func deckActionHandler(action func()) http.HandlerFunc {
@@ -166,7 +376,7 @@ func deckLoadHandler(mixer *mixer.DJMixer, deckID mixer.DeckID) http.HandlerFunc
 			return
 		}
 
-		log.Printf("⏳ Deck %d: Starting ASYNC WAV loading for: %s", deckID, req.File)
+		log.Printf("⏳ Deck %s: Starting ASYNC track loading for: %s", deckID, req.File)
 		go mixer.LoadTrackAsync(deckID, req.File)
 
 		w.Header().Set("Content-Type", "application/json")
@@ -191,12 +401,51 @@ func deckRequestHandler[T any](action func(T)) http.HandlerFunc {
 }
 
 func main() {
-	engine, err := NewAudioEngine()
+	var config EngineConfig
+	flag.IntVar(&config.DeckCount, "decks", 4, "number of decks (1-8)")
+	flag.IntVar(&config.SampleRate, "rate", defaultSampleRate, "sample rate of the mixer and the output device")
+	flag.IntVar(&config.Output.Device, "device", -1, "output device id (see /api/devices, -1 for the default device)")
+	flag.IntVar(&config.Output.FramesPerBuffer, "buffer", defaultFramesPerBuffer, "frames per buffer of the output stream")
+	flag.BoolVar(&config.Output.CueQuad, "cue-quad", false, "open a 4-channel stream and send the headphone cue to channels 3/4")
+	flag.IntVar(&config.Output.CueDevice, "cue-device", -1, "device id (see /api/devices) for the headphone cue output")
+	flag.StringVar(&config.Backend, "backend", backendPortAudio, "output backend: portaudio, file or null")
+	flag.StringVar(&config.OutputFile, "out", "mix.wav", "WAV file to write (file backend)")
+	flag.IntVar(&config.BitDepth, "bits", 24, "WAV bit depth: 16, 24 or 32 (float) (file backend)")
+	flag.BoolVar(&config.Realtime, "realtime", true, "run the file/null backend in real time (false renders as fast as possible and exits)")
+	flag.DurationVar(&config.Duration, "duration", 0, "length to write with the file backend (0 = until stopped)")
+	var files deckFiles
+	flag.Var(&files, "play", "load and play a file on start, as deck=file (repeatable)")
+	flag.Parse()
+
+	engine, err := NewAudioEngine(config)
 	if err != nil {
 		log.Fatal("Failed to create audio engine:", err)
 	}
 	defer engine.Close()
 
+	// 💡 オフラインレンダー：サーバーは起動せず、実時間より速くファイルに書き出して終わる
+	offline := config.Backend == backendFile && !config.Realtime
+	if offline && config.Duration <= 0 {
+		log.Fatal("-duration is required when rendering offline (-realtime=false)")
+	}
+
+	if err := playFiles(engine.mixer, files, !offline); err != nil {
+		log.Fatal("Failed to load files:", err)
+	}
+	if err := engine.Start(); err != nil {
+		log.Fatal("Failed to start audio output:", err)
+	}
+
+	if offline {
+		started := time.Now()
+		if err := engine.Wait(); err != nil {
+			log.Fatal("Offline render failed:", err)
+		}
+		engine.Close()
+		log.Printf("✅ Rendered %s (%.1fs of audio in %.2fs)", config.OutputFile, config.Duration.Seconds(), time.Since(started).Seconds())
+		return
+	}
+
 	// 新しいマルチプレクサ(ルーター)を作成
 	mux := http.NewServeMux()
 
@@ -279,11 +528,10 @@ func main() {
 	// 🎛️ HTTP API Endpoints (アクション)
 	// =======================================================
 
-	// ... (Deck A, Deck B, Mixer API ハンドラは変更なし。省略) ...
+	// ========== Deck API ==========
+	// 💡 すべてのデッキで共通。{id} は "a", "b", "c", ...（デッキの数は -decks で指定）
 
-	// ========== Deck A API ==========
-
-	mux.HandleFunc("/api/deck/a/load", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/deck/{id}/load", deckHandler(engine.mixer, func(w http.ResponseWriter, r *http.Request, id mixer.DeckID, deck *audio.Track) {
 		if r.Method != "POST" {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -291,16 +539,16 @@ func main() {
 
 		var req LoadRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			log.Printf("❌ Deck A load: JSON decode error: %v", err)
+			log.Printf("❌ Deck %s load: JSON decode error: %v", id, err)
 			http.Error(w, "Invalid request body (JSON decode failed)", http.StatusBadRequest)
 			return
 		}
 
 		file := req.File
-		log.Printf("📥 Deck A load request received: %s", file)
+		log.Printf("📥 Deck %s load request received: %s", id, file)
 
 		if file == "" {
-			log.Printf("❌ Deck A load: file parameter missing in JSON")
+			log.Printf("❌ Deck %s load: file parameter missing in JSON", id)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]string{"error": "file parameter required"})
@@ -308,46 +556,46 @@ func main() {
 		}
 
 		if _, err := os.Stat(file); os.IsNotExist(err) {
-			log.Printf("❌ Deck A load: file not found: %s", file)
+			log.Printf("❌ Deck %s load: file not found: %s", id, file)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(map[string]string{"error": "file not found"})
 			return
 		}
 
-		log.Printf("⏳ Deck A: Starting ASYNC WAV loading for: %s", file)
+		log.Printf("⏳ Deck %s: Starting ASYNC track loading for: %s", id, file)
 
 		// 💡 修正: デッドロックを避けるため、チャンネル経由で安全にロード処理を依頼する
 		// LoadTrackAsync は mixer パッケージ側での実装が必要になります。
 		// このメソッドは内部でゴルーチンを起動し、ファイルのデコードを行い、
 		// デコード結果をチャンネル経由でオーディオ処理ループに安全に渡します。
-		go engine.mixer.LoadTrackAsync(mixer.DeckA, file) // 💡 修正: DeckAを識別する定数を渡す
+		go engine.mixer.LoadTrackAsync(id, file)
 
 		// 💡 修正: HTTP 応答は即座に返す (この修正を維持)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusAccepted) // 202 Accepted
 		json.NewEncoder(w).Encode(map[string]string{"status": "loading started asynchronously", "file": file})
-	})
+	}))
 
-	mux.HandleFunc("/api/deck/a/play", func(w http.ResponseWriter, r *http.Request) {
-		engine.mixer.DeckA.Play()
+	mux.HandleFunc("/api/deck/{id}/play", deckHandler(engine.mixer, func(w http.ResponseWriter, r *http.Request, id mixer.DeckID, deck *audio.Track) {
+		deck.Play()
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"status": "playing"})
-	})
+	}))
 
-	mux.HandleFunc("/api/deck/a/pause", func(w http.ResponseWriter, r *http.Request) {
-		engine.mixer.DeckA.Pause()
+	mux.HandleFunc("/api/deck/{id}/pause", deckHandler(engine.mixer, func(w http.ResponseWriter, r *http.Request, id mixer.DeckID, deck *audio.Track) {
+		deck.Pause()
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"status": "paused"})
-	})
+	}))
 
-	mux.HandleFunc("/api/deck/a/stop", func(w http.ResponseWriter, r *http.Request) {
-		engine.mixer.DeckA.Stop()
+	mux.HandleFunc("/api/deck/{id}/stop", deckHandler(engine.mixer, func(w http.ResponseWriter, r *http.Request, id mixer.DeckID, deck *audio.Track) {
+		deck.Stop()
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"status": "stopped"})
-	})
+	}))
 
-	mux.HandleFunc("/api/deck/a/seek", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/deck/{id}/seek", deckHandler(engine.mixer, func(w http.ResponseWriter, r *http.Request, id mixer.DeckID, deck *audio.Track) {
 		var req struct {
 			Position float64 `json:"position"`
 		}
@@ -356,15 +604,15 @@ func main() {
 			return
 		}
 
-		engine.mixer.DeckA.Seek(req.Position)
+		deck.Seek(req.Position)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"status":   "seeked",
 			"position": req.Position,
 		})
-	})
+	}))
 
-	mux.HandleFunc("/api/deck/a/volume", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/deck/{id}/volume", deckHandler(engine.mixer, func(w http.ResponseWriter, r *http.Request, id mixer.DeckID, deck *audio.Track) {
 		var req struct {
 			Volume float64 `json:"volume"`
 		}
@@ -373,102 +621,100 @@ func main() {
 			return
 		}
 
-		engine.mixer.DeckA.SetVolume(req.Volume)
+		// 💡 デッキの音量はチャンネルフェーダー（曲を差し替えても値が残る）
+		strip := engine.mixer.Strip(id)
+		strip.SetFader(req.Volume)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"status": "ok",
-			"volume": req.Volume,
+			"volume": strip.GetFader(),
 		})
-	})
+	}))
 
-	mux.HandleFunc("/api/deck/a/eq", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/deck/{id}/trim", deckHandler(engine.mixer, func(w http.ResponseWriter, r *http.Request, id mixer.DeckID, deck *audio.Track) {
 		var req struct {
-			Low  float64 `json:"low"`
-			Mid  float64 `json:"mid"`
-			High float64 `json:"high"`
+			DB float64 `json:"db"` // -24 ～ +12
 		}
-
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		engine.mixer.DeckA.EQ.SetLow(req.Low)
-		engine.mixer.DeckA.EQ.SetMid(req.Mid)
-		engine.mixer.DeckA.EQ.SetHigh(req.High)
-
+		strip := engine.mixer.Strip(id)
+		strip.SetTrim(req.DB)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"status": "ok",
-			"eq":     req,
+			"db":     strip.GetTrim(),
 		})
-	})
+	}))
 
-	mux.HandleFunc("/api/deck/a/filter", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/deck/{id}/fader", deckHandler(engine.mixer, func(w http.ResponseWriter, r *http.Request, id mixer.DeckID, deck *audio.Track) {
 		var req struct {
-			Type      string  `json:"type"`
-			Cutoff    float64 `json:"cutoff"`
-			Resonance float64 `json:"resonance"`
+			Value float64 `json:"value"` // 0.0 ～ 1.0
 		}
-
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		switch req.Type {
-		case "lowpass":
-			engine.mixer.DeckA.Filter.SetLowpass(req.Cutoff, req.Resonance)
-		case "highpass":
-			engine.mixer.DeckA.Filter.SetHighpass(req.Cutoff, req.Resonance)
-		case "none":
-			engine.mixer.DeckA.Filter.Reset()
-		}
-
+		strip := engine.mixer.Strip(id)
+		strip.SetFader(req.Value)
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
-	})
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "ok",
+			"value":  strip.GetFader(),
+		})
+	}))
 
-	mux.HandleFunc("/api/deck/a/speed", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/deck/{id}/crossfader", deckHandler(engine.mixer, func(w http.ResponseWriter, r *http.Request, id mixer.DeckID, deck *audio.Track) {
 		var req struct {
-			Speed float64 `json:"speed"`
+			Assign string `json:"assign"` // "A", "B", "THRU"
 		}
-
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		engine.mixer.DeckA.SetSpeed(req.Speed)
-
+		assign, err := mixer.ParseCrossfaderAssign(req.Assign)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		engine.mixer.Strip(id).SetAssign(assign)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"status": "ok",
-			"speed":  req.Speed,
+			"assign": assign.String(),
 		})
-	})
+	}))
 
-	mux.HandleFunc("/api/deck/a/cuepoint/add", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/deck/{id}/pfl", deckHandler(engine.mixer, func(w http.ResponseWriter, r *http.Request, id mixer.DeckID, deck *audio.Track) {
 		var req struct {
-			Name  string `json:"name"`
-			Color string `json:"color"`
+			Enabled bool `json:"enabled"`
 		}
-
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		engine.mixer.DeckA.AddCuePoint(req.Name, req.Color)
-
+		engine.mixer.Strip(id).SetPFL(req.Enabled)
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{"status": "added"})
-	})
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":  "ok",
+			"enabled": req.Enabled,
+		})
+	}))
 
-	mux.HandleFunc("/api/deck/a/loop/set", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/deck/{id}/eq", deckHandler(engine.mixer, func(w http.ResponseWriter, r *http.Request, id mixer.DeckID, deck *audio.Track) {
+		// 💡 送られてきた項目だけを変える
 		var req struct {
-			Start float64 `json:"start"`
-			End   float64 `json:"end"`
+			Low      *float64 `json:"low"`      // -1.0 ～ 1.0（classic は ±12dB、isolator は -1.0 でキル）
+			Mid      *float64 `json:"mid"`      // 同上
+			High     *float64 `json:"high"`     // 同上
+			Mode     *string  `json:"mode"`     // "classic", "isolator"
+			LowFreq  *float64 `json:"lowFreq"`  // アイソレーターの低域と中域の境目（Hz）
+			HighFreq *float64 `json:"highFreq"` // アイソレーターの中域と高域の境目（Hz）
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -476,15 +722,62 @@ func main() {
 			return
 		}
 
-		engine.mixer.DeckA.CueManager.SetLoop(req.Start, req.End)
+		// 先にすべてチェックしてから反映する（途中でエラーになっても中途半端に変わらない）
+		mode := deck.EQ.GetMode()
+		if req.Mode != nil {
+			parsed, err := audio.ParseEQMode(*req.Mode)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			mode = parsed
+		}
+		if req.LowFreq != nil || req.HighFreq != nil {
+			lowFreq, highFreq := deck.EQ.GetCrossovers()
+			if req.LowFreq != nil {
+				lowFreq = *req.LowFreq
+			}
+			if req.HighFreq != nil {
+				highFreq = *req.HighFreq
+			}
+			if err := deck.EQ.SetCrossovers(lowFreq, highFreq); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		deck.EQ.SetMode(mode)
+		if req.Low != nil {
+			deck.EQ.SetLow(*req.Low)
+		}
+		if req.Mid != nil {
+			deck.EQ.SetMid(*req.Mid)
+		}
+		if req.High != nil {
+			deck.EQ.SetHigh(*req.High)
+		}
 
+		lowFreq, highFreq := deck.EQ.GetCrossovers()
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{"status": "loop set"})
-	})
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "ok",
+			"eq": map[string]interface{}{
+				"mode":     deck.EQ.GetMode().String(),
+				"low":      deck.EQ.GetLow(),
+				"mid":      deck.EQ.GetMid(),
+				"high":     deck.EQ.GetHigh(),
+				"lowFreq":  lowFreq,
+				"highFreq": highFreq,
+			},
+		})
+	}))
 
-	mux.HandleFunc("/api/deck/a/loop/enable", func(w http.ResponseWriter, r *http.Request) {
+	// 💡 以前の形式（type / cutoff / resonance）。意味も以前と同じ：cutoff 0.0 ～ 1.0 は 20Hz ～ 20kHz に直線、resonance 0.0 ～ 1.0 は Q 1 ～ 10
+	mux.HandleFunc("/api/deck/{id}/filter", deckHandler(engine.mixer, func(w http.ResponseWriter, r *http.Request, id mixer.DeckID, deck *audio.Track) {
 		var req struct {
-			Enabled bool `json:"enabled"`
+			Type      string  `json:"type"`
+			Cutoff    float64 `json:"cutoff"`
+			Resonance float64 `json:"resonance"`
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -492,122 +785,158 @@ func main() {
 			return
 		}
 
-		engine.mixer.DeckA.CueManager.EnableLoop(req.Enabled)
-
-		if req.Enabled {
-			engine.mixer.DeckA.CueManager.ActivateLoop()
+		switch req.Type {
+		case "lowpass":
+			deck.Filter.SetLowpass(req.Cutoff, req.Resonance)
+		case "highpass":
+			deck.Filter.SetHighpass(req.Cutoff, req.Resonance)
+		case "none":
+			deck.Filter.Reset()
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"status":  "ok",
-			"enabled": req.Enabled,
-		})
-	})
-
-	// ========== Deck B API ==========
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	}))
 
-	mux.HandleFunc("/api/deck/b/load", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != "POST" {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
+	// 💡 1つのつまみのDJフィルター（上の /filter は以前の type / cutoff 形式との互換用）
+	mux.HandleFunc("/api/deck/{id}/filter/color", deckHandler(engine.mixer, func(w http.ResponseWriter, r *http.Request, id mixer.DeckID, deck *audio.Track) {
+		// 💡 送られてきた項目だけを変える
+		var req struct {
+			Color     *float64 `json:"color"`     // -1.0（ローパス）～ 0（素通し）～ 1.0（ハイパス）
+			Resonance *float64 `json:"resonance"` // 0.0 ～ 1.0
 		}
 
-		var req LoadRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			log.Printf("❌ Deck B load: JSON decode error: %v", err)
-			http.Error(w, "Invalid request body (JSON decode failed)", http.StatusBadRequest)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		file := req.File
-		log.Printf("📥 Deck B load request received: %s", file)
-
-		if file == "" {
-			log.Printf("❌ Deck B load: file parameter missing in JSON")
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"error": "file parameter required"})
-			return
+		if req.Color != nil {
+			deck.Filter.SetColor(*req.Color)
 		}
-
-		if _, err := os.Stat(file); os.IsNotExist(err) {
-			log.Printf("❌ Deck B load: file not found: %s", file)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(map[string]string{"error": "file not found"})
-			return
+		if req.Resonance != nil {
+			deck.Filter.SetResonance(*req.Resonance)
 		}
 
-		log.Printf("⏳ Deck B: Starting ASYNC WAV loading for: %s", file)
-
-		// 💡 修正: デッドロックを避けるため、チャンネル経由で安全にロード処理を依頼する
-		// LoadTrackAsync は mixer パッケージ側での実装が必要になります。
-		go engine.mixer.LoadTrackAsync(mixer.DeckB, file) // 💡 修正: DeckBを識別する定数を渡す
-
-		// 💡 修正: HTTP 応答は即座に返す (この修正を維持)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusAccepted) // 202 Accepted
-		json.NewEncoder(w).Encode(map[string]string{"status": "loading started asynchronously", "file": file})
-	})
-
-	mux.HandleFunc("/api/deck/b/play", func(w http.ResponseWriter, r *http.Request) {
-		engine.mixer.DeckB.Play()
+		filter := deck.Filter.Settings()
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{"status": "playing"})
-	})
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "ok",
+			"filter": map[string]interface{}{
+				"color":     filter.Color,
+				"resonance": filter.Resonance,
+				"type":      filter.Type(),
+				"frequency": filter.Frequency(),
+			},
+		})
+	}))
+
+	// 💡 GET はラックの状態を返すだけ。POST は送られてきた項目だけを変える
+	mux.HandleFunc("/api/deck/{id}/effects", deckHandler(engine.mixer, func(w http.ResponseWriter, r *http.Request, id mixer.DeckID, deck *audio.Track) {
+		if r.Method == "POST" {
+			var req struct {
+				Effect  string             `json:"effect"`  // "echo", "reverb", "flanger", "phaser", "bitcrusher"
+				Enabled *bool              `json:"enabled"` // オン・オフ
+				Mix     *float64           `json:"mix"`     // dry/wet（0.0 ～ 1.0）
+				Params  map[string]float64 `json:"params"`  // つまみ（例：echo の "beats", "feedback"）
+				Order   []string           `json:"order"`   // かける順番（すべてのエフェクトを並べる）
+			}
 
-	mux.HandleFunc("/api/deck/b/pause", func(w http.ResponseWriter, r *http.Request) {
-		engine.mixer.DeckB.Pause()
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{"status": "paused"})
-	})
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 
-	mux.HandleFunc("/api/deck/b/stop", func(w http.ResponseWriter, r *http.Request) {
-		engine.mixer.DeckB.Stop()
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{"status": "stopped"})
-	})
+			// 先にすべてチェックしてから反映する（途中でエラーになっても中途半端に変わらない）
+			var slot *audio.EffectSlot
+			if req.Effect != "" {
+				found, err := deck.Effects.Slot(req.Effect)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				slot = found
+			}
+			if slot == nil && (req.Enabled != nil || req.Mix != nil || len(req.Params) > 0) {
+				http.Error(w, "effect is required", http.StatusBadRequest)
+				return
+			}
+			for name := range req.Params {
+				if slot.Param(name) == nil {
+					http.Error(w, fmt.Sprintf("unknown parameter for %s: %q", slot.Effect.Name(), name), http.StatusBadRequest)
+					return
+				}
+			}
+			if req.Order != nil {
+				if err := deck.Effects.SetOrder(req.Order); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
 
-	mux.HandleFunc("/api/deck/b/seek", func(w http.ResponseWriter, r *http.Request) {
-		var req struct {
-			Position float64 `json:"position"`
+			if slot != nil {
+				for name, value := range req.Params {
+					slot.Param(name).Set(value)
+				}
+				if req.Mix != nil {
+					slot.SetMix(*req.Mix)
+				}
+				if req.Enabled != nil {
+					slot.SetEnabled(*req.Enabled)
+				}
+			}
 		}
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
+
+		slots := deck.Effects.Slots()
+		effects := make([]map[string]interface{}, len(slots))
+		for i, slot := range slots {
+			params := make(map[string]interface{})
+			for _, p := range slot.Effect.Params() {
+				params[p.Name] = map[string]interface{}{
+					"value":   p.Get(),
+					"min":     p.Min,
+					"max":     p.Max,
+					"default": p.Default,
+				}
+			}
+			effects[i] = map[string]interface{}{
+				"name":    slot.Effect.Name(),
+				"enabled": slot.IsEnabled(),
+				"mix":     slot.GetMix(),
+				"params":  params,
+				"clamped": slot.Clamped(), // つまみの値どおりにかけられず、縮めているか
+			}
 		}
 
-		engine.mixer.DeckB.Seek(req.Position)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"status":   "seeked",
-			"position": req.Position,
+			"status":  "ok",
+			"effects": effects,
 		})
-	})
+	}))
 
-	mux.HandleFunc("/api/deck/b/volume", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/deck/{id}/speed", deckHandler(engine.mixer, func(w http.ResponseWriter, r *http.Request, id mixer.DeckID, deck *audio.Track) {
 		var req struct {
-			Volume float64 `json:"volume"`
+			Speed float64 `json:"speed"`
 		}
+
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		engine.mixer.DeckB.SetVolume(req.Volume)
+		deck.SetSpeed(req.Speed)
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"status": "ok",
-			"volume": req.Volume,
+			"speed":  req.Speed,
 		})
-	})
+	}))
 
-	mux.HandleFunc("/api/deck/b/eq", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/deck/{id}/pitch", deckHandler(engine.mixer, func(w http.ResponseWriter, r *http.Request, id mixer.DeckID, deck *audio.Track) {
 		var req struct {
-			Low  float64 `json:"low"`
-			Mid  float64 `json:"mid"`
-			High float64 `json:"high"`
+			Semitones float64 `json:"semitones"` // -12 ～ +12
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -615,22 +944,18 @@ func main() {
 			return
 		}
 
-		engine.mixer.DeckB.EQ.SetLow(req.Low)
-		engine.mixer.DeckB.EQ.SetMid(req.Mid)
-		engine.mixer.DeckB.EQ.SetHigh(req.High)
+		deck.PitchShift.SetSemitones(req.Semitones)
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"status": "ok",
-			"eq":     req,
+			"status":    "ok",
+			"semitones": deck.PitchShift.GetSemitones(),
 		})
-	})
+	}))
 
-	mux.HandleFunc("/api/deck/b/filter", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/deck/{id}/keylock", deckHandler(engine.mixer, func(w http.ResponseWriter, r *http.Request, id mixer.DeckID, deck *audio.Track) {
 		var req struct {
-			Type      string  `json:"type"`
-			Cutoff    float64 `json:"cutoff"`
-			Resonance float64 `json:"resonance"`
+			Enabled bool `json:"enabled"`
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -638,22 +963,18 @@ func main() {
 			return
 		}
 
-		switch req.Type {
-		case "lowpass":
-			engine.mixer.DeckB.Filter.SetLowpass(req.Cutoff, req.Resonance)
-		case "highpass":
-			engine.mixer.DeckB.Filter.SetHighpass(req.Cutoff, req.Resonance)
-		case "none":
-			engine.mixer.DeckB.Filter.Reset()
-		}
+		deck.SetKeyLock(req.Enabled)
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
-	})
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":  "ok",
+			"enabled": req.Enabled,
+		})
+	}))
 
-	mux.HandleFunc("/api/deck/b/speed", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/deck/{id}/interpolation", deckHandler(engine.mixer, func(w http.ResponseWriter, r *http.Request, id mixer.DeckID, deck *audio.Track) {
 		var req struct {
-			Speed float64 `json:"speed"`
+			Mode string `json:"mode"` // "linear", "hermite", "sinc"
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -661,16 +982,21 @@ func main() {
 			return
 		}
 
-		engine.mixer.DeckB.SetSpeed(req.Speed)
+		mode, err := audio.ParseInterpolation(req.Mode)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		deck.SetInterpolation(mode)
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"status": "ok",
-			"speed":  req.Speed,
+			"mode":   mode.String(),
 		})
-	})
+	}))
 
-	mux.HandleFunc("/api/deck/b/cuepoint/add", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/deck/{id}/cuepoint/add", deckHandler(engine.mixer, func(w http.ResponseWriter, r *http.Request, id mixer.DeckID, deck *audio.Track) {
 		var req struct {
 			Name  string `json:"name"`
 			Color string `json:"color"`
@@ -681,13 +1007,13 @@ func main() {
 			return
 		}
 
-		engine.mixer.DeckB.AddCuePoint(req.Name, req.Color)
+		deck.AddCuePoint(req.Name, req.Color)
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"status": "added"})
-	})
+	}))
 
-	mux.HandleFunc("/api/deck/b/loop/set", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/deck/{id}/loop/set", deckHandler(engine.mixer, func(w http.ResponseWriter, r *http.Request, id mixer.DeckID, deck *audio.Track) {
 		var req struct {
 			Start float64 `json:"start"`
 			End   float64 `json:"end"`
@@ -698,13 +1024,13 @@ func main() {
 			return
 		}
 
-		engine.mixer.DeckB.CueManager.SetLoop(req.Start, req.End)
+		deck.CueManager.SetLoop(req.Start, req.End)
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"status": "loop set"})
-	})
+	}))
 
-	mux.HandleFunc("/api/deck/b/loop/enable", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/deck/{id}/loop/enable", deckHandler(engine.mixer, func(w http.ResponseWriter, r *http.Request, id mixer.DeckID, deck *audio.Track) {
 		var req struct {
 			Enabled bool `json:"enabled"`
 		}
@@ -714,10 +1040,10 @@ func main() {
 			return
 		}
 
-		engine.mixer.DeckB.CueManager.EnableLoop(req.Enabled)
+		deck.CueManager.EnableLoop(req.Enabled)
 
 		if req.Enabled {
-			engine.mixer.DeckB.CueManager.ActivateLoop()
+			deck.CueManager.ActivateLoop()
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -725,24 +1051,59 @@ func main() {
 			"status":  "ok",
 			"enabled": req.Enabled,
 		})
-	})
+	}))
 
 	// ========== Mixer API ==========
 
 	mux.HandleFunc("/api/mixer/crossfader", func(w http.ResponseWriter, r *http.Request) {
+		// 💡 送られてきた項目だけ変更する（位置だけ送る従来のUIもそのまま使える）
 		var req struct {
-			Value float64 `json:"value"`
+			Value   *float64 `json:"value"`   // -1.0 (A) ～ 1.0 (B)
+			Curve   *string  `json:"curve"`   // "power", "linear", "dipless", "scratch"
+			Slope   *float64 `json:"slope"`   // 0.0 ～ 1.0
+			Reverse *bool    `json:"reverse"` // 左右反転（ハムスター）
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		engine.mixer.SetCrossfader(req.Value)
+		// 先にすべて検証してから変更する
+		curve := engine.mixer.GetCrossfaderCurve()
+		if req.Curve != nil {
+			var err error
+			if curve, err = mixer.ParseCrossfaderCurve(*req.Curve); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		// 💡 スロープはスクラッチカーブにしか効かないので、他のカーブでは受け付けない（効かない設定を黙って保存しない）
+		if req.Slope != nil && !curve.UsesSlope() {
+			http.Error(w, fmt.Sprintf("slope only applies to the %s curve (current curve: %s)", mixer.CurveScratch, curve), http.StatusBadRequest)
+			return
+		}
+
+		if req.Curve != nil {
+			engine.mixer.SetCrossfaderCurve(curve)
+		}
+		if req.Value != nil {
+			engine.mixer.SetCrossfader(*req.Value)
+		}
+		if req.Slope != nil {
+			engine.mixer.SetCrossfaderSlope(*req.Slope)
+		}
+		if req.Reverse != nil {
+			engine.mixer.SetCrossfaderReverse(*req.Reverse)
+		}
+
+		status := engine.mixer.GetStatus()
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"status": "ok",
-			"value":  req.Value,
+			"status":  "ok",
+			"value":   status["Crossfader"],
+			"curve":   status["CrossfaderCurve"],
+			"slope":   status["CrossfaderSlope"],
+			"reverse": status["CrossfaderReverse"],
 		})
 	})
 
@@ -763,6 +1124,58 @@ func main() {
 		})
 	})
 
+	mux.HandleFunc("/api/mixer/cue", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Mix    *float64 `json:"mix"`    // 0.0 (キューのみ) ～ 1.0 (マスターのみ)
+			Volume *float64 `json:"volume"` // ヘッドフォンの音量 0.0 ～ 1.0
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if req.Mix != nil {
+			engine.mixer.SetCueMix(*req.Mix)
+		}
+		if req.Volume != nil {
+			engine.mixer.SetHeadphoneVolume(*req.Volume)
+		}
+
+		status := engine.mixer.GetStatus()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "ok",
+			"mix":    status["CueMix"],
+			"volume": status["HeadphoneVolume"],
+		})
+	})
+
+	mux.HandleFunc("/api/mixer/limiter", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Ceiling *float64 `json:"ceiling"` // dB（-12 ～ 0）
+			Release *float64 `json:"release"` // ms（10 ～ 1000）
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		limiter := engine.mixer.Limiter
+		if req.Ceiling != nil {
+			limiter.SetCeiling(*req.Ceiling)
+		}
+		if req.Release != nil {
+			limiter.SetRelease(*req.Release)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":  "ok",
+			"ceiling": limiter.GetCeiling(),
+			"release": limiter.GetRelease(),
+		})
+	})
+
 	mux.HandleFunc("/api/mixer/sync", func(w http.ResponseWriter, r *http.Request) {
 		var req struct {
 			Enabled bool   `json:"enabled"`
@@ -774,7 +1187,10 @@ func main() {
 			return
 		}
 
-		engine.mixer.EnableSync(req.Enabled, req.Master)
+		if err := engine.mixer.EnableSync(req.Enabled, req.Master); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -784,30 +1200,153 @@ func main() {
 		})
 	})
 
+	// ========== Recorder API ==========
+
+	mux.HandleFunc("/api/recorder/start", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			Name       string  `json:"name"`       // recordings/ の下のファイル名（空なら set_日時.wav）
+			Format     string  `json:"format"`     // "wav", "flac"（空ならファイル名の拡張子から）
+			BitDepth   int     `json:"bitDepth"`   // 16, 24, 32（WAV のみ）
+			MaxBytes   int64   `json:"maxBytes"`   // 1ファイルの大きさの上限（0 なら分割しない）
+			MaxSeconds float64 `json:"maxSeconds"` // 1ファイルの長さの上限（0 なら分割しない）
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		formatName := req.Format
+		if formatName == "" {
+			formatName = strings.TrimPrefix(filepath.Ext(req.Name), ".")
+		}
+		format, err := recorder.ParseFormat(formatName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = engine.mixer.Recorder.Start(recorder.Options{
+			Name:        req.Name,
+			Format:      format,
+			BitDepth:    req.BitDepth,
+			MaxBytes:    req.MaxBytes,
+			MaxDuration: time.Duration(req.MaxSeconds * float64(time.Second)),
+		})
+		if err == recorder.ErrRecording || errors.Is(err, os.ErrExist) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(engine.mixer.Recorder.GetStatus())
+	})
+
+	mux.HandleFunc("/api/recorder/stop", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		err := engine.mixer.Recorder.Stop()
+		if err == recorder.ErrNotRecording {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(engine.mixer.Recorder.GetStatus())
+	})
+
+	mux.HandleFunc("/api/recorder/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(engine.mixer.Recorder.GetStatus())
+	})
+
 	// ⚠️ HTTPポーリング用のStatus API（WebSocketへの移行により、バックアップとして残すか削除可能）
 	mux.HandleFunc("/api/mixer/status", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(engine.mixer.GetStatus())
 	})
 
+	// ========== Output API ==========
+
+	mux.HandleFunc("/api/output", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			// 💡 送られてきた項目だけ変更し、出力ストリームを開き直す（デッキはそのまま）
+			var req struct {
+				Device          *int  `json:"device"`
+				SampleRate      *int  `json:"sampleRate"`
+				FramesPerBuffer *int  `json:"framesPerBuffer"`
+				CueQuad         *bool `json:"cueQuad"`
+				CueDevice       *int  `json:"cueDevice"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			config, ok := engine.OutputConfig()
+			if !ok {
+				config = StreamConfig{
+					Device:          -1,
+					SampleRate:      engine.mixer.SampleRate(),
+					FramesPerBuffer: defaultFramesPerBuffer,
+					CueDevice:       -1,
+				}
+			}
+			if req.Device != nil {
+				config.Device = *req.Device
+			}
+			if req.SampleRate != nil {
+				config.SampleRate = *req.SampleRate
+			}
+			if req.FramesPerBuffer != nil {
+				config.FramesPerBuffer = *req.FramesPerBuffer
+			}
+			if req.CueQuad != nil {
+				config.CueQuad = *req.CueQuad
+			}
+			if req.CueDevice != nil {
+				config.CueDevice = *req.CueDevice
+			}
+
+			if err := engine.Reconfigure(config); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(engine.OutputStatus())
+	})
+
 	// ========== Utility API ==========
 
 	mux.HandleFunc("/api/devices", func(w http.ResponseWriter, r *http.Request) {
-		devices, err := portaudio.Devices()
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		// 💡 file / null 出力では PortAudio を初期化していないので、デバイスは一覧しない（空の一覧を返す）
+		if !engine.UsesPortAudio() {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode([]map[string]interface{}{})
 			return
 		}
 
-		deviceList := make([]map[string]interface{}, len(devices))
-		for i, device := range devices {
-			deviceList[i] = map[string]interface{}{
-				"id":                i,
-				"name":              device.Name,
-				"maxInputChannels":  device.MaxInputChannels,
-				"maxOutputChannels": device.MaxOutputChannels,
-				"defaultSampleRate": device.DefaultSampleRate,
-			}
+		deviceList, err := listDevices()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -818,26 +1357,30 @@ func main() {
 	fmt.Println("═══════════════════════════════════════════════════════")
 	fmt.Println("Server running on http://localhost:8080")
 	fmt.Println("\nFeatures enabled:")
-	fmt.Println(" ✅ 2-Deck System")
+	fmt.Printf(" ✅ %d-Deck System\n", engine.mixer.DeckCount())
 	fmt.Println(" ✅ 3-Band EQ")
-	fmt.Println(" ✅ Hi/Low Pass Filters")
+	fmt.Println(" ✅ DJ Color Filter (LPF / HPF)")
+	fmt.Println(" ✅ Insert Effects (Echo / Reverb / Flanger / Phaser / Bitcrusher)")
 	fmt.Println(" ✅ BPM Detection & Sync")
 	fmt.Println(" ✅ Cue Points & Loops")
+	fmt.Println(" ✅ Headphone Cue (PFL)")
 	fmt.Println(" ✅ Pitch Control")
 	fmt.Println(" ✅ WebSocket Status Stream")
+	fmt.Println(" ✅ Set Recording (WAV / FLAC)")
 	fmt.Println("\nPress Ctrl+C to stop")
 
 	// =======================================================
 	// 🚀 ステータス配信ゴルーチン (10ms間隔)
 	// =======================================================
-	// 🚀 ステータス配信ゴルーチン (20 FPS)
+	// 🚀 ステータス配信ゴルーチン (10 FPS)
 	go func() {
 		log.Println("📡 Status broadcasting goroutine started.") // 💡 起動ログ
 		const pingInterval = 10 * time.Second
 		lastPing := time.Now()
 
 		// 💡 50msだと負荷が高い可能性があるため、一旦 100ms (10FPS) で安定させます
-		ticker := time.NewTicker(200 * time.Millisecond)
+		//    ラウドネスメーターも 100ms ごとに更新されるので、これより遅いとメーターがカクつく
+		ticker := time.NewTicker(100 * time.Millisecond)
 		defer ticker.Stop()
 
 		// 💡 ログの間隔を管理
@@ -889,6 +1432,16 @@ func main() {
 		}
 	}()
 
+	// 💡 Ctrl+C で止めたときも、録音中のファイルを閉じてから終わる
+	go func() {
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		<-interrupt
+		log.Println("🛑 Shutting down...")
+		engine.Close()
+		os.Exit(0)
+	}()
+
 	// サーバー起動時に、作成した mux を enableCORS でラップします
 	log.Fatal(http.ListenAndServe(":8080", enableCORS(mux)))
 }
@@ -0,0 +1,83 @@
+package audio
+
+import "math"
+
+// BeatGrid はビートグリッド（一定テンポの拍の位置）
+// 位置はすべて等速再生時のトラック上の秒数
+type BeatGrid struct {
+	BPM           float64 // テンポ（0.01 BPM 単位）
+	FirstBeat     float64 // 最初の拍の位置（0 以上、1拍未満）
+	FirstDownbeat float64 // 最初の小節頭（ダウンビート）の位置（0 以上、1小節未満）
+	BeatsPerBar   int     // 1小節の拍数
+}
+
+// Interval は1拍の長さ（秒）
+func (g *BeatGrid) Interval() float64 {
+	if g == nil || g.BPM <= 0 {
+		return 0
+	}
+	return 60.0 / g.BPM
+}
+
+// BeatTime は n 拍目の位置（秒）を返す（n は負でもよい）
+func (g *BeatGrid) BeatTime(n int) float64 {
+	return g.FirstBeat + float64(n)*g.Interval()
+}
+
+// BeatNumber は position（秒）が何拍目かを小数で返す
+func (g *BeatGrid) BeatNumber(position float64) float64 {
+	interval := g.Interval()
+	if interval == 0 {
+		return 0
+	}
+	return (position - g.FirstBeat) / interval
+}
+
+// Phase は拍の中での位置（0.0 ～ 1.0 未満）を返す
+func (g *BeatGrid) Phase(position float64) float64 {
+	beat := g.BeatNumber(position)
+	return beat - math.Floor(beat)
+}
+
+// BarPhase は小節の中での位置（0.0 ～ 1.0 未満）を返す
+func (g *BeatGrid) BarPhase(position float64) float64 {
+	barLength := g.Interval() * float64(g.BeatsPerBar)
+	if barLength == 0 {
+		return 0
+	}
+	bar := (position - g.FirstDownbeat) / barLength
+	return bar - math.Floor(bar)
+}
+
+// NearestBeat は position に最も近い拍の位置（秒）を返す
+func (g *BeatGrid) NearestBeat(position float64) float64 {
+	return g.BeatTime(int(math.Round(g.BeatNumber(position))))
+}
+
+// IsDownbeat は n 拍目が小節頭か
+func (g *BeatGrid) IsDownbeat(n int) bool {
+	if g.BeatsPerBar <= 0 {
+		return false
+	}
+	offset := int(math.Round(g.BeatNumber(g.FirstDownbeat)))
+	k := (n - offset) % g.BeatsPerBar
+	return k == 0
+}
+
+// Downbeats は from ～ to（秒）の範囲にある小節頭の位置を返す
+func (g *BeatGrid) Downbeats(from, to float64) []float64 {
+	barLength := g.Interval() * float64(g.BeatsPerBar)
+	if barLength == 0 || to < from {
+		return nil
+	}
+	first := math.Ceil((from - g.FirstDownbeat) / barLength)
+	var downbeats []float64
+	for n := first; ; n++ {
+		t := g.FirstDownbeat + n*barLength
+		if t > to {
+			break
+		}
+		downbeats = append(downbeats, t)
+	}
+	return downbeats
+}
@@ -0,0 +1,60 @@
+package audio
+
+import "math"
+
+// ビットクラッシャー
+//
+// 解説：音をわざと粗くして、昔のゲーム機やサンプラーのような「ザラザラ」した音にする
+//
+//	bits：      音の細かさ（ビット数）を落とす。少ないほど階段状になって歪む
+//	downsample：同じサンプルを何回か続けて出す（サンプルレートを落とす）。大きいほどこもって金属的になる
+type Bitcrusher struct {
+	bits       *EffectParam // 量子化のビット数（1 ～ 16）
+	downsample *EffectParam // 同じサンプルを続ける回数（1 ～ 32）
+
+	// 以下はオーディオスレッド専用
+	held [2]float32 // 続けて出しているサンプル
+	hold int        // 次のサンプルを取るまでの残り回数
+}
+
+// NewBitcrusher はビットクラッシャーを作成
+func NewBitcrusher(sampleRate float64) *Bitcrusher {
+	return &Bitcrusher{
+		bits:       newEffectParam("bits", 1, 16, 8),
+		downsample: newEffectParam("downsample", 1, 32, 4),
+	}
+}
+
+// Name はエフェクトの名前
+func (b *Bitcrusher) Name() string {
+	return "bitcrusher"
+}
+
+// Params はつまみの一覧
+func (b *Bitcrusher) Params() []*EffectParam {
+	return []*EffectParam{b.bits, b.downsample}
+}
+
+// Reset は続けて出しているサンプルを消す
+func (b *Bitcrusher) Reset() {
+	b.held = [2]float32{}
+	b.hold = 0
+}
+
+// Process はビットクラッシャーをかける
+func (b *Bitcrusher) Process(samples []float32, beatSeconds float64) {
+	// 💡 bits は小数でもよい（つまみを回したときに段数がなめらかに変わる）
+	steps := float32(math.Pow(2, b.bits.Get()-1))
+	downsample := int(math.Round(b.downsample.Get()))
+
+	for i := 0; i+1 < len(samples); i += 2 {
+		if b.hold <= 0 {
+			for ch := 0; ch < 2; ch++ {
+				b.held[ch] = float32(math.Round(float64(samples[i+ch]*steps))) / steps
+			}
+			b.hold = downsample
+		}
+		b.hold--
+		samples[i], samples[i+1] = b.held[0], b.held[1]
+	}
+}
@@ -2,13 +2,46 @@ package audio
 
 import (
 	"math"
+	"sort"
+	"sync"
 )
 
-// BPMDetector はBPM（テンポ）を検出
+// テンポ解析の設定
+const (
+	onsetHopsPerSecond = 200   // オンセット強度の時間分解能（5ms）
+	onsetAnalysisRate  = 22050 // 解析用に間引いた後のサンプルレート（目安）
+	onsetFrameSeconds  = 0.023 // FFT の窓の長さ（22.05kHz で 512 サンプル）
+	onsetLowBandHz     = 200.0 // キック・ベースの帯域（ダウンビート判定用）
+	onsetMeanSeconds   = 0.25  // 局所平均を引く範囲（±）
+	onsetPeakPosition  = 0.75  // フラックスが最大になる時の、窓の中でのアタックの位置（0: 古い側 ～ 1: 新しい側）
+	tempoMinBPM        = 60.0  // 粗い推定の範囲
+	tempoMaxBPM        = 240.0
+	tempoHarmonics     = 4 // コムフィルタで見る倍数の数
+	beatsPerBar        = 4
+)
+
+// BPMRangeMin は検出結果を [BPMRangeMin, 2×BPMRangeMin) に収めるための下限
+// 倍・半分のどちらとも取れるテンポを、DJソフトで一般的な 88 ～ 175 BPM に寄せる
+var BPMRangeMin = 88.0
+
+// BPMDetector はBPM（テンポ）とビートグリッドを検出
+//
+// 解説：
+//  1. オンセット強度 … 5msごとにFFTし、各周波数の「音量の増え方」を合計する（スペクトルフラックス）
+//     ドラムのアタックなど、音が鳴り始めた瞬間に大きくなる
+//  2. 粗いテンポ … オンセット強度の自己相関を、拍の位置（1,2,3,4拍後）と裏拍の位置で比べる
+//     （拍で強く裏拍で弱いテンポほど正しい。倍・半分の取り違えを防ぐ）
+//  3. 細かいテンポと位相 … 候補のテンポでオンセット強度を1拍ごとに折り返して重ね、
+//     山が最も鋭くなるテンポと位相を探す。さらに各拍のアタック位置に直線を当てはめて、
+//     0.01 BPM 単位まで追い込む
+//  4. ダウンビート … 4拍のうち、アタックと低音が最も強い拍を小節頭とみなす
 type BPMDetector struct {
 	sampleRate int
+
+	mu         sync.RWMutex // 解析は別ゴルーチンで行うので結果を保護する
 	bpm        float64
-	confidence float64 // 検出の信頼度（0.0 - 1.0）
+	confidence float64   // 検出の信頼度（0.0 - 1.0）
+	grid       *BeatGrid // 解析結果のビートグリッド（未解析なら nil）
 }
 
 // NewBPMDetector はBPM検出器を作成
@@ -21,132 +54,323 @@ func NewBPMDetector(sampleRate int) *BPMDetector {
 	}
 }
 
-// DetectBPM は音声データからBPMを検出
+// DetectBPM は音声データ（ステレオ・インターリーブ）からBPMとビートグリッドを検出
 // スライス（[]float32）は動的配列、Goの重要なデータ構造
 func (d *BPMDetector) DetectBPM(samples []float32) float64 {
 	// サンプル数が少なすぎる場合は検出不可
-	if len(samples) < d.sampleRate*2 {
+	if len(samples)/2 < d.sampleRate*2 {
 		return 0
 	}
 
-	// 1. エネルギー包絡線を計算
-	// 音の大きさの変化を追跡する
-	envelope := d.calculateEnvelope(samples)
+	// 1. オンセット強度を計算
+	onset := d.onsetStrength(samples)
+	if len(onset.full) < onsetHopsPerSecond {
+		return 0
+	}
 
-	// 2. ピーク検出（ビートの位置を見つける）
-	peaks := d.detectPeaks(envelope)
+	// 2. 自己相関とコムフィルタで粗いテンポを推定
+	coarse := estimateTempo(onset.full, onset.fps)
+	if coarse == 0 {
+		return 0
+	}
+	for coarse < BPMRangeMin {
+		coarse *= 2
+	}
+	for coarse >= BPMRangeMin*2 {
+		coarse /= 2
+	}
 
-	// 3. ピーク間隔からBPMを計算
-	if len(peaks) < 2 {
-		return 0 // ピークが少なすぎる
+	// 3. 折り返しで細かいテンポと位相を求め、各拍の位置への直線当てはめで追い込む
+	period, offset, contrast := fitBeatGrid(onset.full, 60*onset.fps/coarse)
+	period, offset = refineBeatGrid(onset.full, period, offset)
+	bpm := math.Round(60*onset.fps/period*100) / 100
+
+	// 4. ダウンビート（何拍目が小節頭か）
+	downbeat := detectDownbeat(onset, period, offset)
+
+	// フレーム番号 → 秒
+	interval := 60 / bpm
+	firstBeat := onset.seconds(offset)
+	firstDownbeat := firstBeat + float64(downbeat)*interval
+	// 💡 先頭ちょうどの拍が誤差で「1拍後の直前」にならないように、1ms 未満の差は 0 に寄せる
+	wrap := func(position, length float64) float64 {
+		position = positiveMod(position, length)
+		if length-position < 0.001 {
+			position = 0
+		}
+		return position
+	}
+	grid := &BeatGrid{
+		BPM:           bpm,
+		FirstBeat:     wrap(firstBeat, interval),
+		FirstDownbeat: wrap(firstDownbeat, interval*beatsPerBar),
+		BeatsPerBar:   beatsPerBar,
 	}
 
-	// ピーク間の平均間隔を計算
-	intervals := make([]float64, 0, len(peaks)-1)
-	for i := 1; i < len(peaks); i++ {
-		interval := float64(peaks[i] - peaks[i-1])
-		intervals = append(intervals, interval)
+	// 信頼度：山の鋭さ（一様なら 0、鋭いほど 1 に近づく）
+	confidence := 0.0
+	if contrast > 1 {
+		confidence = 1 - 1/contrast
 	}
 
-	// 中央値を使用（外れ値に強い）
-	avgInterval := d.median(intervals)
+	d.mu.Lock()
+	d.bpm = bpm
+	d.confidence = confidence
+	d.grid = grid
+	d.mu.Unlock()
 
-	// サンプル数から秒に変換し、BPMを計算
-	// BPM = 60秒 / (ビート間隔[秒])
-	intervalInSeconds := avgInterval / float64(d.sampleRate)
-	if intervalInSeconds > 0 {
-		d.bpm = 60.0 / intervalInSeconds
+	return bpm
+}
 
-		// BPMの妥当な範囲にクランプ（60-200 BPM）
-		if d.bpm < 60 {
-			d.bpm *= 2 // ハーフタイムの可能性
-		}
-		if d.bpm > 200 {
-			d.bpm /= 2 // ダブルタイムの可能性
-		}
+// onsetEnvelope はオンセット強度の時系列
+type onsetEnvelope struct {
+	full  []float64 // 全帯域
+	low   []float64 // 低域のみ（キック・ベース）
+	fps   float64   // 1秒あたりのフレーム数
+	delay float64   // フレーム番号 0 の時刻（秒）
+}
+
+// seconds はフレーム番号（小数）をトラック上の秒に変換
+func (o *onsetEnvelope) seconds(frame float64) float64 {
+	return frame/o.fps + o.delay
+}
 
-		d.confidence = d.calculateConfidence(intervals)
+// onsetStrength はスペクトルフラックスでオンセット強度を計算
+// 解説：音の「鳴り始め」を、周波数ごとの音量の増加として捉える
+func (d *BPMDetector) onsetStrength(samples []float32) *onsetEnvelope {
+	// 💡 11kHz 以上はテンポ解析にほぼ不要なので、先にモノラル化して間引き、FFTを軽くする
+	decimation := d.sampleRate / onsetAnalysisRate
+	if decimation < 1 {
+		decimation = 1
+	}
+	rate := d.sampleRate / decimation
+	mono := make([]float32, len(samples)/2/decimation)
+	for i := range mono {
+		var sum float32
+		for j := 0; j < decimation; j++ {
+			k := (i*decimation + j) * 2
+			sum += samples[k] + samples[k+1]
+		}
+		mono[i] = sum / float32(2*decimation)
 	}
 
-	return d.bpm
-}
+	// 窓の長さは目安に最も近い2のべき乗
+	target := onsetFrameSeconds * float64(rate)
+	frameSize := 1
+	for float64(frameSize)*1.5 < target {
+		frameSize <<= 1
+	}
+	hop := rate / onsetHopsPerSecond
+	n := (len(mono)-frameSize)/hop + 1
+	if n < 2 {
+		return &onsetEnvelope{}
+	}
 
-// calculateEnvelope はエネルギー包絡線を計算
-// 解説：音の「大きさの変化」を滑らかな曲線として表現
-func (d *BPMDetector) calculateEnvelope(samples []float32) []float64 {
-	// ウィンドウサイズ：約0.05秒分のサンプル
-	windowSize := d.sampleRate / 20
-	envelope := make([]float64, len(samples)/windowSize)
+	window := make([]float64, frameSize)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(frameSize))
+	}
+	lowBins := int(onsetLowBandHz * float64(frameSize) / float64(rate))
+
+	re := make([]float64, frameSize)
+	im := make([]float64, frameSize)
+	prev := make([]float64, frameSize/2+1)
+	onset := &onsetEnvelope{
+		full: make([]float64, n),
+		low:  make([]float64, n),
+		fps:  float64(rate) / float64(hop),
+		// 💡 フラックスはアタックが窓に入ってすぐ（窓の中心より新しい側）で最大になるので、その位置をフレームの時刻とする
+		delay: float64(frameSize) * onsetPeakPosition / float64(rate),
+	}
 
-	// 各ウィンドウでRMS（Root Mean Square）を計算
-	for i := range envelope {
-		start := i * windowSize
-		end := start + windowSize
-		if end > len(samples) {
-			end = len(samples)
+	for t := 0; t < n; t++ {
+		start := t * hop
+		for i := range re {
+			re[i] = float64(mono[start+i]) * window[i]
+			im[i] = 0
 		}
-
-		// RMS計算：音の実効値
-		var sum float64
-		for j := start; j < end; j++ {
-			val := float64(samples[j])
-			sum += val * val // 二乗和
+		fft(re, im)
+
+		var flux, lowFlux float64
+		for k := 1; k <= frameSize/2; k++ {
+			// 対数圧縮（小さな音のアタックも拾えるように）
+			mag := math.Log1p(100 * math.Sqrt(re[k]*re[k]+im[k]*im[k]))
+			if diff := mag - prev[k]; t > 0 && diff > 0 {
+				flux += diff
+				if k <= lowBins {
+					lowFlux += diff
+				}
+			}
+			prev[k] = mag
 		}
-		envelope[i] = math.Sqrt(sum / float64(end-start)) // 平均の平方根
+		onset.full[t] = flux
+		onset.low[t] = lowFlux
 	}
 
-	return envelope
+	// 局所平均を引いて、持続音による底上げを取り除く
+	radius := int(onsetMeanSeconds * onset.fps)
+	onset.full = subtractLocalMean(onset.full, radius)
+	onset.low = subtractLocalMean(onset.low, radius)
+	return onset
 }
 
-// detectPeaks はエネルギー包絡線からピークを検出
-// 解説：「ドン」というビートの位置を見つける
-func (d *BPMDetector) detectPeaks(envelope []float64) []int {
-	if len(envelope) < 3 {
-		return nil
+// subtractLocalMean は ±radius の移動平均を引き、負の値を 0 にする
+func subtractLocalMean(values []float64, radius int) []float64 {
+	prefix := make([]float64, len(values)+1)
+	for i, v := range values {
+		prefix[i+1] = prefix[i] + v
 	}
+	out := make([]float64, len(values))
+	for i, v := range values {
+		lo, hi := i-radius, i+radius+1
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > len(values) {
+			hi = len(values)
+		}
+		mean := (prefix[hi] - prefix[lo]) / float64(hi-lo)
+		if v > mean {
+			out[i] = v - mean
+		}
+	}
+	return out
+}
 
-	// 動的閾値を計算（平均値の1.5倍）
-	var sum float64
-	for _, val := range envelope {
-		sum += val
+// estimateTempo は自己相関とコムフィルタで粗いテンポ（BPM）を推定
+func estimateTempo(onset []float64, fps float64) float64 {
+	maxLag := int(60*fps/tempoMinBPM*tempoHarmonics) + 2
+	if maxLag >= len(onset) {
+		maxLag = len(onset) - 1
 	}
-	threshold := (sum / float64(len(envelope))) * 1.5
 
-	peaks := make([]int, 0, 100)
+	// 自己相関（ラグが大きいほど重なる長さが短いので、長さで割る）
+	acf := make([]float64, maxLag+1)
+	for lag := range acf {
+		var sum float64
+		for i := 0; i+lag < len(onset); i++ {
+			sum += onset[i] * onset[i+lag]
+		}
+		acf[lag] = sum / float64(len(onset)-lag)
+	}
+	at := func(lag float64) float64 {
+		i := int(lag)
+		if i+1 >= len(acf) {
+			return 0
+		}
+		f := lag - float64(i)
+		return acf[i]*(1-f) + acf[i+1]*f
+	}
 
-	// ピーク検出：前後より大きく、閾値を超える点
-	for i := 1; i < len(envelope)-1; i++ {
-		if envelope[i] > envelope[i-1] &&
-			envelope[i] > envelope[i+1] &&
-			envelope[i] > threshold {
-			peaks = append(peaks, i)
+	best, bestScore := 0.0, 0.0
+	for bpm := tempoMinBPM; bpm <= tempoMaxBPM; bpm += 0.25 {
+		period := 60 * fps / bpm
+		// 拍の位置の相関 − 裏拍の位置の相関
+		var score float64
+		for m := 1; m <= tempoHarmonics; m++ {
+			score += at(float64(m)*period) - at((float64(m)-0.5)*period)
+		}
+		if score > bestScore {
+			best, bestScore = bpm, score
 		}
 	}
+	return best
+}
 
-	return peaks
+// fitBeatGrid は period（フレーム）の ±2% で、折り返したオンセット強度の山が最も鋭くなる周期を探す
+// 戻り値は周期、最初の拍のフレーム位置、山の鋭さ（一様なら 1）
+func fitBeatGrid(onset []float64, coarse float64) (period, offset, contrast float64) {
+	for p := coarse * 0.98; p <= coarse*1.02; p += coarse * 0.0002 {
+		if o, c := foldBeats(onset, p); c > contrast {
+			period, offset, contrast = p, o, c
+		}
+	}
+	return period, offset, contrast
 }
 
-// median は中央値を計算
-// 解説：平均値より外れ値に強い統計量
-func (d *BPMDetector) median(values []float64) float64 {
-	if len(values) == 0 {
-		return 0
+// refineBeatGrid は各拍の近くのアタック位置を小数フレームで求め、直線（位置 = offset + k×period）を当てはめる
+// 解説：折り返しでは数ms単位までしか分からないが、数十～数百拍ぶんの位置から傾きを求めると
+// テンポの誤差は拍数に反比例して小さくなる
+func refineBeatGrid(onset []float64, period, offset float64) (float64, float64) {
+	radius := int(period / 8)
+	var beats, positions, peaks []float64
+	for k := 0; ; k++ {
+		center := int(math.Round(offset + float64(k)*period))
+		if center+radius+1 >= len(onset) {
+			break
+		}
+		peak := -1
+		for i := center - radius; i <= center+radius; i++ {
+			if i > 0 && (peak < 0 || onset[i] > onset[peak]) {
+				peak = i
+			}
+		}
+		if peak < 0 || onset[peak] == 0 {
+			continue
+		}
+		// 放物線補間で小数フレームの位置を求める
+		pos := float64(peak)
+		a, b, c := onset[peak-1], onset[peak], onset[peak+1]
+		if denom := a - 2*b + c; denom < 0 {
+			pos += 0.5 * (a - c) / denom
+		}
+		beats = append(beats, float64(k))
+		positions = append(positions, pos)
+		peaks = append(peaks, onset[peak])
 	}
 
-	// コピーしてソート
-	sorted := make([]float64, len(values))
-	copy(sorted, values)
+	// アタックが弱い拍（ブレイクなど）は使わない
+	threshold := 0.3 * medianOf(peaks)
+	use := make([]bool, len(beats))
+	for i := range use {
+		use[i] = peaks[i] >= threshold
+	}
 
-	// バブルソート（小規模データなので十分）
-	for i := 0; i < len(sorted); i++ {
-		for j := i + 1; j < len(sorted); j++ {
-			if sorted[i] > sorted[j] {
-				sorted[i], sorted[j] = sorted[j], sorted[i]
+	// 最小二乗法で当てはめ、大きく外れた拍を除いてもう一度
+	for pass := 0; pass < 2; pass++ {
+		a, b, ok := fitLine(beats, positions, use)
+		if !ok {
+			break
+		}
+		offset, period = a, b
+		for i := range use {
+			if math.Abs(positions[i]-(a+b*beats[i])) > 2 {
+				use[i] = false
 			}
 		}
 	}
+	return period, offset
+}
 
-	// 中央値を返す
+// fitLine は use が true の点に y = a + b×x を最小二乗法で当てはめる
+func fitLine(x, y []float64, use []bool) (a, b float64, ok bool) {
+	var n, sx, sy, sxx, sxy float64
+	for i := range x {
+		if !use[i] {
+			continue
+		}
+		n++
+		sx += x[i]
+		sy += y[i]
+		sxx += x[i] * x[i]
+		sxy += x[i] * y[i]
+	}
+	denom := n*sxx - sx*sx
+	if n < 4 || denom == 0 {
+		return 0, 0, false
+	}
+	b = (n*sxy - sx*sy) / denom
+	a = (sy - b*sx) / n
+	return a, b, true
+}
+
+// medianOf は中央値を計算
+func medianOf(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
 	mid := len(sorted) / 2
 	if len(sorted)%2 == 0 {
 		return (sorted[mid-1] + sorted[mid]) / 2
@@ -154,42 +378,121 @@ func (d *BPMDetector) median(values []float64) float64 {
 	return sorted[mid]
 }
 
-// calculateConfidence は検出の信頼度を計算
-// 解説：ビート間隔のばらつきが小さいほど信頼度が高い
-func (d *BPMDetector) calculateConfidence(intervals []float64) float64 {
-	if len(intervals) < 2 {
-		return 0
+// foldBeats はオンセット強度を period フレームごとに折り返して重ね、山の位置と鋭さを返す
+func foldBeats(onset []float64, period float64) (offset, contrast float64) {
+	bins := int(period)
+	if bins < 4 {
+		return 0, 0
+	}
+	hist := make([]float64, bins)
+	var total float64
+	for i, v := range onset {
+		if v == 0 {
+			continue
+		}
+		// 隣り合う2つのビンに按分（ビンの境目で山が割れないように）
+		q := float64(i) / period
+		pos := (q - math.Floor(q)) * float64(bins)
+		k := int(pos)
+		f := pos - float64(k)
+		hist[k%bins] += v * (1 - f)
+		hist[(k+1)%bins] += v * f
+		total += v
+	}
+	if total == 0 {
+		return 0, 0
 	}
 
-	// 標準偏差を計算
-	mean := 0.0
-	for _, val := range intervals {
-		mean += val
+	// [1 2 1] で平滑化（循環）
+	smooth := make([]float64, bins)
+	for k := range hist {
+		smooth[k] = (hist[(k+bins-1)%bins] + 2*hist[k] + hist[(k+1)%bins]) / 4
 	}
-	mean /= float64(len(intervals))
 
-	variance := 0.0
-	for _, val := range intervals {
-		diff := val - mean
-		variance += diff * diff
+	peak := 0
+	for k := range smooth {
+		if smooth[k] > smooth[peak] {
+			peak = k
+		}
 	}
-	variance /= float64(len(intervals))
-	stdDev := math.Sqrt(variance)
 
-	// 変動係数（CV）から信頼度を計算
-	// CV = 標準偏差 / 平均
-	cv := stdDev / mean
-	confidence := 1.0 - math.Min(cv, 1.0)
+	// 山の頂点を放物線補間
+	pos := float64(peak)
+	a, b, c := smooth[(peak+bins-1)%bins], smooth[peak], smooth[(peak+1)%bins]
+	if denom := a - 2*b + c; denom < 0 {
+		pos += 0.5 * (a - c) / denom
+	}
 
-	return confidence
+	offset = positiveMod(pos/float64(bins)*period, period)
+	contrast = smooth[peak] / (total / float64(bins))
+	return offset, contrast
+}
+
+// detectDownbeat は何拍目（0 ～ beatsPerBar-1）が小節頭かを推定
+// 解説：小節頭にはキックやクラッシュが来ることが多いので、アタックと低音の強さを拍ごとに比べる
+func detectDownbeat(onset *onsetEnvelope, period, offset float64) int {
+	peakNear := func(values []float64, center float64) float64 {
+		var peak float64
+		for i := int(center) - 2; i <= int(center)+2; i++ {
+			if i >= 0 && i < len(values) && values[i] > peak {
+				peak = values[i]
+			}
+		}
+		return peak
+	}
+
+	var sums [beatsPerBar]float64
+	var counts [beatsPerBar]int
+	for k := 0; ; k++ {
+		center := offset + float64(k)*period
+		if int(center) >= len(onset.full) {
+			break
+		}
+		sums[k%beatsPerBar] += peakNear(onset.full, center) + 2*peakNear(onset.low, center)
+		counts[k%beatsPerBar]++
+	}
+
+	best, bestScore := 0, -1.0
+	for k := range sums {
+		if counts[k] == 0 {
+			continue
+		}
+		if score := sums[k] / float64(counts[k]); score > bestScore {
+			best, bestScore = k, score
+		}
+	}
+	return best
+}
+
+// positiveMod は 0 以上 m 未満の剰余
+func positiveMod(x, m float64) float64 {
+	if m <= 0 {
+		return 0
+	}
+	r := math.Mod(x, m)
+	if r < 0 {
+		r += m
+	}
+	return r
 }
 
 // GetBPM は検出されたBPMを返す
 func (d *BPMDetector) GetBPM() float64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
 	return d.bpm
 }
 
 // GetConfidence は検出の信頼度を返す
 func (d *BPMDetector) GetConfidence() float64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
 	return d.confidence
 }
+
+// GetBeatGrid は検出されたビートグリッドを返す（未解析なら nil）
+func (d *BPMDetector) GetBeatGrid() *BeatGrid {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.grid
+}
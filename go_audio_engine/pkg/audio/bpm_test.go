@@ -0,0 +1,81 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+// TestDetectBPMClickTracks はクリック音の fixture（generate-test-wav.js の generateClickTrack）で
+// テンポ・最初の拍・小節頭が分かっている値と一致することを確認する
+// 💡 クリックは offset 秒から1拍ごとに鳴り、4拍ごとの小節頭は高く大きい音
+func TestDetectBPMClickTracks(t *testing.T) {
+	const (
+		bpmTolerance    = 0.05  // BPM
+		offsetTolerance = 0.005 // 秒（オンセット強度の時間分解能 5ms）
+	)
+
+	tests := []struct {
+		file   string
+		bpm    float64
+		offset float64 // 最初のクリック（小節頭）の位置（秒）
+	}{
+		{"click_120bpm.wav", 120, 0},
+		{"click_128bpm.wav", 128, 0.25},
+		{"click_174bpm.wav", 174, 0.1},
+		{"click_93.5bpm.wav", 93.5, 0.37},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			stream, _ := openFixture(t, tt.file)
+			data, err := ReadAll(stream)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data = ToStereo(data, StreamChannelLayout(stream))
+
+			d := NewBPMDetector(stream.SampleRate())
+			if bpm := d.DetectBPM(data); math.Abs(bpm-tt.bpm) > bpmTolerance {
+				t.Errorf("BPM = %v, want %v", bpm, tt.bpm)
+			}
+			if c := d.GetConfidence(); c < 0.5 {
+				t.Errorf("confidence = %v, want a clear beat (>= 0.5)", c)
+			}
+
+			grid := d.GetBeatGrid()
+			if grid == nil {
+				t.Fatal("beat grid is nil")
+			}
+			if grid.BeatsPerBar != 4 {
+				t.Errorf("beats per bar = %d, want 4", grid.BeatsPerBar)
+			}
+
+			// 最初の拍は offset を1拍で割った余り、小節頭は offset そのもの（どの fixture も1小節目の中）
+			interval := 60 / tt.bpm
+			wantFirstBeat := math.Mod(tt.offset, interval)
+			if diff := circularDiff(grid.FirstBeat, wantFirstBeat, interval); diff > offsetTolerance {
+				t.Errorf("first beat = %.4f s, want %.4f s", grid.FirstBeat, wantFirstBeat)
+			}
+			if diff := circularDiff(grid.FirstDownbeat, tt.offset, interval*4); diff > offsetTolerance {
+				t.Errorf("first downbeat = %.4f s, want %.4f s", grid.FirstDownbeat, tt.offset)
+			}
+		})
+	}
+}
+
+// circularDiff は周期 period の中での a と b の差（先頭と末尾をまたぐずれも小さく数える）
+func circularDiff(a, b, period float64) float64 {
+	d := math.Abs(positiveMod(a-b, period))
+	return math.Min(d, period-d)
+}
+
+// TestDetectBPMTooShort は2秒未満の音声では検出しないことを確認する
+func TestDetectBPMTooShort(t *testing.T) {
+	d := NewBPMDetector(44100)
+	if bpm := d.DetectBPM(make([]float32, 44100*2)); bpm != 0 {
+		t.Errorf("BPM for 1 second = %v, want 0", bpm)
+	}
+	if d.GetBeatGrid() != nil {
+		t.Error("beat grid for 1 second is not nil")
+	}
+}
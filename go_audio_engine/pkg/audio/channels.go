@@ -0,0 +1,193 @@
+package audio
+
+// Speaker はスピーカー位置
+// 並び順は WAVE_FORMAT_EXTENSIBLE のチャンネルマスクのビット順と同じ
+type Speaker int
+
+const (
+	SpeakerFrontLeft Speaker = iota
+	SpeakerFrontRight
+	SpeakerFrontCenter
+	SpeakerLFE
+	SpeakerBackLeft
+	SpeakerBackRight
+	SpeakerFrontLeftOfCenter
+	SpeakerFrontRightOfCenter
+	SpeakerBackCenter
+	SpeakerSideLeft
+	SpeakerSideRight
+	SpeakerOther // 天井スピーカーなど、上記以外
+)
+
+// channelLayouter はチャンネル配置を知っているストリーム
+// 実装していないストリームは DefaultChannelLayout の並びとみなす
+type channelLayouter interface {
+	ChannelLayout() []Speaker
+}
+
+// StreamChannelLayout はストリームのチャンネル配置を返す
+func StreamChannelLayout(stream PCMStream) []Speaker {
+	if l, ok := stream.(channelLayouter); ok {
+		if layout := l.ChannelLayout(); len(layout) == stream.Channels() {
+			return layout
+		}
+	}
+	return DefaultChannelLayout(stream.Channels())
+}
+
+// DefaultChannelLayout はWAV/FLACの標準的なチャンネル順（SMPTE順）
+func DefaultChannelLayout(channels int) []Speaker {
+	switch channels {
+	case 1:
+		return []Speaker{SpeakerFrontCenter}
+	case 2:
+		return []Speaker{SpeakerFrontLeft, SpeakerFrontRight}
+	case 3:
+		return []Speaker{SpeakerFrontLeft, SpeakerFrontRight, SpeakerFrontCenter}
+	case 4:
+		return []Speaker{SpeakerFrontLeft, SpeakerFrontRight, SpeakerBackLeft, SpeakerBackRight}
+	case 5:
+		return []Speaker{SpeakerFrontLeft, SpeakerFrontRight, SpeakerFrontCenter, SpeakerBackLeft, SpeakerBackRight}
+	case 6:
+		return []Speaker{SpeakerFrontLeft, SpeakerFrontRight, SpeakerFrontCenter, SpeakerLFE,
+			SpeakerBackLeft, SpeakerBackRight}
+	case 7:
+		return []Speaker{SpeakerFrontLeft, SpeakerFrontRight, SpeakerFrontCenter, SpeakerLFE,
+			SpeakerBackCenter, SpeakerSideLeft, SpeakerSideRight}
+	case 8:
+		return []Speaker{SpeakerFrontLeft, SpeakerFrontRight, SpeakerFrontCenter, SpeakerLFE,
+			SpeakerBackLeft, SpeakerBackRight, SpeakerSideLeft, SpeakerSideRight}
+	}
+
+	// 9ch以上は不明なので、左右交互に振り分ける
+	layout := make([]Speaker, channels)
+	for i := range layout {
+		if i%2 == 0 {
+			layout[i] = SpeakerFrontLeft
+		} else {
+			layout[i] = SpeakerFrontRight
+		}
+	}
+	return layout
+}
+
+// layoutFromChannelMask はWAVのチャンネルマスクから配置を作る
+// マスクのビット数がチャンネル数と合わない場合は nil
+func layoutFromChannelMask(mask uint32, channels int) []Speaker {
+	layout := make([]Speaker, 0, channels)
+	for bit := 0; bit < 32 && len(layout) < channels; bit++ {
+		if mask&(1<<bit) == 0 {
+			continue
+		}
+		speaker := Speaker(bit)
+		if speaker > SpeakerOther {
+			speaker = SpeakerOther
+		}
+		layout = append(layout, speaker)
+	}
+	if len(layout) != channels {
+		return nil
+	}
+	return layout
+}
+
+// stereoGains はステレオへのダウンミックス係数（ITU-R BS.775 準拠）
+// LFE は捨てる（DJミックスでは低域はフロントに含まれている前提）
+func stereoGains(speaker Speaker) (left, right float32) {
+	const minus3dB = 0.70710678
+	switch speaker {
+	case SpeakerFrontLeft, SpeakerFrontLeftOfCenter:
+		return 1, 0
+	case SpeakerFrontRight, SpeakerFrontRightOfCenter:
+		return 0, 1
+	case SpeakerFrontCenter:
+		return minus3dB, minus3dB
+	case SpeakerBackLeft, SpeakerSideLeft:
+		return minus3dB, 0
+	case SpeakerBackRight, SpeakerSideRight:
+		return 0, minus3dB
+	case SpeakerBackCenter:
+		return 0.5, 0.5
+	case SpeakerLFE:
+		return 0, 0
+	default:
+		return 0.5, 0.5
+	}
+}
+
+// StereoMixer は任意のチャンネル配置をステレオに変換する
+// モノラルは左右に同じ音を配置（アップミックス）、
+// 3ch以上は BS.775 の係数でダウンミックスする
+type StereoMixer struct {
+	channels int
+	left     []float32 // 入力チャンネルごとの左への係数
+	right    []float32 // 入力チャンネルごとの右への係数
+}
+
+// NewStereoMixer はチャンネル配置からミキサーを作成
+func NewStereoMixer(layout []Speaker) *StereoMixer {
+	m := &StereoMixer{
+		channels: len(layout),
+		left:     make([]float32, len(layout)),
+		right:    make([]float32, len(layout)),
+	}
+
+	switch len(layout) {
+	case 1:
+		// モノラルはそのまま左右にコピー（音量は変えない）
+		m.left[0], m.right[0] = 1, 1
+		return m
+	case 2:
+		// ステレオは左右が入れ替わっていなければそのまま
+		if layout[0] != SpeakerFrontRight {
+			m.left[0], m.right[1] = 1, 1
+		} else {
+			m.left[1], m.right[0] = 1, 1
+		}
+		return m
+	}
+
+	var sumL, sumR float32
+	for i, speaker := range layout {
+		m.left[i], m.right[i] = stereoGains(speaker)
+		sumL += m.left[i]
+		sumR += m.right[i]
+	}
+
+	// 全チャンネルがフルスケールでもクリップしないように正規化
+	norm := sumL
+	if sumR > norm {
+		norm = sumR
+	}
+	if norm > 1 {
+		for i := range m.left {
+			m.left[i] /= norm
+			m.right[i] /= norm
+		}
+	}
+	return m
+}
+
+// Process はインターリーブされた入力をステレオに変換して out に追記する
+func (m *StereoMixer) Process(in []float32, out []float32) []float32 {
+	frames := len(in) / m.channels
+	for f := 0; f < frames; f++ {
+		frame := in[f*m.channels : (f+1)*m.channels]
+		var l, r float32
+		for c, v := range frame {
+			l += v * m.left[c]
+			r += v * m.right[c]
+		}
+		out = append(out, l, r)
+	}
+	return out
+}
+
+// ToStereo はデータ全体をステレオに変換する（既にステレオならそのまま返す）
+func ToStereo(data []float32, layout []Speaker) []float32 {
+	if len(layout) == 2 && layout[0] != SpeakerFrontRight {
+		return data
+	}
+	m := NewStereoMixer(layout)
+	return m.Process(data, make([]float32, 0, len(data)/len(layout)*2))
+}
@@ -0,0 +1,142 @@
+package audio
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// zeroCrossingFreq はステレオ・インターリーブの ch チャンネルの周波数を、上向きのゼロ交差の数から求める
+func zeroCrossingFreq(stereo []float32, ch, sampleRate int) float64 {
+	frames := len(stereo) / 2
+	first, last, count := -1, -1, 0
+	for f := 1; f < frames; f++ {
+		if stereo[(f-1)*2+ch] < 0 && stereo[f*2+ch] >= 0 {
+			if first < 0 {
+				first = f
+			} else {
+				count++
+			}
+			last = f
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return float64(count) * float64(sampleRate) / float64(last-first)
+}
+
+// TestToStereoKeepsLengthAndPitch はステレオへの変換で長さ（フレーム数）と音程（周波数）が変わらないことを確認する
+func TestToStereoKeepsLengthAndPitch(t *testing.T) {
+	const (
+		sampleRate = 44100
+		frames     = sampleRate // 1秒
+		freq       = 440.0
+	)
+
+	tests := []struct {
+		name     string
+		channels int
+	}{
+		{"mono", 1},
+		{"stereo", 2},
+		{"3.0", 3},
+		{"quad", 4},
+		{"5.1", 6},
+		{"7.1", 8},
+		{"10ch", 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// すべてのチャンネルに同じ音程のサイン波（チャンネルごとに音量を変える）
+			data := make([]float32, frames*tt.channels)
+			for f := 0; f < frames; f++ {
+				for c := 0; c < tt.channels; c++ {
+					gain := 0.5 / float64(c+1)
+					data[f*tt.channels+c] = float32(gain * sine(freq, sampleRate, f))
+				}
+			}
+
+			layout := DefaultChannelLayout(tt.channels)
+			stereo := ToStereo(data, layout)
+			if len(stereo) != frames*2 {
+				t.Fatalf("ToStereo length = %d frames, want %d", len(stereo)/2, frames)
+			}
+			for ch, name := range []string{"left", "right"} {
+				got := zeroCrossingFreq(stereo, ch, sampleRate)
+				if math.Abs(got-freq) > 1 {
+					t.Errorf("%s frequency = %.2f Hz, want %.0f Hz", name, got, freq)
+				}
+			}
+
+			// 少しずつ変換しても（ストリーミング再生）、まとめて変換したのと同じになる
+			m := NewStereoMixer(layout)
+			var chunked []float32
+			for start := 0; start < len(data); {
+				end := min(start+1000*tt.channels, len(data))
+				chunked = m.Process(data[start:end], chunked)
+				start = end
+			}
+			if len(chunked) != len(stereo) {
+				t.Fatalf("StereoMixer length = %d frames, want %d", len(chunked)/2, frames)
+			}
+			for i := range stereo {
+				if chunked[i] != stereo[i] {
+					t.Fatalf("StereoMixer sample %d = %v, want %v", i, chunked[i], stereo[i])
+				}
+			}
+		})
+	}
+}
+
+// TestToStereoMono はモノラルが左右に同じ音量でコピーされることを確認する
+func TestToStereoMono(t *testing.T) {
+	mono := []float32{0.5, -1, 0.25}
+	stereo := ToStereo(mono, DefaultChannelLayout(1))
+	want := []float32{0.5, 0.5, -1, -1, 0.25, 0.25}
+	if len(stereo) != len(want) {
+		t.Fatalf("length = %d, want %d", len(stereo), len(want))
+	}
+	for i := range want {
+		if stereo[i] != want[i] {
+			t.Errorf("sample %d = %v, want %v", i, stereo[i], want[i])
+		}
+	}
+}
+
+// TestLoadMonoKeepsDuration はモノラルのファイルを読み込んでも長さが変わらず、左右に同じ音が出ることを確認する
+func TestLoadMonoKeepsDuration(t *testing.T) {
+	for _, mode := range []LoadMode{LoadMemory, LoadStreaming} {
+		track := NewTrack(44100)
+		if err := track.LoadWithMode(testdataDir+"/vorbis_mono.ogg", mode); err != nil {
+			t.Fatal(err)
+		}
+		if d := track.GetDuration(); math.Abs(d-1.0) > 1e-6 {
+			t.Errorf("mode %d: duration = %v, want 1.0", mode, d)
+		}
+
+		// ストリーミングは先読みが溜まるまで待つ
+		for i := 0; i < 100 && track.IsStreaming(); i++ {
+			if buffered, _ := track.StreamStats(); buffered >= 0.1 {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		track.Play()
+		out := make([]float32, 4096*2)
+		track.ReadSamples(out)
+		audible := false
+		for i := 0; i < len(out); i += 2 {
+			if out[i] != out[i+1] {
+				t.Fatalf("mode %d: frame %d left %v != right %v", mode, i/2, out[i], out[i+1])
+			}
+			audible = audible || out[i] != 0
+		}
+		if !audible {
+			t.Errorf("mode %d: output is silent", mode)
+		}
+		track.Close()
+	}
+}
@@ -1,5 +1,10 @@
 package audio
 
+import (
+	"sync"
+	"sync/atomic"
+)
+
 // CuePoint はキューポイント（頭出し位置）
 type CuePoint struct {
 	Name     string  // キューポイントの名前（例："Intro", "Drop"）
@@ -17,25 +22,45 @@ type Loop struct {
 }
 
 // CuePointManager はキューポイントとループを管理
+// 解説：キューポイントは API のスレッドだけが使うのでミューテックスで守る。
+// ループはオーディオスレッドが毎ブロック読むので、変更のたびに新しい Loop を作って atomic に差し替える
+// （コピーオンライト）。オーディオスレッドはロックを取らずに、その時点の Loop をまるごと読める
 type CuePointManager struct {
-	CuePoints []CuePoint // スライス：可変長の配列
-	Loop      Loop
+	mu        sync.Mutex
+	cuePoints []CuePoint           // スライス：可変長の配列
+	loop      atomic.Pointer[Loop] // 変更しない（差し替えるだけ）
 }
 
 // NewCuePointManager はマネージャーを作成
 func NewCuePointManager() *CuePointManager {
-	return &CuePointManager{
-		CuePoints: make([]CuePoint, 0, 8), // 初期容量8
-		Loop: Loop{
-			Enabled: false,
-		},
+	m := &CuePointManager{
+		cuePoints: make([]CuePoint, 0, 8), // 初期容量8
 	}
+	m.loop.Store(&Loop{
+		Enabled: false,
+	})
+	return m
+}
+
+// GetLoop は現在のループ区間を返す
+func (m *CuePointManager) GetLoop() Loop {
+	return *m.loop.Load()
+}
+
+// updateLoop はループをコピーして変更し、差し替える（呼び出し側で mu をロックすること）
+func (m *CuePointManager) updateLoop(update func(loop *Loop)) {
+	loop := *m.loop.Load()
+	update(&loop)
+	m.loop.Store(&loop)
 }
 
 // AddCuePoint はキューポイントを追加
 func (m *CuePointManager) AddCuePoint(name string, position float64, color string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	// append: スライスに要素を追加（Goの重要な組み込み関数）
-	m.CuePoints = append(m.CuePoints, CuePoint{
+	m.cuePoints = append(m.cuePoints, CuePoint{
 		Name:     name,
 		Position: position,
 		Color:    color,
@@ -44,25 +69,40 @@ func (m *CuePointManager) AddCuePoint(name string, position float64, color strin
 
 // RemoveCuePoint は指定インデックスのキューポイントを削除
 func (m *CuePointManager) RemoveCuePoint(index int) bool {
-	if index < 0 || index >= len(m.CuePoints) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if index < 0 || index >= len(m.cuePoints) {
 		return false // 範囲外
 	}
 
 	// スライスから要素を削除する慣用句
 	// 解説：削除したい要素の前と後をつなげる
-	m.CuePoints = append(
-		m.CuePoints[:index],      // 前半部分
-		m.CuePoints[index+1:]..., // 後半部分（...は展開演算子）
+	m.cuePoints = append(
+		m.cuePoints[:index],      // 前半部分
+		m.cuePoints[index+1:]..., // 後半部分（...は展開演算子）
 	)
 	return true
 }
 
 // GetCuePoint は指定インデックスのキューポイントを取得
+// 💡 返すのはコピー（書き換えても元のキューポイントは変わらない）
 func (m *CuePointManager) GetCuePoint(index int) *CuePoint {
-	if index < 0 || index >= len(m.CuePoints) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if index < 0 || index >= len(m.cuePoints) {
 		return nil // nilはポインタのゼロ値（存在しないことを表す）
 	}
-	return &m.CuePoints[index]
+	cue := m.cuePoints[index]
+	return &cue
+}
+
+// GetCuePoints は全てのキューポイントのコピーを返す
+func (m *CuePointManager) GetCuePoints() []CuePoint {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]CuePoint(nil), m.cuePoints...)
 }
 
 // SetLoop はループ区間を設定
@@ -71,30 +111,40 @@ func (m *CuePointManager) SetLoop(start, end float64) {
 		return // 無効な区間
 	}
 
-	m.Loop.Start = start
-	m.Loop.End = end
-	m.Loop.Length = end - start
-	m.Loop.Enabled = true
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.updateLoop(func(loop *Loop) {
+		loop.Start = start
+		loop.End = end
+		loop.Length = end - start
+		loop.Enabled = true
+	})
 }
 
 // EnableLoop はループを有効化
 func (m *CuePointManager) EnableLoop(enabled bool) {
-	m.Loop.Enabled = enabled
-	if !enabled {
-		m.Loop.IsActive = false
-	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.updateLoop(func(loop *Loop) {
+		loop.Enabled = enabled
+		if !enabled {
+			loop.IsActive = false
+		}
+	})
 }
 
 // CheckLoop は現在位置がループ終点を超えたかチェック
 // 超えていたらループ開始位置を返す
+// 💡 オーディオスレッドから呼ばれるので、ロックは取らない
 func (m *CuePointManager) CheckLoop(currentPosition float64) (shouldLoop bool, newPosition float64) {
-	if !m.Loop.Enabled || !m.Loop.IsActive {
+	loop := m.loop.Load()
+	if !loop.Enabled || !loop.IsActive {
 		return false, currentPosition
 	}
 
 	// ループ終点を超えた場合
-	if currentPosition >= m.Loop.End {
-		return true, m.Loop.Start
+	if currentPosition >= loop.End {
+		return true, loop.Start
 	}
 
 	return false, currentPosition
@@ -102,29 +152,40 @@ func (m *CuePointManager) CheckLoop(currentPosition float64) (shouldLoop bool, n
 
 // ActivateLoop はループを開始
 func (m *CuePointManager) ActivateLoop() {
-	if m.Loop.Enabled {
-		m.Loop.IsActive = true
-	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.updateLoop(func(loop *Loop) {
+		if loop.Enabled {
+			loop.IsActive = true
+		}
+	})
 }
 
 // DeactivateLoop はループを停止（次の通過時にループしない）
 func (m *CuePointManager) DeactivateLoop() {
-	m.Loop.IsActive = false
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.updateLoop(func(loop *Loop) {
+		loop.IsActive = false
+	})
 }
 
-// FindNearestCuePoint は指定位置に最も近いキューポイントを探す
+// FindNearestCuePoint は指定位置に最も近いキューポイントを探す（コピーを返す）
 func (m *CuePointManager) FindNearestCuePoint(position float64) *CuePoint {
-	if len(m.CuePoints) == 0 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.cuePoints) == 0 {
 		return nil
 	}
 
 	// 最も近いキューポイントを見つける
-	var nearest *CuePoint
+	var nearest CuePoint
 	minDistance := -1.0
 
-	for i := range m.CuePoints {
+	for _, cue := range m.cuePoints {
 		// 距離を計算（絶対値）
-		distance := position - m.CuePoints[i].Position
+		distance := position - cue.Position
 		if distance < 0 {
 			distance = -distance
 		}
@@ -132,20 +193,25 @@ func (m *CuePointManager) FindNearestCuePoint(position float64) *CuePoint {
 		// 最小距離を更新
 		if minDistance < 0 || distance < minDistance {
 			minDistance = distance
-			nearest = &m.CuePoints[i]
+			nearest = cue
 		}
 	}
 
-	return nearest
+	return &nearest
 }
 
 // ClearAllCuePoints は全てのキューポイントを削除
 func (m *CuePointManager) ClearAllCuePoints() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	// 新しい空のスライスを作成
-	m.CuePoints = make([]CuePoint, 0, 8)
+	m.cuePoints = make([]CuePoint, 0, 8)
 }
 
 // GetCuePointCount はキューポイントの数を返す
 func (m *CuePointManager) GetCuePointCount() int {
-	return len(m.CuePoints)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.cuePoints)
 }
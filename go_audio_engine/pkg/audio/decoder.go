@@ -0,0 +1,196 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// PCMStream はデコーダーが返すPCMデータの読み出し口
+// サンプルはインターリーブされた float32（-1.0 ～ 1.0）で返す
+type PCMStream interface {
+	SampleRate() int
+	Channels() int
+
+	// Length は総フレーム数を返す（不明な場合は -1）
+	Length() int64
+
+	// Read は dst にサンプルを書き込み、書き込んだサンプル数を返す
+	// 終端に達したら io.EOF を返す
+	Read(dst []float32) (int, error)
+
+	// SeekFrame は指定フレームから読み出せるように位置を移動する
+	SeekFrame(frame int64) error
+}
+
+// Decoder はオーディオフォーマットごとのデコーダー
+type Decoder interface {
+	// Name はフォーマット名（"wav", "flac" など）
+	Name() string
+
+	// Extensions は対応する拡張子（ドット付き、小文字）
+	Extensions() []string
+
+	// Sniff はファイル先頭のバイト列（マジックバイト）から対応可否を判定
+	Sniff(header []byte) bool
+
+	// Open はデコードを開始する
+	Open(r io.ReadSeeker) (PCMStream, error)
+}
+
+// sniffLen はマジックバイト判定に使う先頭バイト数
+const sniffLen = 16
+
+// ErrUnknownFormat は対応するデコーダーが見つからない場合のエラー
+var ErrUnknownFormat = errors.New("unknown audio format")
+
+// decoderRegistry は登録済みデコーダーの一覧
+var decoderRegistry = struct {
+	sync.RWMutex
+	decoders []Decoder
+}{}
+
+// RegisterDecoder はデコーダーを登録
+// 同名のデコーダーが既にあれば置き換える
+func RegisterDecoder(d Decoder) {
+	decoderRegistry.Lock()
+	defer decoderRegistry.Unlock()
+
+	for i, existing := range decoderRegistry.decoders {
+		if existing.Name() == d.Name() {
+			decoderRegistry.decoders[i] = d
+			return
+		}
+	}
+	decoderRegistry.decoders = append(decoderRegistry.decoders, d)
+}
+
+// Decoders は登録済みデコーダーの名前一覧を返す
+func Decoders() []string {
+	decoderRegistry.RLock()
+	defer decoderRegistry.RUnlock()
+
+	names := make([]string, len(decoderRegistry.decoders))
+	for i, d := range decoderRegistry.decoders {
+		names[i] = d.Name()
+	}
+	return names
+}
+
+// FindDecoder はファイルパスと先頭バイト列からデコーダーを選ぶ
+// 1. マジックバイトが一致するもの（複数あれば拡張子も一致するものを優先）
+// 2. マジックバイトで判定できなければ拡張子が一致するもの
+func FindDecoder(path string, header []byte) (Decoder, error) {
+	decoderRegistry.RLock()
+	defer decoderRegistry.RUnlock()
+
+	ext := strings.ToLower(filepath.Ext(path))
+
+	var sniffed []Decoder
+	for _, d := range decoderRegistry.decoders {
+		if d.Sniff(header) {
+			sniffed = append(sniffed, d)
+		}
+	}
+	for _, d := range sniffed {
+		if hasExtension(d, ext) {
+			return d, nil
+		}
+	}
+	if len(sniffed) > 0 {
+		return sniffed[0], nil
+	}
+
+	for _, d := range decoderRegistry.decoders {
+		if hasExtension(d, ext) {
+			return d, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, path)
+}
+
+func hasExtension(d Decoder, ext string) bool {
+	for _, e := range d.Extensions() {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenStream はファイルに合うデコーダーを選んでストリームを開く
+func OpenStream(path string, r io.ReadSeeker) (PCMStream, Decoder, error) {
+	header := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, nil, fmt.Errorf("failed to read header: %v", err)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, fmt.Errorf("failed to rewind: %v", err)
+	}
+
+	decoder, err := FindDecoder(path, header[:n])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stream, err := decoder.Open(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", decoder.Name(), err)
+	}
+	if stream.Channels() <= 0 || stream.SampleRate() <= 0 {
+		return nil, nil, fmt.Errorf("%s: invalid format (SR:%d, Ch:%d)",
+			decoder.Name(), stream.SampleRate(), stream.Channels())
+	}
+
+	return stream, decoder, nil
+}
+
+// ReadAll はストリームを最後まで読み込む
+func ReadAll(stream PCMStream) ([]float32, error) {
+	return ReadFrames(stream, -1)
+}
+
+// ReadFrames はストリームから最大 maxFrames フレーム読み込む（負なら最後まで）
+func ReadFrames(stream PCMStream, maxFrames int64) ([]float32, error) {
+	// 長さが分かる場合は最初に確保しておく（再確保を避ける）
+	frames := stream.Length()
+	if maxFrames >= 0 && (frames < 0 || maxFrames < frames) {
+		frames = maxFrames
+	}
+	capacity := 0
+	if frames > 0 {
+		capacity = int(frames) * stream.Channels()
+	}
+	data := make([]float32, 0, capacity)
+
+	chunk := make([]float32, 4096*stream.Channels())
+	for maxFrames < 0 || int64(len(data)) < maxFrames*int64(stream.Channels()) {
+		n, err := stream.Read(chunk)
+		data = append(data, chunk[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if maxFrames >= 0 && int64(len(data)) > maxFrames*int64(stream.Channels()) {
+		data = data[:maxFrames*int64(stream.Channels())]
+	}
+
+	// 途中で切れたフレームは捨てる
+	data = data[:len(data)-len(data)%stream.Channels()]
+	return data, nil
+}
+
+func init() {
+	RegisterDecoder(wavDecoder{})
+	RegisterDecoder(flacDecoder{})
+	RegisterDecoder(mp3Decoder{})
+	RegisterDecoder(oggDecoder{})
+}
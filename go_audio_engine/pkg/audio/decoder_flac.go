@@ -0,0 +1,124 @@
+package audio
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/mewkiz/flac"
+)
+
+// flacDecoder はFLAC用デコーダー（pure Go）
+type flacDecoder struct{}
+
+func (flacDecoder) Name() string { return "flac" }
+
+func (flacDecoder) Extensions() []string { return []string{".flac"} }
+
+func (flacDecoder) Sniff(header []byte) bool {
+	return bytes.HasPrefix(header, []byte("fLaC"))
+}
+
+func (flacDecoder) Open(r io.ReadSeeker) (PCMStream, error) {
+	stream, err := flac.NewSeek(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FLAC file: %v", err)
+	}
+	return &flacStream{stream: stream}, nil
+}
+
+// flacStream はFLACフレームを1つずつデコードして返す
+type flacStream struct {
+	stream   *flac.Stream
+	frameBuf []float32 // デコード用バッファ（フレームごとに再利用）
+	pending  []float32 // デコード済みで未読のサンプル（インターリーブ）
+	skip     int       // シーク後に読み捨てるサンプル数
+	eof      bool      // シークで終端を越えた
+}
+
+func (s *flacStream) SampleRate() int { return int(s.stream.Info.SampleRate) }
+
+func (s *flacStream) Channels() int { return int(s.stream.Info.NChannels) }
+
+func (s *flacStream) Length() int64 {
+	if s.stream.Info.NSamples == 0 {
+		return -1
+	}
+	return int64(s.stream.Info.NSamples)
+}
+
+// Seek はフレーム（FLACのブロック）単位でしか移動できないので、端数は読み捨てる
+func (s *flacStream) SeekFrame(frame int64) error {
+	s.pending = nil
+	s.skip = 0
+	s.eof = false
+	if n := s.Length(); n > 0 && frame >= n {
+		s.eof = true
+		return nil
+	}
+
+	start, err := s.stream.Seek(uint64(frame))
+	if err != nil {
+		return fmt.Errorf("failed to seek FLAC stream: %v", err)
+	}
+	s.skip = int(frame-int64(start)) * s.Channels()
+	return nil
+}
+
+func (s *flacStream) Read(dst []float32) (int, error) {
+	if s.eof {
+		return 0, io.EOF
+	}
+	n := 0
+	for n < len(dst) {
+		if len(s.pending) == 0 {
+			if err := s.decodeFrame(); err != nil {
+				if err == io.EOF && n > 0 {
+					return n, nil
+				}
+				return n, err
+			}
+		}
+		if s.skip > 0 {
+			skipped := min(s.skip, len(s.pending))
+			s.pending = s.pending[skipped:]
+			s.skip -= skipped
+			continue
+		}
+		copied := copy(dst[n:], s.pending)
+		s.pending = s.pending[copied:]
+		n += copied
+	}
+	return n, nil
+}
+
+// decodeFrame は次のフレームをデコードして pending に詰める
+func (s *flacStream) decodeFrame() error {
+	frame, err := s.stream.ParseNext()
+	if err == io.EOF {
+		return io.EOF
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decode FLAC frame: %v", err)
+	}
+
+	bitsPerSample := frame.BitsPerSample
+	if bitsPerSample == 0 {
+		bitsPerSample = s.stream.Info.BitsPerSample
+	}
+	scale := float32(int64(1) << (bitsPerSample - 1))
+
+	channels := len(frame.Subframes)
+	blockSize := int(frame.BlockSize)
+	if cap(s.frameBuf) < blockSize*channels {
+		s.frameBuf = make([]float32, blockSize*channels)
+	}
+	s.pending = s.frameBuf[:blockSize*channels]
+
+	for ch, sub := range frame.Subframes {
+		for i := 0; i < blockSize; i++ {
+			s.pending[i*channels+ch] = float32(sub.Samples[i]) / scale
+		}
+	}
+	return nil
+}
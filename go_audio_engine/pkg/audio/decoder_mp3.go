@@ -0,0 +1,87 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/hajimehoshi/go-mp3"
+)
+
+// mp3Decoder はMP3用デコーダー（pure Go）
+// go-mp3 は常に 16bit ステレオで出力する（モノラル音源も2chに展開される）
+type mp3Decoder struct{}
+
+func (mp3Decoder) Name() string { return "mp3" }
+
+func (mp3Decoder) Extensions() []string { return []string{".mp3"} }
+
+func (mp3Decoder) Sniff(header []byte) bool {
+	// ID3v2タグ付き
+	if bytes.HasPrefix(header, []byte("ID3")) {
+		return true
+	}
+	// タグなし: フレーム同期ワード（11bitすべて1）+ Layer III
+	return len(header) >= 2 && header[0] == 0xFF && header[1]&0xE0 == 0xE0 && header[1]&0x06 == 0x02
+}
+
+func (mp3Decoder) Open(r io.ReadSeeker) (PCMStream, error) {
+	decoder, err := mp3.NewDecoder(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MP3 file: %v", err)
+	}
+	return &mp3Stream{decoder: decoder}, nil
+}
+
+// mp3BytesPerFrame は go-mp3 の出力1フレームあたりのバイト数（16bit × 2ch）
+const mp3BytesPerFrame = 4
+
+// mp3Stream はデコード済みバイト列を float32 に変換して返す
+type mp3Stream struct {
+	decoder *mp3.Decoder
+	raw     []byte
+}
+
+func (s *mp3Stream) SampleRate() int { return s.decoder.SampleRate() }
+
+func (s *mp3Stream) Channels() int { return 2 }
+
+func (s *mp3Stream) Length() int64 {
+	if s.decoder.Length() < 0 {
+		return -1
+	}
+	return s.decoder.Length() / mp3BytesPerFrame
+}
+
+func (s *mp3Stream) SeekFrame(frame int64) error {
+	if _, err := s.decoder.Seek(frame*mp3BytesPerFrame, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek MP3 stream: %v", err)
+	}
+	return nil
+}
+
+func (s *mp3Stream) Read(dst []float32) (int, error) {
+	size := len(dst) * 2
+	if cap(s.raw) < size {
+		s.raw = make([]byte, size)
+	}
+	s.raw = s.raw[:size]
+
+	n, err := io.ReadFull(s.decoder, s.raw)
+	if err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	if n == 0 && err == nil {
+		err = io.EOF
+	}
+	if err != nil && err != io.EOF {
+		return 0, fmt.Errorf("failed to decode MP3: %v", err)
+	}
+
+	samples := n / 2
+	for i := 0; i < samples; i++ {
+		dst[i] = float32(int16(binary.LittleEndian.Uint16(s.raw[i*2:]))) / 32768.0
+	}
+	return samples, err
+}
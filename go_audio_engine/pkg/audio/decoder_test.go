@@ -0,0 +1,210 @@
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hajimehoshi/go-mp3"
+	"github.com/jfreymuth/oggvorbis"
+)
+
+// testdataDir はテスト用の音声ファイルの場所（generate-test-wav.js などで作成）
+const testdataDir = "../../testdata"
+
+// openFixture は testdata のファイルをデコーダーの登録から選んで開く
+func openFixture(t *testing.T, name string) (PCMStream, Decoder) {
+	t.Helper()
+	f, err := os.Open(filepath.Join(testdataDir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	stream, decoder, err := OpenStream(name, f)
+	if err != nil {
+		t.Fatalf("OpenStream(%s): %v", name, err)
+	}
+	return stream, decoder
+}
+
+// mp3Reference は go-mp3 で直接デコードした先頭 n サンプル
+func mp3Reference(t *testing.T, name string, n int) []float32 {
+	t.Helper()
+	f, err := os.Open(filepath.Join(testdataDir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	decoder, err := mp3.NewDecoder(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := make([]byte, n*2)
+	if _, err := io.ReadFull(decoder, raw); err != nil {
+		t.Fatal(err)
+	}
+	samples := make([]float32, n)
+	for i := range samples {
+		samples[i] = float32(int16(binary.LittleEndian.Uint16(raw[i*2:]))) / 32768
+	}
+	return samples
+}
+
+// oggReference は oggvorbis で直接デコードした先頭 n サンプル
+func oggReference(t *testing.T, name string, n int) []float32 {
+	t.Helper()
+	f, err := os.Open(filepath.Join(testdataDir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	samples, _, err := oggvorbis.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return samples[:n]
+}
+
+// sine は frame フレーム目のサイン波の値
+func sine(freq float64, sampleRate, frame int) float64 {
+	return math.Sin(2 * math.Pi * freq * float64(frame) / float64(sampleRate))
+}
+
+func TestDecoders(t *testing.T) {
+	const firstSamples = 4096 // MP3 は先頭の数百サンプルが無音（デコーダーの遅延）なので、長めに比べる
+
+	tests := []struct {
+		file       string
+		decoder    string
+		sampleRate int
+		channels   int
+		frames     int64 // 総フレーム数（-1 なら確認しない）
+		want       func(t *testing.T) []float32
+		tolerance  float64
+	}{
+		{
+			// generate-test-wav.js：440Hz、振幅 0.3、16bit ステレオ
+			file: "tone_440hz.wav", decoder: "wav", sampleRate: 44100, channels: 2, frames: 44100 * 5,
+			want: func(t *testing.T) []float32 {
+				want := make([]float32, firstSamples)
+				for i := range want {
+					want[i] = float32(math.Floor(32767*0.3*sine(440, 44100, i/2)) / 32768)
+				}
+				return want
+			},
+			tolerance: 1.0 / 32768,
+		},
+		{
+			// FLACWriter で作成：440Hz、48kHz、16bit、左 0.5 倍・右 -0.25 倍
+			file: "tone_440hz_48k.flac", decoder: "flac", sampleRate: 48000, channels: 2, frames: 48000 / 4,
+			want: func(t *testing.T) []float32 {
+				want := make([]float32, firstSamples)
+				for i := range want {
+					gain := 0.5
+					if i%2 == 1 {
+						gain = -0.25
+					}
+					want[i] = float32(gain * sine(440, 48000, i/2))
+				}
+				return want
+			},
+			tolerance: 1.0 / 32768,
+		},
+		{
+			// go-mp3 の example/mpeg2.mp3 から 40 フレームを切り出したもの（MPEG-2 Layer III）
+			file: "mpeg2_22050hz.mp3", decoder: "mp3", sampleRate: 22050, channels: 2, frames: -1,
+			want: func(t *testing.T) []float32 {
+				return mp3Reference(t, "mpeg2_22050hz.mp3", firstSamples)
+			},
+		},
+		{
+			// oggvorbis の testdata/test.ogg（1秒、モノラル）
+			file: "vorbis_mono.ogg", decoder: "ogg", sampleRate: 44100, channels: 1, frames: 44100,
+			want: func(t *testing.T) []float32 {
+				return oggReference(t, "vorbis_mono.ogg", firstSamples)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			stream, decoder := openFixture(t, tt.file)
+			if decoder.Name() != tt.decoder {
+				t.Errorf("decoder = %s, want %s", decoder.Name(), tt.decoder)
+			}
+			if stream.SampleRate() != tt.sampleRate {
+				t.Errorf("sample rate = %d, want %d", stream.SampleRate(), tt.sampleRate)
+			}
+			if stream.Channels() != tt.channels {
+				t.Errorf("channels = %d, want %d", stream.Channels(), tt.channels)
+			}
+
+			data, err := ReadAll(stream)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.frames >= 0 && int64(len(data)/tt.channels) != tt.frames {
+				t.Errorf("frames = %d, want %d", len(data)/tt.channels, tt.frames)
+			}
+
+			want := tt.want(t)
+			peak := 0.0
+			for i, w := range want {
+				if math.Abs(float64(data[i]-w)) > tt.tolerance {
+					t.Fatalf("sample %d = %v, want %v", i, data[i], w)
+				}
+			}
+			for _, v := range data {
+				peak = math.Max(peak, math.Abs(float64(v)))
+			}
+			if peak < 0.01 || peak > 1 {
+				t.Errorf("peak = %v, want audible and within ±1.0", peak)
+			}
+		})
+	}
+}
+
+// TestFindDecoderSniff はマジックバイトが拡張子より優先されることを確認する
+func TestFindDecoderSniff(t *testing.T) {
+	tests := []struct {
+		file    string
+		decoder string
+	}{
+		{"tone_440hz.wav", "wav"},
+		{"tone_440hz_48k.flac", "flac"},
+		{"mpeg2_22050hz.mp3", "mp3"},
+		{"vorbis_mono.ogg", "ogg"},
+	}
+
+	for _, tt := range tests {
+		header, err := os.ReadFile(filepath.Join(testdataDir, tt.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		header = header[:sniffLen]
+
+		// 拡張子が違っていても、中身で選ぶ
+		for _, path := range []string{tt.file, "renamed.bin", "renamed.wav"} {
+			decoder, err := FindDecoder(path, header)
+			if err != nil {
+				t.Fatalf("FindDecoder(%s): %v", path, err)
+			}
+			if decoder.Name() != tt.decoder {
+				t.Errorf("FindDecoder(%s) for %s = %s, want %s", path, tt.file, decoder.Name(), tt.decoder)
+			}
+		}
+	}
+
+	// 中身で判定できなければ拡張子で選ぶ
+	decoder, err := FindDecoder("unknown.flac", []byte("????"))
+	if err != nil || decoder.Name() != "flac" {
+		t.Errorf("FindDecoder by extension = %v, %v, want flac", decoder, err)
+	}
+	if _, err := FindDecoder("unknown.xyz", []byte("????")); err == nil {
+		t.Error("FindDecoder for unknown format succeeded")
+	}
+}
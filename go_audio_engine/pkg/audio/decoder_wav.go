@@ -0,0 +1,291 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// WAVフォーマットタグ（fmtチャンクの wFormatTag）
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatIEEEFloat  = 0x0003
+	wavFormatExtensible = 0xFFFE
+)
+
+// wavMaxFmtSize はfmtチャンクとして受け付ける最大のバイト数
+// WAVE_FORMAT_EXTENSIBLE でも 40 バイトなので、それより大きいものは壊れているか細工されたファイル
+// （サイズをそのまま信じると 4GB を確保させられる）
+const wavMaxFmtSize = 64
+
+// wavDecoder はWAV（RIFF/WAVE）用デコーダー
+// 8/16/24/32bit 整数PCM と 32/64bit 浮動小数点に対応
+type wavDecoder struct{}
+
+func (wavDecoder) Name() string { return "wav" }
+
+func (wavDecoder) Extensions() []string { return []string{".wav", ".wave"} }
+
+func (wavDecoder) Sniff(header []byte) bool {
+	return len(header) >= 12 &&
+		bytes.Equal(header[0:4], []byte("RIFF")) &&
+		bytes.Equal(header[8:12], []byte("WAVE"))
+}
+
+// wavFormat はfmtチャンクの内容
+type wavFormat struct {
+	FormatTag     uint16 // 1: PCM, 3: IEEE float（EXTENSIBLEの場合はサブフォーマットを展開済み）
+	Channels      uint16
+	SampleRate    uint32
+	BlockAlign    uint16 // 1フレームのバイト数
+	BitsPerSample uint16
+	ChannelMask   uint32 // スピーカー配置（EXTENSIBLEのみ、0なら不明）
+}
+
+func (wavDecoder) Open(r io.ReadSeeker) (PCMStream, error) {
+	fileSize, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var riffHeader [12]byte
+	if _, err := io.ReadFull(r, riffHeader[:]); err != nil {
+		return nil, fmt.Errorf("invalid WAV file: %v", err)
+	}
+	if !(wavDecoder{}).Sniff(riffHeader[:]) {
+		return nil, fmt.Errorf("invalid WAV file: missing RIFF/WAVE header")
+	}
+
+	// チャンクを順に読み、fmt と data を探す
+	var format *wavFormat
+	for {
+		var chunkHeader [8]byte
+		if _, err := io.ReadFull(r, chunkHeader[:]); err != nil {
+			return nil, fmt.Errorf("invalid WAV file: data chunk not found")
+		}
+		id := string(chunkHeader[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunkHeader[4:8]))
+
+		switch id {
+		case "fmt ":
+			if size > wavMaxFmtSize || size > fileSize {
+				return nil, fmt.Errorf("invalid WAV file: fmt chunk too large (%d bytes)", size)
+			}
+			body := make([]byte, size)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return nil, fmt.Errorf("invalid WAV file: %v", err)
+			}
+			if format, err = parseWavFormat(body); err != nil {
+				return nil, err
+			}
+			if size%2 == 1 {
+				if _, err := r.Seek(1, io.SeekCurrent); err != nil {
+					return nil, err
+				}
+			}
+
+		case "data":
+			if format == nil {
+				return nil, fmt.Errorf("invalid WAV file: data chunk before fmt chunk")
+			}
+			start, err := r.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return nil, err
+			}
+			// 録音途中のファイルなどはサイズが 0 や 0xFFFFFFFF のことがある
+			if size == 0 || start+size > fileSize {
+				size = fileSize - start
+			}
+			return newWavStream(r, *format, start, size)
+
+		default:
+			// LIST / bext / smpl などは読み飛ばす（RIFFチャンクは2バイト境界）
+			if _, err := r.Seek(size+size%2, io.SeekCurrent); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// parseWavFormat はfmtチャンクを解析する
+func parseWavFormat(body []byte) (*wavFormat, error) {
+	if len(body) < 16 {
+		return nil, fmt.Errorf("invalid WAV file: fmt chunk too short")
+	}
+	format := &wavFormat{
+		FormatTag:     binary.LittleEndian.Uint16(body[0:2]),
+		Channels:      binary.LittleEndian.Uint16(body[2:4]),
+		SampleRate:    binary.LittleEndian.Uint32(body[4:8]),
+		BlockAlign:    binary.LittleEndian.Uint16(body[12:14]),
+		BitsPerSample: binary.LittleEndian.Uint16(body[14:16]),
+	}
+
+	// WAVE_FORMAT_EXTENSIBLE: サブフォーマットGUIDの先頭2バイトが実際のフォーマットタグ
+	if format.FormatTag == wavFormatExtensible {
+		if len(body) < 26 {
+			return nil, fmt.Errorf("invalid WAV file: truncated WAVE_FORMAT_EXTENSIBLE header")
+		}
+		format.ChannelMask = binary.LittleEndian.Uint32(body[20:24])
+		format.FormatTag = binary.LittleEndian.Uint16(body[24:26])
+	}
+
+	if format.Channels == 0 {
+		return nil, fmt.Errorf("invalid WAV file: no channels")
+	}
+	return format, nil
+}
+
+// wavSampleDecoder は1サンプル分のバイト列を -1.0 ～ 1.0 に変換する関数
+type wavSampleDecoder func(b []byte) float32
+
+// wavSampleDecoderFor はフォーマットタグとコンテナサイズから変換関数を選ぶ
+// 20bit を 24bit コンテナに入れたファイルなどは左詰めなので、コンテナサイズで正規化すれば良い
+func wavSampleDecoderFor(formatTag uint16, bytesPerSample int) (wavSampleDecoder, error) {
+	switch formatTag {
+	case wavFormatPCM:
+		switch bytesPerSample {
+		case 1:
+			// 8bit は符号なし（無音が128）
+			return func(b []byte) float32 {
+				return (float32(b[0]) - 128) / 128.0
+			}, nil
+		case 2:
+			return func(b []byte) float32 {
+				return float32(int16(binary.LittleEndian.Uint16(b))) / 32768.0
+			}, nil
+		case 3:
+			return func(b []byte) float32 {
+				// 24bit → int32 に符号拡張
+				v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+				return float32(v) / 8388608.0
+			}, nil
+		case 4:
+			return func(b []byte) float32 {
+				return float32(float64(int32(binary.LittleEndian.Uint32(b))) / 2147483648.0)
+			}, nil
+		}
+
+	case wavFormatIEEEFloat:
+		switch bytesPerSample {
+		case 4:
+			return func(b []byte) float32 {
+				return math.Float32frombits(binary.LittleEndian.Uint32(b))
+			}, nil
+		case 8:
+			return func(b []byte) float32 {
+				return float32(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+			}, nil
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported WAV format tag: 0x%04X", formatTag)
+	}
+
+	return nil, fmt.Errorf("unsupported WAV sample size: %d bytes (format tag 0x%04X)", bytesPerSample, formatTag)
+}
+
+// wavStream はWAVのdataチャンクを少しずつ読み出す
+type wavStream struct {
+	r              io.ReadSeeker
+	format         wavFormat
+	bytesPerSample int
+	decode         wavSampleDecoder
+
+	dataStart int64 // dataチャンク本体の開始オフセット
+	dataSize  int64 // dataチャンク本体のバイト数
+	remaining int64 // 未読のバイト数
+
+	raw []byte
+}
+
+func newWavStream(r io.ReadSeeker, format wavFormat, dataStart, dataSize int64) (*wavStream, error) {
+	// BlockAlign が壊れているファイルもあるので、ビット深度からも計算して大きい方を使う
+	bytesPerSample := int(format.BlockAlign) / int(format.Channels)
+	if fromBits := (int(format.BitsPerSample) + 7) / 8; fromBits > bytesPerSample {
+		bytesPerSample = fromBits
+	}
+
+	decode, err := wavSampleDecoderFor(format.FormatTag, bytesPerSample)
+	if err != nil {
+		return nil, err
+	}
+
+	bytesPerFrame := int64(bytesPerSample) * int64(format.Channels)
+	dataSize -= dataSize % bytesPerFrame
+
+	return &wavStream{
+		r:              r,
+		format:         format,
+		bytesPerSample: bytesPerSample,
+		decode:         decode,
+		dataStart:      dataStart,
+		dataSize:       dataSize,
+		remaining:      dataSize,
+	}, nil
+}
+
+func (s *wavStream) SampleRate() int { return int(s.format.SampleRate) }
+
+func (s *wavStream) Channels() int { return int(s.format.Channels) }
+
+func (s *wavStream) Length() int64 {
+	return s.dataSize / (int64(s.bytesPerSample) * int64(s.format.Channels))
+}
+
+func (s *wavStream) ChannelLayout() []Speaker {
+	if s.format.ChannelMask != 0 {
+		if layout := layoutFromChannelMask(s.format.ChannelMask, s.Channels()); layout != nil {
+			return layout
+		}
+	}
+	return DefaultChannelLayout(s.Channels())
+}
+
+func (s *wavStream) SeekFrame(frame int64) error {
+	offset := frame * int64(s.bytesPerSample) * int64(s.format.Channels)
+	if offset < 0 || offset > s.dataSize {
+		return fmt.Errorf("seek out of range: frame %d", frame)
+	}
+	if _, err := s.r.Seek(s.dataStart+offset, io.SeekStart); err != nil {
+		return err
+	}
+	s.remaining = s.dataSize - offset
+	return nil
+}
+
+func (s *wavStream) Read(dst []float32) (int, error) {
+	if s.remaining <= 0 {
+		return 0, io.EOF
+	}
+
+	size := int64(len(dst) * s.bytesPerSample)
+	if size > s.remaining {
+		size = s.remaining
+	}
+	if int64(cap(s.raw)) < size {
+		s.raw = make([]byte, size)
+	}
+	raw := s.raw[:size]
+
+	n, err := io.ReadFull(s.r, raw)
+	s.remaining -= int64(n)
+	if err == io.ErrUnexpectedEOF {
+		// ファイルが途中で切れている場合は読めた分だけ返す
+		s.remaining = 0
+		err = nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read WAV data: %v", err)
+	}
+
+	samples := n / s.bytesPerSample
+	for i := 0; i < samples; i++ {
+		dst[i] = s.decode(raw[i*s.bytesPerSample:])
+	}
+	return samples, nil
+}
@@ -0,0 +1,109 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// TestWAVBitDepths はビット深度ごとに、正負の最大値・符号・半分の値が正しく読めることを確認する
+// fixture は generate-test-wav.js の generateBitDepth で作成（正の最大、負の最大、0、+半分、-半分）
+func TestWAVBitDepths(t *testing.T) {
+	tests := []struct {
+		file string
+		want []float32
+	}{
+		// 💡 整数PCMは正の最大が 1.0 にわずかに届かない（負の最大がちょうど -1.0）
+		{"pcm_8bit.wav", []float32{127.0 / 128, -1, 0, 0.5, -0.5}},
+		{"pcm_16bit.wav", []float32{32767.0 / 32768, -1, 0, 0.5, -0.5}},
+		{"pcm_24bit.wav", []float32{8388607.0 / 8388608, -1, 0, 0.5, -0.5}},
+		{"pcm_32bit.wav", []float32{2147483647.0 / 2147483648, -1, 0, 0.5, -0.5}},
+		{"float_32bit.wav", []float32{1, -1, 0, 0.5, -0.5}},
+		{"float_64bit.wav", []float32{1, -1, 0, 0.5, -0.5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			stream, decoder := openFixture(t, tt.file)
+			if decoder.Name() != "wav" {
+				t.Errorf("decoder = %s, want wav", decoder.Name())
+			}
+			if stream.SampleRate() != 44100 || stream.Channels() != 1 {
+				t.Errorf("format = %d Hz %d ch, want 44100 Hz 1 ch", stream.SampleRate(), stream.Channels())
+			}
+
+			data, err := ReadAll(stream)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(data) != len(tt.want) {
+				t.Fatalf("samples = %d, want %d", len(data), len(tt.want))
+			}
+			for i, w := range tt.want {
+				if data[i] != w {
+					t.Errorf("sample %d = %v, want %v", i, data[i], w)
+				}
+			}
+		})
+	}
+}
+
+// wavHeader は RIFF/WAVE ヘッダーと、指定したサイズを名乗る fmt チャンクのヘッダーだけのファイル
+func wavHeader(fmtSize uint32) []byte {
+	b := []byte("RIFF\x00\x00\x00\x00WAVEfmt ")
+	return binary.LittleEndian.AppendUint32(b, fmtSize)
+}
+
+// TestWAVRejectsOversizedFmt は fmt チャンクのサイズが大きすぎるファイルを、確保する前に弾くことを確認する
+func TestWAVRejectsOversizedFmt(t *testing.T) {
+	for _, size := range []uint32{wavMaxFmtSize + 1, 0xFFFFFFFF} {
+		data := append(wavHeader(size), make([]byte, 100)...)
+		allocs := testing.AllocsPerRun(1, func() {
+			if _, err := (wavDecoder{}).Open(bytes.NewReader(data)); err == nil {
+				t.Errorf("fmt size %d: want error", size)
+			}
+		})
+		if allocs > 10 {
+			t.Errorf("fmt size %d: %v allocations before rejecting", size, allocs)
+		}
+	}
+}
+
+// failingSeeker は n 回目以降の Seek でエラーを返す
+type failingSeeker struct {
+	*bytes.Reader
+	seeks, failAt int
+}
+
+func (s *failingSeeker) Seek(offset int64, whence int) (int64, error) {
+	s.seeks++
+	if s.seeks >= s.failAt {
+		return 0, errors.New("seek failed")
+	}
+	return s.Reader.Seek(offset, whence)
+}
+
+// TestWAVSeekErrors は fmt チャンクの後の Seek の失敗をそのまま返すことを確認する
+func TestWAVSeekErrors(t *testing.T) {
+	// 17 バイトの fmt チャンク（奇数なので1バイト読み飛ばす）と data チャンク
+	body := make([]byte, 18)
+	binary.LittleEndian.PutUint16(body[0:2], wavFormatPCM)
+	binary.LittleEndian.PutUint16(body[2:4], 1)
+	binary.LittleEndian.PutUint32(body[4:8], 44100)
+	binary.LittleEndian.PutUint16(body[12:14], 2)
+	binary.LittleEndian.PutUint16(body[14:16], 16)
+	data := append(wavHeader(17), body...)
+	data = append(data, "data\x04\x00\x00\x00\x00\x00\x00\x00"...)
+
+	if _, err := (wavDecoder{}).Open(bytes.NewReader(data)); err != nil {
+		t.Fatalf("valid file: %v", err)
+	}
+	// 1, 2 回目はファイルサイズを調べる Seek、3 回目がパディング、4 回目が data の位置
+	for _, failAt := range []int{3, 4} {
+		r := &failingSeeker{Reader: bytes.NewReader(data), failAt: failAt}
+		if _, err := (wavDecoder{}).Open(r); err == nil {
+			t.Errorf("seek %d fails: want error", failAt)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package audio
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// エコー（テンポ同期のディレイ）
+//
+// 解説：音を拍の長さ（1/16 拍 ～ 4拍）だけ遅らせて返す。遅らせた音の一部を入力に戻す（フィードバック）と、
+// 「タン、タン、タン…」とだんだん小さくなりながら繰り返す。
+// テンポが変わったり beats を変えたりしたときは、遅れの長さをなめらかに動かす（テープエコーのようにピッチが少し揺れる）
+//
+// 💡 ディレイは、一番遅いテンポ（検出される BPM の下限 BPMRangeMin を最低速度で再生）で
+// echoMaxBeats 拍を遅らせられる長さを用意する。それでも足りないとき（BPMRangeMin を後から下げたなど）は
+// 入る長さに縮めて、Clamped で知らせる
+const echoMaxBeats = 4.0 // 遅らせられる最大の拍数
+
+// echoBufferSeconds はディレイに用意する長さ（秒）
+func echoBufferSeconds() float64 {
+	slowestBeat := math.Max(60/BPMRangeMin/minSpeed, defaultBeatSeconds)
+	return echoMaxBeats * slowestBeat
+}
+
+// Echo はテンポ同期のエコー
+type Echo struct {
+	beats    *EffectParam // 遅れの長さ（拍）
+	feedback *EffectParam // 繰り返しの残り具合
+
+	sampleRate float64
+	clamped    atomic.Bool // 遅れの長さがディレイに入りきらず、縮めているか（オーディオスレッドが書く）
+
+	// 以下はオーディオスレッド専用
+	line  delayLine
+	delay *Smoother // 遅れの長さ（フレーム）
+	fresh bool      // Reset の直後か（遅れの長さをなめらかにせず、いきなり合わせる）
+}
+
+// NewEcho はエコーを作成
+func NewEcho(sampleRate float64) *Echo {
+	return &Echo{
+		beats:      newEffectParam("beats", 1.0/16, echoMaxBeats, 0.75),
+		feedback:   newEffectParam("feedback", 0, 0.95, 0.5),
+		sampleRate: sampleRate,
+		line:       newDelayLine(int(math.Ceil(sampleRate*echoBufferSeconds())) + 2),
+		delay:      NewSmoother(sampleRate, SmoothSeconds, 1),
+	}
+}
+
+// Name はエフェクトの名前
+func (e *Echo) Name() string {
+	return "echo"
+}
+
+// Params はつまみの一覧
+func (e *Echo) Params() []*EffectParam {
+	return []*EffectParam{e.beats, e.feedback}
+}
+
+// Reset はディレイの中身を消す
+func (e *Echo) Reset() {
+	e.line.reset()
+	e.fresh = true
+}
+
+// Process はエコーをかける（遅らせた音だけを出す）
+func (e *Echo) Process(samples []float32, beatSeconds float64) {
+	if e.fresh {
+		e.delay.Jump(e.delayFrames(beatSeconds))
+		e.fresh = false
+	} else {
+		e.delay.Set(e.delayFrames(beatSeconds))
+	}
+	feedback := float32(e.feedback.Get())
+
+	for i := 0; i+1 < len(samples); i += 2 {
+		l, r := e.line.read(e.delay.Next())
+		e.line.write(samples[i]+l*feedback, samples[i+1]+r*feedback)
+		samples[i], samples[i+1] = l, r
+	}
+}
+
+// Clamped は遅れの長さがディレイに入りきらず、縮めているか
+func (e *Echo) Clamped() bool {
+	return e.clamped.Load()
+}
+
+// delayFrames は今のテンポでの遅れの長さ（フレーム）
+func (e *Echo) delayFrames(beatSeconds float64) float64 {
+	frames := e.beats.Get() * beatLength(beatSeconds) * e.sampleRate
+	limit := float64(e.line.frames() - 2)
+	e.clamped.Store(frames > limit)
+	return clamp(frames, 1, limit)
+}
+
+// delayLine はステレオのディレイ（リングバッファ）
+// 💡 遅れは小数のフレームでもよい（前後のフレームを直線で補間する）
+type delayLine struct {
+	buffer []float32 // ステレオ・インターリーブ
+	pos    int       // 次に書くフレーム
+}
+
+// newDelayLine は frames フレーム分のディレイを作成
+func newDelayLine(frames int) delayLine {
+	return delayLine{buffer: make([]float32, frames*2)}
+}
+
+// frames はディレイに入るフレーム数
+func (d *delayLine) frames() int {
+	return len(d.buffer) / 2
+}
+
+// read は delay フレーム前の音を返す（1 以上、frames() 未満）
+func (d *delayLine) read(delay float64) (l, r float32) {
+	n := d.frames()
+	p := float64(d.pos) - delay
+	if p < 0 {
+		p += float64(n)
+	}
+	i0 := int(p)
+	frac := float32(p - float64(i0))
+	i1 := i0 + 1
+	if i1 >= n {
+		i1 = 0
+	}
+	l = d.buffer[i0*2] + (d.buffer[i1*2]-d.buffer[i0*2])*frac
+	r = d.buffer[i0*2+1] + (d.buffer[i1*2+1]-d.buffer[i0*2+1])*frac
+	return l, r
+}
+
+// write は1フレーム書いて進める
+func (d *delayLine) write(l, r float32) {
+	d.buffer[d.pos*2] = l
+	d.buffer[d.pos*2+1] = r
+	d.pos++
+	if d.pos >= d.frames() {
+		d.pos = 0
+	}
+}
+
+// reset はディレイの中身を消す
+func (d *delayLine) reset() {
+	clear(d.buffer)
+	d.pos = 0
+}
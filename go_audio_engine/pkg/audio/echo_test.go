@@ -0,0 +1,163 @@
+package audio
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// processBlocks は effect を 512 フレームずつかける
+func processBlocks(effect Effect, samples []float32, beatSeconds float64) {
+	for start := 0; start < len(samples); start += 512 * 2 {
+		effect.Process(samples[start:min(start+512*2, len(samples))], beatSeconds)
+	}
+}
+
+// TestEchoImpulseTiming はインパルスを入れたとき、繰り返しが拍の長さちょうどの間隔で、
+// フィードバックの割合ずつ小さくなって返ってくることを確認する
+// 💡 遅れが小数のフレームのときは前後のフレームに分かれるので、重心と合計で比べる
+func TestEchoImpulseTiming(t *testing.T) {
+	const (
+		sampleRate = 44100.0
+		feedback   = 0.5
+		repeats    = 3
+	)
+
+	tests := []struct {
+		name  string
+		bpm   float64
+		speed float64
+		beats float64
+	}{
+		{"150bpm 1/2 beat", 150, 1, 0.5}, // 0.2秒 = 8820 フレーム
+		{"128bpm 3/4 beat", 128, 1, 0.75},
+		{"174bpm 1/16 beat", 174, 1, 1.0 / 16},
+		{"120bpm pitched up", 120, 1.08, 1},
+		// 一番遅いテンポ（BPM の下限を最低速度で再生）で最大の拍数
+		{"slowest tempo 4 beats", BPMRangeMin, minSpeed, echoMaxBeats},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			beatSeconds := 60 / tt.bpm / tt.speed
+			delay := tt.beats * beatSeconds * sampleRate
+
+			e := NewEcho(sampleRate)
+			e.beats.Set(tt.beats)
+			e.feedback.Set(feedback)
+			e.Reset()
+
+			samples := make([]float32, (int(delay)*repeats+int(sampleRate/10))*2)
+			samples[0], samples[1] = 1, -1
+			processBlocks(e, samples, beatSeconds)
+
+			if e.Clamped() {
+				t.Errorf("Clamped = true, want false (delay %.0f frames)", delay)
+			}
+
+			for k := 1; k <= repeats; k++ {
+				center := delay * float64(k)
+				lo, hi := int(center)-4, int(center)+4
+				var sum, moment float64
+				for i := lo; i <= hi; i++ {
+					v := float64(samples[i*2])
+					if -float64(samples[i*2+1]) != v {
+						t.Fatalf("frame %d: right %v is not -left %v", i, samples[i*2+1], v)
+					}
+					sum += v
+					moment += v * float64(i)
+				}
+				want := math.Pow(feedback, float64(k-1))
+				if math.Abs(sum-want) > 1e-4 {
+					t.Errorf("repeat %d: level %v, want %v", k, sum, want)
+				}
+				if at := moment / sum; math.Abs(at-center) > 0.01 {
+					t.Errorf("repeat %d: at frame %.3f, want %.3f", k, at, center)
+				}
+
+				// 繰り返しの間は無音
+				gapEnd := int(center) - 4
+				gapStart := int(delay*float64(k-1)) + 4
+				for i := gapStart; i < gapEnd; i++ {
+					if samples[i*2] != 0 {
+						t.Fatalf("frame %d between repeats = %v, want 0", i, samples[i*2])
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestEchoClamped はディレイに入りきらない遅れのとき、入る長さに縮めて Clamped で知らせることを確認する
+func TestEchoClamped(t *testing.T) {
+	const sampleRate = 44100.0
+	e := NewEcho(sampleRate)
+	e.beats.Set(echoMaxBeats)
+	e.Reset()
+
+	samples := make([]float32, 512*2)
+	beatSeconds := 2 * echoBufferSeconds() / echoMaxBeats // 入る長さの2倍
+	e.Process(samples, beatSeconds)
+	if !e.Clamped() {
+		t.Error("Clamped = false for a delay longer than the buffer")
+	}
+	if got, limit := e.delay.Value(), float64(e.line.frames()-2); got != limit {
+		t.Errorf("delay = %v frames, want the buffer limit %v", got, limit)
+	}
+
+	e.Process(samples, defaultBeatSeconds)
+	if e.Clamped() {
+		t.Error("Clamped = true after returning to a normal tempo")
+	}
+}
+
+// TestEffectSlotDryWet は dry/wet 0 で原音そのまま、1 でエフェクト音そのままになることを確認する
+func TestEffectSlotDryWet(t *testing.T) {
+	const (
+		sampleRate  = 44100.0
+		beatSeconds = 0.5
+	)
+	rampFrames := int(sampleRate * SmoothSeconds)
+
+	noise := make([]float32, int(sampleRate)*2)
+	rng := rand.New(rand.NewSource(1))
+	for i := range noise {
+		noise[i] = float32(rng.Float64()*2 - 1)
+	}
+
+	// オフ、またはオンで dry/wet 0：原音そのまま
+	for _, enabled := range []bool{false, true} {
+		slot := newEffectSlot(NewEcho(sampleRate), sampleRate)
+		slot.SetEnabled(enabled)
+		slot.SetMix(0)
+		samples := append([]float32(nil), noise...)
+		for start := 0; start < len(samples); start += 512 * 2 {
+			slot.process(samples[start:min(start+512*2, len(samples))], beatSeconds)
+		}
+		for i := range samples {
+			if samples[i] != noise[i] {
+				t.Fatalf("enabled %v mix 0: sample %d = %v, want %v", enabled, i, samples[i], noise[i])
+			}
+		}
+	}
+
+	// dry/wet 1：なめらかに wet が上がりきった後は、エフェクト音そのまま
+	slot := newEffectSlot(NewEcho(sampleRate), sampleRate)
+	slot.SetEnabled(true)
+	slot.SetMix(1)
+	samples := append([]float32(nil), noise...)
+	for start := 0; start < len(samples); start += 512 * 2 {
+		slot.process(samples[start:min(start+512*2, len(samples))], beatSeconds)
+	}
+
+	reference := NewEcho(sampleRate)
+	reference.Reset()
+	want := append([]float32(nil), noise...)
+	processBlocks(reference, want, beatSeconds)
+
+	for i := rampFrames * 2; i < len(samples); i++ {
+		if math.Abs(float64(samples[i]-want[i])) > 1e-6 {
+			t.Fatalf("mix 1: sample %d = %v, want %v", i, samples[i], want[i])
+		}
+	}
+}
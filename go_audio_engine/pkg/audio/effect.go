@@ -0,0 +1,248 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync/atomic"
+)
+
+// インサートエフェクト（デッキごとのエフェクトラック）
+//
+// 解説：EQ の後ろに、エフェクトを直列につないだラック（チェーン）を置く
+//
+//	EQ → [bitcrusher] → [phaser] → [flanger] → [echo] → [reverb] → 出力
+//
+// 各エフェクトはスロットに入っていて、スロットごとにオン・オフと dry/wet（原音とエフェクト音の割合）を持つ。
+// エフェクト自身は「エフェクト音だけ（wet 100%）」を作り、原音と混ぜるのはスロットの役目。
+// つなぐ順番は API から入れ替えられる。
+//
+// 💡 テンポ同期：ディレイの長さや揺れの周期は「拍」で指定し、デッキの実際のテンポ（BPM × 再生速度）から秒にする
+const defaultBeatSeconds = 60.0 / 120.0 // テンポがわからないときの1拍（120 BPM）
+
+// Effect はインサートエフェクト
+// 💡 Process と Reset はオーディオスレッドから呼ばれる。ロックもメモリ確保もしないこと
+type Effect interface {
+	// Name は API やステータスで使う名前（"echo" など）
+	Name() string
+	// Params はつまみの一覧（API のスレッドから値を変える）
+	Params() []*EffectParam
+	// Process はステレオ・インターリーブのサンプルをエフェクト音（wet 100%）に置き換える
+	// beatSeconds は今のテンポでの1拍の長さ（秒、0 ならテンポ不明）
+	Process(samples []float32, beatSeconds float64)
+	// Reset は残響やディレイの中身を消す
+	Reset()
+}
+
+// clampReporter はつまみの値どおりにかけられないことがあるエフェクト（エコーの遅れの長さなど）
+type clampReporter interface {
+	Clamped() bool
+}
+
+// EffectParam はエフェクトのつまみ1つ
+// 💡 API のスレッドから変更され、オーディオスレッドがロックなしで読むので atomic で持つ
+type EffectParam struct {
+	Name    string
+	Min     float64
+	Max     float64
+	Default float64
+	value   atomic.Uint64 // float64 のビット列
+}
+
+// newEffectParam はつまみを作成（最初は Default の位置）
+func newEffectParam(name string, min, max, def float64) *EffectParam {
+	p := &EffectParam{Name: name, Min: min, Max: max, Default: def}
+	p.Set(def)
+	return p
+}
+
+// Set はつまみの値を設定（範囲外は Min ～ Max に収める）
+func (p *EffectParam) Set(value float64) {
+	p.value.Store(math.Float64bits(clamp(value, p.Min, p.Max)))
+}
+
+// Get はつまみの値を返す
+func (p *EffectParam) Get() float64 {
+	return math.Float64frombits(p.value.Load())
+}
+
+// beatLength は1拍の長さ（秒）を返す（テンポ不明なら 120 BPM とみなす）
+func beatLength(beatSeconds float64) float64 {
+	if beatSeconds <= 0 {
+		return defaultBeatSeconds
+	}
+	return beatSeconds
+}
+
+// EffectSlot はラックの1スロット（エフェクトと、そのオン・オフ、dry/wet）
+type EffectSlot struct {
+	Effect Effect
+
+	enabled atomic.Bool
+	mix     atomic.Uint64 // dry/wet（0.0 = 原音だけ ～ 1.0 = エフェクト音だけ、float64 のビット列）
+
+	// 以下はオーディオスレッド専用
+	mixRamp *Smoother // 実際にかける wet の割合（オフなら 0 に向かう）
+	running bool      // 直前のブロックでエフェクトを動かしたか
+	dry     []float32 // 原音の作業バッファ
+}
+
+// newEffectSlot はスロットを作成（最初はオフ、dry/wet は 0.5）
+func newEffectSlot(effect Effect, sampleRate float64) *EffectSlot {
+	s := &EffectSlot{
+		Effect:  effect,
+		mixRamp: NewSmoother(sampleRate, SmoothSeconds, 0),
+	}
+	s.SetMix(0.5)
+	return s
+}
+
+// SetEnabled はエフェクトのオン・オフを切り替える
+func (s *EffectSlot) SetEnabled(enabled bool) {
+	s.enabled.Store(enabled)
+}
+
+// IsEnabled はエフェクトがオンか
+func (s *EffectSlot) IsEnabled() bool {
+	return s.enabled.Load()
+}
+
+// SetMix は dry/wet を設定（0.0 ～ 1.0）
+func (s *EffectSlot) SetMix(mix float64) {
+	s.mix.Store(math.Float64bits(clamp(mix, 0, 1)))
+}
+
+// GetMix は dry/wet を返す
+func (s *EffectSlot) GetMix() float64 {
+	return math.Float64frombits(s.mix.Load())
+}
+
+// Clamped はエフェクトがつまみの値どおりにかけられず、範囲を縮めているか
+func (s *EffectSlot) Clamped() bool {
+	if c, ok := s.Effect.(clampReporter); ok {
+		return c.Clamped()
+	}
+	return false
+}
+
+// Param は名前からつまみを返す（なければ nil）
+func (s *EffectSlot) Param(name string) *EffectParam {
+	for _, p := range s.Effect.Params() {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// process はスロットのエフェクトをかけて、原音と混ぜる
+// 💡 オフにしてもすぐには止めず、wet が 0 になってから止める（プチッと鳴らさない）
+func (s *EffectSlot) process(samples []float32, beatSeconds float64) {
+	target := 0.0
+	if s.IsEnabled() {
+		target = s.GetMix()
+	}
+	s.mixRamp.Set(target)
+
+	if !s.mixRamp.Ramping() && s.mixRamp.Value() == 0 {
+		s.running = false
+		return
+	}
+	if !s.running {
+		s.Effect.Reset() // 前にオンにしていたときの残響を鳴らさない
+		s.running = true
+	}
+
+	if cap(s.dry) < len(samples) {
+		s.dry = make([]float32, len(samples))
+	}
+	dry := s.dry[:len(samples)]
+	copy(dry, samples)
+
+	s.Effect.Process(samples, beatSeconds)
+
+	for i := 0; i+1 < len(samples); i += 2 {
+		wet := float32(s.mixRamp.Next())
+		samples[i] = dry[i] + (samples[i]-dry[i])*wet
+		samples[i+1] = dry[i+1] + (samples[i+1]-dry[i+1])*wet
+	}
+}
+
+// EffectRack はデッキのエフェクトラック
+type EffectRack struct {
+	slots []*EffectSlot                 // すべてのスロット（作成時に決まり、以後変わらない）
+	chain atomic.Pointer[[]*EffectSlot] // かける順番（入れ替えるときは新しいスライスを作って差し替える）
+}
+
+// NewEffectRack はすべてのエフェクトを入れたラックを作成（最初はすべてオフ）
+func NewEffectRack(sampleRate float64) *EffectRack {
+	effects := []Effect{
+		NewBitcrusher(sampleRate),
+		NewPhaser(sampleRate),
+		NewFlanger(sampleRate),
+		NewEcho(sampleRate),
+		NewReverb(sampleRate),
+	}
+
+	r := &EffectRack{}
+	for _, effect := range effects {
+		r.slots = append(r.slots, newEffectSlot(effect, sampleRate))
+	}
+	chain := append([]*EffectSlot(nil), r.slots...)
+	r.chain.Store(&chain)
+	return r
+}
+
+// Slots はスロットをかける順番で返す
+func (r *EffectRack) Slots() []*EffectSlot {
+	return *r.chain.Load()
+}
+
+// Slot は名前からスロットを返す（大文字・小文字は区別しない）
+func (r *EffectRack) Slot(name string) (*EffectSlot, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, s := range r.slots {
+		if s.Effect.Name() == name {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown effect: %q (%s)", name, strings.Join(r.names(), ", "))
+}
+
+// SetOrder はエフェクトをかける順番を設定する（すべてのエフェクトを1回ずつ並べる）
+func (r *EffectRack) SetOrder(names []string) error {
+	if len(names) != len(r.slots) {
+		return fmt.Errorf("effect order must list all %d effects (%s)", len(r.slots), strings.Join(r.names(), ", "))
+	}
+	chain := make([]*EffectSlot, 0, len(names))
+	seen := make(map[*EffectSlot]bool)
+	for _, name := range names {
+		s, err := r.Slot(name)
+		if err != nil {
+			return err
+		}
+		if seen[s] {
+			return fmt.Errorf("effect %q appears twice in the order", s.Effect.Name())
+		}
+		seen[s] = true
+		chain = append(chain, s)
+	}
+	r.chain.Store(&chain)
+	return nil
+}
+
+// Process はラックのエフェクトを順番にかける
+func (r *EffectRack) Process(samples []float32, beatSeconds float64) {
+	for _, s := range *r.chain.Load() {
+		s.process(samples, beatSeconds)
+	}
+}
+
+// names はエフェクトの名前の一覧（エラーメッセージ用）
+func (r *EffectRack) names() []string {
+	names := make([]string, len(r.slots))
+	for i, s := range r.slots {
+		names[i] = s.Effect.Name()
+	}
+	return names
+}
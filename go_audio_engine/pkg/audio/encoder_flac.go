@@ -0,0 +1,130 @@
+package audio
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// flacBlockSize は1フレームあたりのサンプル数（FLAC の標準的な値）
+const flacBlockSize = 4096
+
+// FLACWriter は FLAC ファイルを書き出す（16/24bit、1 ～ 2ch）
+// 解説：サンプルを flacBlockSize ずつためて、1フレームずつエンコードする。
+// 予測方式（定数・固定次数・そのまま）はエンコーダーがフレームごとに選ぶ
+type FLACWriter struct {
+	enc        *flac.Encoder
+	sampleRate int
+	channels   int
+	bitDepth   int
+	pending    [][]int32 // チャンネルごとの、まだエンコードしていないサンプル
+	frames     int64
+}
+
+// NewFLACWriter はヘッダーを書き込み、FLACWriter を返す
+func NewFLACWriter(w io.WriteSeeker, sampleRate, channels, bitDepth int) (*FLACWriter, error) {
+	if bitDepth != 16 && bitDepth != 24 {
+		return nil, fmt.Errorf("unsupported FLAC bit depth: %d (16 or 24)", bitDepth)
+	}
+	if channels < 1 || channels > 2 || sampleRate <= 0 {
+		return nil, fmt.Errorf("invalid FLAC format: %d Hz, %d channels", sampleRate, channels)
+	}
+
+	info := &meta.StreamInfo{
+		BlockSizeMin:  flacBlockSize,
+		BlockSizeMax:  flacBlockSize,
+		SampleRate:    uint32(sampleRate),
+		NChannels:     uint8(channels),
+		BitsPerSample: uint8(bitDepth),
+	}
+	// 💡 エンコーダーは Close で書き込み先も閉じてしまうので、Write と Seek だけを見せる
+	enc, err := flac.NewEncoder(struct{ io.WriteSeeker }{w}, info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create FLAC encoder: %v", err)
+	}
+
+	pending := make([][]int32, channels)
+	for c := range pending {
+		pending[c] = make([]int32, 0, flacBlockSize)
+	}
+	return &FLACWriter{
+		enc:        enc,
+		sampleRate: sampleRate,
+		channels:   channels,
+		bitDepth:   bitDepth,
+		pending:    pending,
+	}, nil
+}
+
+// Write はサンプル（インターリーブ、-1.0 ～ 1.0）を書き込む（範囲外はクリップ）
+func (fw *FLACWriter) Write(samples []float32) error {
+	scale := float64(int32(1)<<(fw.bitDepth-1) - 1)
+	for i := 0; i+fw.channels <= len(samples); i += fw.channels {
+		for c := 0; c < fw.channels; c++ {
+			fw.pending[c] = append(fw.pending[c], quantize(samples[i+c], scale))
+		}
+		if len(fw.pending[0]) == flacBlockSize {
+			if err := fw.writeFrame(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeFrame はたまっているサンプルを1フレームとしてエンコードする
+func (fw *FLACWriter) writeFrame() error {
+	n := len(fw.pending[0])
+	if n == 0 {
+		return nil
+	}
+
+	channels := frame.ChannelsMono
+	if fw.channels == 2 {
+		channels = frame.ChannelsMidSide // 左右の相関を使って小さくする
+	}
+	subframes := make([]*frame.Subframe, fw.channels)
+	for c := range subframes {
+		subframes[c] = &frame.Subframe{
+			SubHeader: frame.SubHeader{Pred: frame.PredVerbatim},
+			Samples:   fw.pending[c],
+			NSamples:  n,
+		}
+	}
+	f := &frame.Frame{
+		Header: frame.Header{
+			HasFixedBlockSize: true,
+			BlockSize:         uint16(n),
+			SampleRate:        uint32(fw.sampleRate),
+			Channels:          channels,
+			BitsPerSample:     uint8(fw.bitDepth),
+		},
+		Subframes: subframes,
+	}
+	if err := fw.enc.WriteFrame(f); err != nil {
+		return fmt.Errorf("failed to encode FLAC frame: %v", err)
+	}
+
+	fw.frames += int64(n)
+	for c := range fw.pending {
+		fw.pending[c] = fw.pending[c][:0]
+	}
+	return nil
+}
+
+// Frames はエンコードしたフレーム数（まだためているサンプルは含まない）
+func (fw *FLACWriter) Frames() int64 {
+	return fw.frames
+}
+
+// Close は残りのサンプルを書き出し、ヘッダー（総サンプル数・MD5）を更新する
+// （元の io.WriteSeeker は閉じない）
+func (fw *FLACWriter) Close() error {
+	if err := fw.writeFrame(); err != nil {
+		return err
+	}
+	return fw.enc.Close()
+}
@@ -0,0 +1,155 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// WAVWriter は WAV（RIFF/WAVE）ファイルを書き出す
+// 16/24bit 整数PCM と 32bit 浮動小数点に対応
+//
+// 解説：ヘッダーのサイズ欄は書き終わるまで分からないので、最初は 0 で書いておき、
+// Close で先頭に戻って埋める（そのため io.WriteSeeker が必要）
+type WAVWriter struct {
+	w          io.WriteSeeker
+	channels   int
+	bitDepth   int
+	sampleRate int
+	dataBytes  int64  // data チャンクに書いたバイト数
+	buf        []byte // 変換用の作業バッファ
+}
+
+// wavHeaderSize は RIFF ヘッダー + fmt チャンク（16バイト）+ data チャンクヘッダーのサイズ
+const wavHeaderSize = 44
+
+// WAVMaxDataBytes は data チャンクに書けるバイト数の上限（サイズ欄が 32bit のため）
+const WAVMaxDataBytes = math.MaxUint32 - wavHeaderSize
+
+// NewWAVWriter はヘッダーを書き込み、WAVWriter を返す
+// bitDepth は 16, 24（整数）または 32（float）
+func NewWAVWriter(w io.WriteSeeker, sampleRate, channels, bitDepth int) (*WAVWriter, error) {
+	if bitDepth != 16 && bitDepth != 24 && bitDepth != 32 {
+		return nil, fmt.Errorf("unsupported WAV bit depth: %d (16, 24 or 32)", bitDepth)
+	}
+	if channels < 1 || sampleRate <= 0 {
+		return nil, fmt.Errorf("invalid WAV format: %d Hz, %d channels", sampleRate, channels)
+	}
+
+	wr := &WAVWriter{
+		w:          w,
+		channels:   channels,
+		bitDepth:   bitDepth,
+		sampleRate: sampleRate,
+	}
+	if err := wr.writeHeader(); err != nil {
+		return nil, err
+	}
+	return wr, nil
+}
+
+// writeHeader は今の dataBytes でヘッダーを書く
+func (wr *WAVWriter) writeHeader() error {
+	formatTag := uint16(wavFormatPCM)
+	if wr.bitDepth == 32 {
+		formatTag = wavFormatIEEEFloat
+	}
+	blockAlign := wr.channels * wr.bitDepth / 8
+
+	var h [wavHeaderSize]byte
+	copy(h[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:8], uint32(wavHeaderSize-8+wr.dataBytes+wr.dataBytes%2))
+	copy(h[8:12], "WAVE")
+	copy(h[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(h[16:20], 16)
+	binary.LittleEndian.PutUint16(h[20:22], formatTag)
+	binary.LittleEndian.PutUint16(h[22:24], uint16(wr.channels))
+	binary.LittleEndian.PutUint32(h[24:28], uint32(wr.sampleRate))
+	binary.LittleEndian.PutUint32(h[28:32], uint32(wr.sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(h[32:34], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(h[34:36], uint16(wr.bitDepth))
+	copy(h[36:40], "data")
+	binary.LittleEndian.PutUint32(h[40:44], uint32(wr.dataBytes))
+
+	_, err := wr.w.Write(h[:])
+	return err
+}
+
+// Write はサンプル（インターリーブ、-1.0 ～ 1.0）を書き込む
+// 整数PCMでは範囲外の値はクリップする
+func (wr *WAVWriter) Write(samples []float32) error {
+	bytesPerSample := wr.bitDepth / 8
+	if wr.dataBytes+int64(len(samples)*bytesPerSample) > WAVMaxDataBytes {
+		return fmt.Errorf("WAV file too large (4GB limit)")
+	}
+
+	n := len(samples) * bytesPerSample
+	if cap(wr.buf) < n {
+		wr.buf = make([]byte, n)
+	}
+	buf := wr.buf[:n]
+
+	for i, v := range samples {
+		b := buf[i*bytesPerSample:]
+		switch wr.bitDepth {
+		case 16:
+			binary.LittleEndian.PutUint16(b, uint16(int16(quantize(v, 32767))))
+		case 24:
+			s := quantize(v, 8388607)
+			b[0], b[1], b[2] = byte(s), byte(s>>8), byte(s>>16)
+		case 32:
+			binary.LittleEndian.PutUint32(b, math.Float32bits(v))
+		}
+	}
+
+	if _, err := wr.w.Write(buf); err != nil {
+		return err
+	}
+	wr.dataBytes += int64(n)
+	return nil
+}
+
+// quantize は -1.0 ～ 1.0 を ±scale の整数に丸める（範囲外はクリップ）
+func quantize(v float32, scale float64) int32 {
+	x := math.Round(float64(v) * scale)
+	if x > scale {
+		x = scale
+	}
+	if x < -scale-1 {
+		x = -scale - 1
+	}
+	return int32(x)
+}
+
+// DataBytes は書き込んだ音声データのバイト数
+func (wr *WAVWriter) DataBytes() int64 {
+	return wr.dataBytes
+}
+
+// Frames は書き込んだフレーム数
+func (wr *WAVWriter) Frames() int64 {
+	return wr.dataBytes / int64(wr.channels*wr.bitDepth/8)
+}
+
+// Close はヘッダーのサイズ欄を埋める（元の io.WriteSeeker は閉じない）
+func (wr *WAVWriter) Close() error {
+	// 💡 data チャンクが奇数バイトなら1バイト詰める（RIFF の決まり）
+	if wr.dataBytes%2 == 1 {
+		if _, err := wr.w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+	end, err := wr.w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := wr.w.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := wr.writeHeader(); err != nil {
+		return err
+	}
+	_, err = wr.w.Seek(end, io.SeekStart)
+	return err
+}
@@ -1,150 +1,250 @@
 package audio
 
 import (
+	"fmt"
 	"math"
+	"strings"
+	"sync/atomic"
 )
 
+// 3バンドEQ
+//
+// 解説：つまみ（Low / Mid / High）は同じまま、2種類の特性を切り替えられる
+//
+//	classic：  ローシェルフ + ピーキング + ハイシェルフ（±12dB）
+//	isolator： 帯域を3つに分けて音量を変える（下まで回すとキル。isolator.go を参照）
+//
+// classic の各バンドは RBJ（Audio EQ Cookbook）のバイカッド。
+// 左右のチャンネルはそれぞれ自分の入出力の履歴（x1, x2, y1, y2）を持つ（Direct Form I）。
+// 係数はゲインが変わったときだけ計算し直す（毎サンプル三角関数を計算しない）。
+// つまみを回している間は、なめらかに動くゲインに合わせて smoothBlockFrames ごとに計算し直す
+const (
+	eqMaxDB    = 12.0    // つまみを振り切ったときのゲイン（±dB）
+	eqLowFreq  = 100.0   // ローシェルフ
+	eqMidFreq  = 1000.0  // ピーキング
+	eqMidQ     = 1.0     // ピーキングの幅
+	eqHighFreq = 10000.0 // ハイシェルフ
+)
+
+// EQMode は EQ の特性
+type EQMode int
+
+const (
+	EQClassic  EQMode = iota // シェルフ + ピーキング（±12dB）
+	EQIsolator               // アイソレーター（-∞ ～ +6dB）
+)
+
+// String はAPIやステータスで使う名前を返す
+func (m EQMode) String() string {
+	if m == EQIsolator {
+		return "isolator"
+	}
+	return "classic"
+}
+
+// ParseEQMode は "classic", "isolator" から特性を返す（大文字・小文字は区別しない）
+func ParseEQMode(name string) (EQMode, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "classic":
+		return EQClassic, nil
+	case "isolator":
+		return EQIsolator, nil
+	}
+	return 0, fmt.Errorf("unknown EQ mode: %q (classic or isolator)", name)
+}
+
 // ThreeBandEQ は3バンドイコライザー
+// 💡 設定は API のスレッドから変更され、オーディオスレッドがロックなしで読むので atomic で持つ
 type ThreeBandEQ struct {
-	Low  float64 // -1.0 ～ 1.0 (0がフラット)
-	Mid  float64
-	High float64
+	low  atomic.Uint64 // -1.0 ～ 1.0 (0がフラット)、float64 のビット列
+	mid  atomic.Uint64
+	high atomic.Uint64
+
+	mode      atomic.Int32  // EQMode
+	crossover atomic.Uint64 // アイソレーターの境目（低域側・高域側を float32 のビット列で上下に詰める）
 
-	// フィルター状態（バイカッド）
-	lowState  [2]float64
-	midState  [2]float64
-	highState [2]float64
+	// 以下はオーディオスレッド専用
+	activeMode        EQMode // 直前のブロックの特性
+	lows, mids, highs eqBand
+	iso               isolator
 
 	sampleRate float64
 }
 
+// eqBand は EQ の1バンド（係数と、左右それぞれのフィルター状態）
+type eqBand struct {
+	knob   *Smoother                    // つまみの位置（なめらかに動かす）
+	design func(db float64) biquadCoefs // ゲイン（dB）から係数を計算する
+	gain   float64                      // 係数を計算したときのゲイン（-1.0 ～ 1.0）
+	coefs  biquadCoefs
+	states [2]biquadState
+}
+
 func NewThreeBandEQ(sampleRate float64) *ThreeBandEQ {
-	return &ThreeBandEQ{
-		Low:        0,
-		Mid:        0,
-		High:       0,
+	eq := &ThreeBandEQ{
 		sampleRate: sampleRate,
+		lows: eqBand{
+			knob: NewSmoother(sampleRate, SmoothSeconds, 0),
+			design: func(db float64) biquadCoefs {
+				return lowShelfCoefs(eqLowFreq, db, sampleRate)
+			},
+		},
+		mids: eqBand{
+			knob: NewSmoother(sampleRate, SmoothSeconds, 0),
+			design: func(db float64) biquadCoefs {
+				return peakingCoefs(eqMidFreq, db, eqMidQ, sampleRate)
+			},
+		},
+		highs: eqBand{
+			knob: NewSmoother(sampleRate, SmoothSeconds, 0),
+			design: func(db float64) biquadCoefs {
+				return highShelfCoefs(eqHighFreq, db, sampleRate)
+			},
+		},
+		iso: newIsolator(sampleRate),
 	}
+	eq.SetCrossovers(DefaultIsolatorLowFreq, DefaultIsolatorHighFreq)
+	return eq
 }
 
 // Process はサンプルにEQを適用
+// 💡 設定はブロックの頭で一度だけ読む（ブロックの途中で変わらない）
 func (eq *ThreeBandEQ) Process(samples []float32) {
-	for i := 0; i < len(samples); i += 2 {
-		// 左チャンネル
-		left := float64(samples[i])
-		left = eq.processLowShelf(left, 0)
-		left = eq.processPeaking(left, 1)
-		left = eq.processHighShelf(left, 2)
-		samples[i] = float32(left)
-
-		// 右チャンネル
-		if i+1 < len(samples) {
-			right := float64(samples[i+1])
-			right = eq.processLowShelf(right, 0)
-			right = eq.processPeaking(right, 1)
-			right = eq.processHighShelf(right, 2)
-			samples[i+1] = float32(right)
-		}
+	// 特性を切り替えたら、切り替えた先の状態を消してから始める
+	mode := eq.GetMode()
+	if mode != eq.activeMode {
+		eq.activeMode = mode
+		eq.lows.reset()
+		eq.mids.reset()
+		eq.highs.reset()
+		eq.iso.reset()
 	}
-}
 
-// ローシェルフフィルター（低音域）
-func (eq *ThreeBandEQ) processLowShelf(sample float64, channel int) float64 {
-	if eq.Low == 0 {
-		return sample
+	if mode == EQIsolator {
+		eq.processIsolator(samples)
+		return
 	}
 
-	// 簡易的なローシェルフ（100Hz付近）
-	freq := 100.0
-	gain := math.Pow(10, eq.Low*12/20) // ±12dB
-
-	omega := 2 * math.Pi * freq / eq.sampleRate
-	sinOmega := math.Sin(omega)
-	cosOmega := math.Cos(omega)
-	alpha := sinOmega / 2
-
-	a := math.Sqrt(gain)
-
-	b0 := a * ((a + 1) - (a-1)*cosOmega + 2*math.Sqrt(a)*alpha)
-	b1 := 2 * a * ((a - 1) - (a+1)*cosOmega)
-	b2 := a * ((a + 1) - (a-1)*cosOmega - 2*math.Sqrt(a)*alpha)
-	a0 := (a + 1) + (a-1)*cosOmega + 2*math.Sqrt(a)*alpha
-	a1 := -2 * ((a - 1) + (a+1)*cosOmega)
-	a2 := (a + 1) + (a-1)*cosOmega - 2*math.Sqrt(a)*alpha
-
-	// バイカッドフィルター
-	output := (b0/a0)*sample + (b1/a0)*eq.lowState[0] + (b2/a0)*eq.lowState[1] -
-		(a1/a0)*eq.lowState[0] - (a2/a0)*eq.lowState[1]
+	eq.lows.knob.Set(eq.GetLow())
+	eq.mids.knob.Set(eq.GetMid())
+	eq.highs.knob.Set(eq.GetHigh())
+
+	for start := 0; start < len(samples); start += smoothBlockFrames * 2 {
+		block := samples[start:min(start+smoothBlockFrames*2, len(samples))]
+		frames := len(block) / 2
+		eq.lows.update(eq.lows.knob.Advance(frames))
+		eq.mids.update(eq.mids.knob.Advance(frames))
+		eq.highs.update(eq.highs.knob.Advance(frames))
+
+		for i := 0; i+1 < len(block); i += 2 {
+			for ch := 0; ch < 2; ch++ {
+				x := float64(block[i+ch])
+				x = eq.lows.process(ch, x)
+				x = eq.mids.process(ch, x)
+				x = eq.highs.process(ch, x)
+				block[i+ch] = float32(x)
+			}
+		}
+	}
+}
 
-	eq.lowState[1] = eq.lowState[0]
-	eq.lowState[0] = sample
+// processIsolator はアイソレーターをかける
+// 💡 バンドの音量は掛けるだけなので、毎サンプルなめらかに動かす
+func (eq *ThreeBandEQ) processIsolator(samples []float32) {
+	lowFreq, highFreq := eq.GetCrossovers()
+	eq.iso.setCrossovers(lowFreq, highFreq, eq.sampleRate)
+	eq.iso.lowGain.Set(isolatorGain(eq.GetLow()))
+	eq.iso.midGain.Set(isolatorGain(eq.GetMid()))
+	eq.iso.highGain.Set(isolatorGain(eq.GetHigh()))
+
+	for i := 0; i+1 < len(samples); i += 2 {
+		low, mid, high := eq.iso.lowGain.Next(), eq.iso.midGain.Next(), eq.iso.highGain.Next()
+		for ch := 0; ch < 2; ch++ {
+			samples[i+ch] = float32(eq.iso.process(ch, float64(samples[i+ch]), low, mid, high))
+		}
+	}
+}
 
-	return output
+// reset はフィルターの状態を消す
+func (b *eqBand) reset() {
+	b.states = [2]biquadState{}
 }
 
-// ピーキングフィルター（中音域）
-func (eq *ThreeBandEQ) processPeaking(sample float64, channel int) float64 {
-	if eq.Mid == 0 {
-		return sample
+// update はゲインが変わっていたら係数を計算し直す
+func (b *eqBand) update(gain float64) {
+	if gain == b.gain {
+		return
 	}
+	b.gain = gain
+	if gain != 0 {
+		b.coefs = b.design(gain * eqMaxDB)
+	}
+}
 
-	// 1kHz付近
-	freq := 1000.0
-	gain := math.Pow(10, eq.Mid*12/20)
-	Q := 1.0
+// process は1サンプルにバンドのフィルターをかける
+// 💡 フラット（ゲイン 0）のときは計算せず、履歴だけ入力のまま進めておく
+// （フラットなバイカッドは入力をそのまま出すので、後でゲインを上げても状態がつながる）
+func (b *eqBand) process(ch int, x float64) float64 {
+	s := &b.states[ch]
+	if b.gain == 0 {
+		s.x2, s.x1 = s.x1, x
+		s.y2, s.y1 = s.y1, x
+		return x
+	}
+	return s.process(&b.coefs, x)
+}
 
-	omega := 2 * math.Pi * freq / eq.sampleRate
-	sinOmega := math.Sin(omega)
+// lowShelfCoefs はローシェルフの係数（シェルフの傾き S = 1）
+func lowShelfCoefs(freq, gainDB, sampleRate float64) biquadCoefs {
+	a := math.Pow(10, gainDB/40)
+	omega := 2 * math.Pi * freq / sampleRate
 	cosOmega := math.Cos(omega)
-	alpha := sinOmega / (2 * Q)
-
-	b0 := 1 + alpha*gain
-	b1 := -2 * cosOmega
-	b2 := 1 - alpha*gain
-	a0 := 1 + alpha/gain
-	a1 := -2 * cosOmega
-	a2 := 1 - alpha/gain
-
-	output := (b0/a0)*sample + (b1/a0)*eq.midState[0] + (b2/a0)*eq.midState[1] -
-		(a1/a0)*eq.midState[0] - (a2/a0)*eq.midState[1]
-
-	eq.midState[1] = eq.midState[0]
-	eq.midState[0] = sample
-
-	return output
+	alpha := math.Sin(omega) / 2 * math.Sqrt2
+	sqrtA := 2 * math.Sqrt(a) * alpha
+
+	a0 := (a + 1) + (a-1)*cosOmega + sqrtA
+	return biquadCoefs{
+		b0: a * ((a + 1) - (a-1)*cosOmega + sqrtA) / a0,
+		b1: 2 * a * ((a - 1) - (a+1)*cosOmega) / a0,
+		b2: a * ((a + 1) - (a-1)*cosOmega - sqrtA) / a0,
+		a1: -2 * ((a - 1) + (a+1)*cosOmega) / a0,
+		a2: ((a + 1) + (a-1)*cosOmega - sqrtA) / a0,
+	}
 }
 
-// ハイシェルフフィルター（高音域）
-func (eq *ThreeBandEQ) processHighShelf(sample float64, channel int) float64 {
-	if eq.High == 0 {
-		return sample
+// highShelfCoefs はハイシェルフの係数（シェルフの傾き S = 1）
+func highShelfCoefs(freq, gainDB, sampleRate float64) biquadCoefs {
+	a := math.Pow(10, gainDB/40)
+	omega := 2 * math.Pi * freq / sampleRate
+	cosOmega := math.Cos(omega)
+	alpha := math.Sin(omega) / 2 * math.Sqrt2
+	sqrtA := 2 * math.Sqrt(a) * alpha
+
+	a0 := (a + 1) - (a-1)*cosOmega + sqrtA
+	return biquadCoefs{
+		b0: a * ((a + 1) + (a-1)*cosOmega + sqrtA) / a0,
+		b1: -2 * a * ((a - 1) + (a+1)*cosOmega) / a0,
+		b2: a * ((a + 1) + (a-1)*cosOmega - sqrtA) / a0,
+		a1: 2 * ((a - 1) - (a+1)*cosOmega) / a0,
+		a2: ((a + 1) - (a-1)*cosOmega - sqrtA) / a0,
 	}
+}
 
-	// 10kHz付近
-	freq := 10000.0
-	gain := math.Pow(10, eq.High*12/20)
-
-	omega := 2 * math.Pi * freq / eq.sampleRate
-	sinOmega := math.Sin(omega)
+// peakingCoefs はピーキング（ベル型）の係数
+func peakingCoefs(freq, gainDB, q, sampleRate float64) biquadCoefs {
+	a := math.Pow(10, gainDB/40)
+	omega := 2 * math.Pi * freq / sampleRate
 	cosOmega := math.Cos(omega)
-	alpha := sinOmega / 2
-
-	a := math.Sqrt(gain)
-
-	b0 := a * ((a + 1) + (a-1)*cosOmega + 2*math.Sqrt(a)*alpha)
-	b1 := -2 * a * ((a - 1) + (a+1)*cosOmega)
-	b2 := a * ((a + 1) + (a-1)*cosOmega - 2*math.Sqrt(a)*alpha)
-	a0 := (a + 1) - (a-1)*cosOmega + 2*math.Sqrt(a)*alpha
-	a1 := 2 * ((a - 1) - (a+1)*cosOmega)
-	a2 := (a + 1) - (a-1)*cosOmega - 2*math.Sqrt(a)*alpha
-
-	output := (b0/a0)*sample + (b1/a0)*eq.highState[0] + (b2/a0)*eq.highState[1] -
-		(a1/a0)*eq.highState[0] - (a2/a0)*eq.highState[1]
-
-	eq.highState[1] = eq.highState[0]
-	eq.highState[0] = sample
-
-	return output
+	alpha := math.Sin(omega) / (2 * q)
+
+	a0 := 1 + alpha/a
+	return biquadCoefs{
+		b0: (1 + alpha*a) / a0,
+		b1: -2 * cosOmega / a0,
+		b2: (1 - alpha*a) / a0,
+		a1: -2 * cosOmega / a0,
+		a2: (1 - alpha/a) / a0,
+	}
 }
 
 // SetLow は低音域のゲインを設定（-1.0 ～ 1.0）
@@ -155,7 +255,7 @@ func (eq *ThreeBandEQ) SetLow(gain float64) {
 	if gain > 1.0 {
 		gain = 1.0
 	}
-	eq.Low = gain
+	eq.low.Store(math.Float64bits(gain))
 }
 
 // SetMid は中音域のゲインを設定
@@ -166,7 +266,7 @@ func (eq *ThreeBandEQ) SetMid(gain float64) {
 	if gain > 1.0 {
 		gain = 1.0
 	}
-	eq.Mid = gain
+	eq.mid.Store(math.Float64bits(gain))
 }
 
 // SetHigh は高音域のゲインを設定
@@ -177,5 +277,48 @@ func (eq *ThreeBandEQ) SetHigh(gain float64) {
 	if gain > 1.0 {
 		gain = 1.0
 	}
-	eq.High = gain
+	eq.high.Store(math.Float64bits(gain))
+}
+
+// GetLow は低音域のゲインを返す
+func (eq *ThreeBandEQ) GetLow() float64 {
+	return math.Float64frombits(eq.low.Load())
+}
+
+// GetMid は中音域のゲインを返す
+func (eq *ThreeBandEQ) GetMid() float64 {
+	return math.Float64frombits(eq.mid.Load())
+}
+
+// GetHigh は高音域のゲインを返す
+func (eq *ThreeBandEQ) GetHigh() float64 {
+	return math.Float64frombits(eq.high.Load())
+}
+
+// SetMode は EQ の特性を切り替える（つまみの値はそのまま）
+func (eq *ThreeBandEQ) SetMode(mode EQMode) {
+	eq.mode.Store(int32(mode))
+}
+
+// GetMode は EQ の特性を返す
+func (eq *ThreeBandEQ) GetMode() EQMode {
+	return EQMode(eq.mode.Load())
+}
+
+// SetCrossovers はアイソレーターの境目（Hz）を設定する
+// 💡 2つの境目は一緒に変わらないとおかしな帯域になるので、1つの atomic にまとめて入れる
+func (eq *ThreeBandEQ) SetCrossovers(lowFreq, highFreq float64) error {
+	maxFreq := min(IsolatorMaxFreq, eq.sampleRate*0.45)
+	if lowFreq < IsolatorMinFreq || highFreq > maxFreq || lowFreq >= highFreq {
+		return fmt.Errorf("invalid crossover frequencies: %.0f Hz / %.0f Hz (%.0f - %.0f Hz, low < high)",
+			lowFreq, highFreq, IsolatorMinFreq, maxFreq)
+	}
+	eq.crossover.Store(uint64(math.Float32bits(float32(lowFreq)))<<32 | uint64(math.Float32bits(float32(highFreq))))
+	return nil
+}
+
+// GetCrossovers はアイソレーターの境目（Hz）を返す
+func (eq *ThreeBandEQ) GetCrossovers() (lowFreq, highFreq float64) {
+	bits := eq.crossover.Load()
+	return float64(math.Float32frombits(uint32(bits >> 32))), float64(math.Float32frombits(uint32(bits)))
 }
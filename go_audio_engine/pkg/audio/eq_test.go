@@ -0,0 +1,125 @@
+package audio
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+// biquadResponse はバイカッドの freq（Hz）での周波数応答（複素数）
+func biquadResponse(c biquadCoefs, freq, sampleRate float64) complex128 {
+	z := cmplx.Exp(complex(0, -2*math.Pi*freq/sampleRate)) // z^-1
+	num := complex(c.b0, 0) + complex(c.b1, 0)*z + complex(c.b2, 0)*z*z
+	den := 1 + complex(c.a1, 0)*z + complex(c.a2, 0)*z*z
+	return num / den
+}
+
+// magnitudeDB はバイカッドの freq（Hz）での利得（dB）
+func magnitudeDB(c biquadCoefs, freq, sampleRate float64) float64 {
+	return 20 * math.Log10(cmplx.Abs(biquadResponse(c, freq, sampleRate)))
+}
+
+// peakingEdges は RBJ のピーキングで、利得がピークの半分（dB）になる上下の周波数
+// 解説：アナログの原型 H(s) = (s² + s·A/Q + 1) / (s² + s/(A·Q) + 1) では、|H|² = A² になるのは
+// |1 - Ω²| = Ω/Q のとき（ゲインによらない）。Cookbook は双一次変換 Ω = tan(ω/2) / tan(ω0/2) なので、
+// それをデジタルの周波数に戻す
+func peakingEdges(freq, q, sampleRate float64) (lower, upper float64) {
+	warp := math.Tan(math.Pi * freq / sampleRate)
+	toHz := func(omega float64) float64 {
+		return math.Atan(omega*warp) * sampleRate / math.Pi
+	}
+	root := math.Sqrt(1/(q*q) + 4)
+	return toHz((root - 1/q) / 2), toHz((root + 1/q) / 2)
+}
+
+// TestEQBandResponse は各バンドのバイカッドの利得を、中心・コーナー周波数と両端で確認する
+// 基準値（RBJ Audio EQ Cookbook の設計どおりの値）：
+//
+//	シェルフ：コーナー周波数でちょうど半分の dB、効く側の端（直流 / ナイキスト）で全量、反対側の端で 0dB
+//	ピーキング：中心でちょうど全量、帯域の端で半分の dB、直流とナイキストで 0dB
+func TestEQBandResponse(t *testing.T) {
+	const (
+		sampleRate = 44100.0
+		nyquist    = sampleRate / 2
+		tolerance  = 0.01 // dB
+	)
+	lower, upper := peakingEdges(eqMidFreq, eqMidQ, sampleRate)
+
+	for _, gain := range []float64{-eqMaxDB, -6, 6, eqMaxDB} {
+		tests := []struct {
+			name  string
+			coefs biquadCoefs
+			freq  float64
+			want  float64
+		}{
+			{"low shelf corner", lowShelfCoefs(eqLowFreq, gain, sampleRate), eqLowFreq, gain / 2},
+			{"low shelf DC", lowShelfCoefs(eqLowFreq, gain, sampleRate), 0, gain},
+			{"low shelf nyquist", lowShelfCoefs(eqLowFreq, gain, sampleRate), nyquist, 0},
+			{"peaking centre", peakingCoefs(eqMidFreq, gain, eqMidQ, sampleRate), eqMidFreq, gain},
+			{"peaking lower edge", peakingCoefs(eqMidFreq, gain, eqMidQ, sampleRate), lower, gain / 2},
+			{"peaking upper edge", peakingCoefs(eqMidFreq, gain, eqMidQ, sampleRate), upper, gain / 2},
+			{"peaking DC", peakingCoefs(eqMidFreq, gain, eqMidQ, sampleRate), 0, 0},
+			{"peaking nyquist", peakingCoefs(eqMidFreq, gain, eqMidQ, sampleRate), nyquist, 0},
+			{"high shelf corner", highShelfCoefs(eqHighFreq, gain, sampleRate), eqHighFreq, gain / 2},
+			{"high shelf nyquist", highShelfCoefs(eqHighFreq, gain, sampleRate), nyquist, gain},
+			{"high shelf DC", highShelfCoefs(eqHighFreq, gain, sampleRate), 0, 0},
+		}
+		for _, tt := range tests {
+			if got := magnitudeDB(tt.coefs, tt.freq, sampleRate); math.Abs(got-tt.want) > tolerance {
+				t.Errorf("%+.0f dB %s (%.1f Hz) = %.3f dB, want %.3f dB", gain, tt.name, tt.freq, got, tt.want)
+			}
+		}
+	}
+}
+
+// measureGainDB は process（EQ やフィルター）にサイン波を通して、入力に対する出力の利得（dB）を測る
+// 💡 つまみのなめらかな動きとフィルターの過渡応答が終わった後半だけを測る
+func measureGainDB(process func([]float32), freq float64, sampleRate int) float64 {
+	frames := sampleRate // 1秒
+	samples := make([]float32, frames*2)
+	for i := 0; i < frames; i++ {
+		v := float32(0.25 * sine(freq, sampleRate, i))
+		samples[i*2], samples[i*2+1] = v, v
+	}
+	input := append([]float32(nil), samples...)
+	for start := 0; start < len(samples); start += 512 * 2 {
+		process(samples[start:min(start+512*2, len(samples))])
+	}
+
+	var in, out float64
+	for i := len(samples) / 2; i < len(samples); i++ {
+		in += float64(input[i]) * float64(input[i])
+		out += float64(samples[i]) * float64(samples[i])
+	}
+	return 10 * math.Log10(out/in)
+}
+
+// TestThreeBandEQKnobs はつまみを振り切ったときに、各バンドの中心・コーナー周波数が
+// 期待どおりの利得になることを、実際に音を通して確認する
+func TestThreeBandEQKnobs(t *testing.T) {
+	const (
+		sampleRate = 44100
+		tolerance  = 0.1 // dB（測定の窓の端数の分だけ緩める）
+	)
+
+	tests := []struct {
+		name string
+		set  func(eq *ThreeBandEQ, knob float64)
+		freq float64
+		want float64 // つまみ 1.0 での利得（dB）
+	}{
+		{"low", (*ThreeBandEQ).SetLow, eqLowFreq, eqMaxDB / 2},
+		{"mid", (*ThreeBandEQ).SetMid, eqMidFreq, eqMaxDB},
+		{"high", (*ThreeBandEQ).SetHigh, eqHighFreq, eqMaxDB / 2},
+	}
+
+	for _, tt := range tests {
+		for _, knob := range []float64{-1, 0, 1} {
+			eq := NewThreeBandEQ(sampleRate)
+			tt.set(eq, knob)
+			if got := measureGainDB(eq.Process, tt.freq, sampleRate); math.Abs(got-tt.want*knob) > tolerance {
+				t.Errorf("%s knob %+.0f at %.0f Hz = %.3f dB, want %.3f dB", tt.name, knob, tt.freq, got, tt.want*knob)
+			}
+		}
+	}
+}
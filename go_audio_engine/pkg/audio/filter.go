@@ -1,121 +1,258 @@
 package audio
 
-import "math"
+import (
+	"math"
+	"sync/atomic"
+)
 
-// Filter はオーディオフィルター
-type Filter struct {
-	Type      string  // "lowpass", "highpass", "none"
-	Cutoff    float64 // 0.0 ～ 1.0
+// DJフィルター（1つのつまみでローパス ↔ ハイパス）
+//
+// 解説：つまみ（Color）は -1.0 ～ 1.0 の1つだけ
+//
+//	左に回す（マイナス）：ローパス。回すほどカットオフが下がって、こもった音になる
+//	真ん中（0）：        素通し
+//	右に回す（プラス）：  ハイパス。回すほどカットオフが上がって、シャカシャカした音になる
+//
+// フィルターは ZDF（ゼロ遅延フィードバック）のステートバリアブルフィルター（Cytomic の SVF）。
+// 1回の計算でローパスとハイパスが両方取れて、カットオフを動かし続けても発振・破綻しない。
+// 係数はつまみが動いたときだけ、smoothBlockFrames ごとに計算し直す。
+//
+// 💡 真ん中付近（filterFadeWidth まで）は原音とフィルターの出力を混ぜて、素通しから自然につなぐ
+//
+// 以前の API（SetLowpass / SetHighpass：type・cutoff・resonance）は、以前と同じ意味のまま使える。
+// cutoff は 20Hz ～ 20kHz に直線で対応し、Q は 1 ～ 10（legacyQ）。つまみはその周波数になる位置に回す
+const (
+	FilterMinFreq = 20.0    // つまみを振り切ったときのカットオフ（ローパス側）
+	FilterMaxFreq = 20000.0 // つまみを振り切ったときのカットオフ（ハイパス側）
+
+	filterMinQ      = math.Sqrt2 / 2 // レゾナンス 0（バタワース、山なし）
+	filterMaxQ      = 8.0            // レゾナンス 1
+	filterFadeWidth = 0.1            // 真ん中から、原音とフィルターの出力を混ぜる幅
+
+	legacyMinQ = 1.0  // 以前の API のレゾナンス 0
+	legacyMaxQ = 10.0 // 以前の API のレゾナンス 1
+)
+
+// FilterSettings はフィルターの設定
+type FilterSettings struct {
+	Color     float64 // -1.0 ～ 1.0（マイナスでローパス、プラスでハイパス、0 で素通し）
 	Resonance float64 // 0.0 ～ 1.0
+}
+
+// Type はつまみの位置から "lowpass", "highpass", "none" を返す
+func (s FilterSettings) Type() string {
+	switch {
+	case s.Color < 0:
+		return "lowpass"
+	case s.Color > 0:
+		return "highpass"
+	}
+	return "none"
+}
+
+// Frequency はつまみの位置のカットオフ周波数（Hz）を返す（素通しのときは 0）
+// 💡 耳に合わせて、つまみの角度に対して周波数が指数的に変わる
+func (s FilterSettings) Frequency() float64 {
+	amount := math.Abs(s.Color)
+	switch {
+	case s.Color < 0:
+		return FilterMaxFreq * math.Pow(FilterMinFreq/FilterMaxFreq, amount)
+	case s.Color > 0:
+		return FilterMinFreq * math.Pow(FilterMaxFreq/FilterMinFreq, amount)
+	}
+	return 0
+}
+
+// Filter は DJ フィルター
+// 💡 設定は API のスレッドから変更され、オーディオスレッドがロックなしで読むので atomic で持つ
+type Filter struct {
+	color     atomic.Uint64 // float64 のビット列
+	resonance atomic.Uint64
+	legacyQ   atomic.Uint64 // 以前の API で設定した Q（float64 のビット列、0 ならつまみの Color・Resonance で動く）
 
 	sampleRate float64
 
-	// フィルター状態
-	buf0 [2]float64
-	buf1 [2]float64
+	// 以下はオーディオスレッド専用
+	colorRamp     *Smoother // つまみを回したときになめらかに動かす
+	resonanceRamp *Smoother
+	active        bool    // 直前のサンプルでフィルターをかけたか
+	highpass      bool    // 今の係数がハイパス側か
+	coefColor     float64 // 係数を計算したときのつまみ
+	coefQ         float64
+	coefs         svfCoefs
+	states        [2]svfState // 左右それぞれの状態
+}
+
+// svfCoefs は SVF の係数
+type svfCoefs struct {
+	a1, a2, a3 float64
+	k          float64 // 1/Q（小さいほどカットオフに山ができる）
+}
+
+// svfState は SVF の状態（2つの積分器に残っている値）
+type svfState struct {
+	ic1eq, ic2eq float64
 }
 
 func NewFilter(sampleRate float64) *Filter {
 	return &Filter{
-		Type:       "none",
-		Cutoff:     0.5,
-		Resonance:  0.0,
-		sampleRate: sampleRate,
+		sampleRate:    sampleRate,
+		colorRamp:     NewSmoother(sampleRate, SmoothSeconds, 0),
+		resonanceRamp: NewSmoother(sampleRate, SmoothSeconds, 0),
+	}
+}
+
+// Settings は現在の設定を返す
+func (f *Filter) Settings() FilterSettings {
+	return FilterSettings{
+		Color:     f.GetColor(),
+		Resonance: f.GetResonance(),
 	}
 }
 
 // Process はフィルターを適用
 func (f *Filter) Process(samples []float32) {
-	if f.Type == "none" {
+	f.colorRamp.Set(f.GetColor())
+	f.resonanceRamp.Set(f.GetResonance())
+	legacyQ := math.Float64frombits(f.legacyQ.Load())
+
+	// 真ん中で止まっていれば素通し（次にかけるときは状態を作り直す）
+	if !f.colorRamp.Ramping() && f.colorRamp.Value() == 0 {
+		f.active = false
+		f.resonanceRamp.Jump(f.resonanceRamp.Target())
 		return
 	}
 
-	for i := 0; i < len(samples); i += 2 {
-		// 左チャンネル
-		samples[i] = float32(f.processChannel(float64(samples[i]), 0))
+	for i := 0; i+1 < len(samples); i += 2 {
+		color, resonance := f.colorRamp.Next(), f.resonanceRamp.Next()
+		// 💡 ローパスとハイパスが入れ替わるときは、ブロックの途中でもすぐに切り替える
+		if (i/2)%smoothBlockFrames == 0 || (color > 0) != f.highpass {
+			f.update(color, resonance, legacyQ, samples[i:i+2])
+		}
 
-		// 右チャンネル
-		if i+1 < len(samples) {
-			samples[i+1] = float32(f.processChannel(float64(samples[i+1]), 1))
+		// 💡 以前の API では原音と混ぜない（以前と同じく、指定したカットオフのフィルターだけを通す）
+		wet := 1.0
+		if legacyQ == 0 {
+			wet = min(math.Abs(color)/filterFadeWidth, 1)
+		}
+		for ch := 0; ch < 2; ch++ {
+			x := float64(samples[i+ch])
+			low, high := f.states[ch].process(&f.coefs, x)
+			y := low
+			if f.highpass {
+				y = high
+			}
+			// 💡 ここではクリップしない（音量の上限はマスターのリミッターで守る）
+			samples[i+ch] = float32(x + wet*(y-x))
 		}
 	}
 }
 
-func (f *Filter) processChannel(sample float64, channel int) float64 {
-	// カットオフ周波数を計算（20Hz - 20kHz）
-	minFreq := 20.0
-	maxFreq := 20000.0
-	freq := minFreq + (maxFreq-minFreq)*f.Cutoff
-
-	// 正規化された周波数
-	omega := 2 * math.Pi * freq / f.sampleRate
-	sinOmega := math.Sin(omega)
-	cosOmega := math.Cos(omega)
-
-	// レゾナンス（Q値）
-	Q := 1.0 + f.Resonance*9.0 // 1.0 - 10.0
-	alpha := sinOmega / (2 * Q)
-
-	var b0, b1, b2, a0, a1, a2 float64
-
-	switch f.Type {
-	case "lowpass":
-		b0 = (1 - cosOmega) / 2
-		b1 = 1 - cosOmega
-		b2 = (1 - cosOmega) / 2
-		a0 = 1 + alpha
-		a1 = -2 * cosOmega
-		a2 = 1 - alpha
-
-	case "highpass":
-		b0 = (1 + cosOmega) / 2
-		b1 = -(1 + cosOmega)
-		b2 = (1 + cosOmega) / 2
-		a0 = 1 + alpha
-		a1 = -2 * cosOmega
-		a2 = 1 - alpha
-
-	default:
-		return sample
+// update はつまみが動いていたら係数を計算し直す
+// 💡 ローパスとハイパスが入れ替わったとき（と、素通しからかけ始めたとき）は、
+// 状態を「入力をそのまま出している」ところから始める（いきなり無音から始めるとプチッと鳴る）
+func (f *Filter) update(color, resonance, legacyQ float64, frame []float32) {
+	highpass := color > 0
+	q := filterMinQ + (filterMaxQ-filterMinQ)*resonance*resonance
+	if legacyQ > 0 {
+		q = legacyQ
+	}
+	if f.active && highpass == f.highpass && color == f.coefColor && q == f.coefQ {
+		return
 	}
 
-	// バイカッドフィルター適用
-	output := (b0/a0)*sample + (b1/a0)*f.buf0[channel] + (b2/a0)*f.buf1[channel] -
-		(a1/a0)*f.buf0[channel] - (a2/a0)*f.buf1[channel]
+	if !f.active || highpass != f.highpass {
+		for ch := range f.states {
+			if highpass {
+				f.states[ch] = svfState{} // 低いカットオフのハイパスは、状態 0 でほぼ素通し
+			} else {
+				f.states[ch] = svfState{ic2eq: float64(frame[ch])} // ローパスの出力 = 今の入力
+			}
+		}
+	}
 
-	f.buf1[channel] = f.buf0[channel]
-	f.buf0[channel] = sample
+	f.active = true
+	f.highpass = highpass
+	f.coefColor, f.coefQ = color, q
 
-	// ソフトクリッピング
-	if output > 1.0 {
-		output = 1.0
-	}
-	if output < -1.0 {
-		output = -1.0
-	}
+	settings := FilterSettings{Color: color, Resonance: resonance}
+	freq := min(settings.Frequency(), f.sampleRate*0.45)
+	f.coefs = svfCoefsFor(freq, q, f.sampleRate)
+}
+
+// svfCoefsFor は SVF の係数を計算する
+func svfCoefsFor(freq, q, sampleRate float64) svfCoefs {
+	g := math.Tan(math.Pi * freq / sampleRate)
+	k := 1 / q
+	a1 := 1 / (1 + g*(g+k))
+	a2 := g * a1
+	return svfCoefs{a1: a1, a2: a2, a3: g * a2, k: k}
+}
+
+// process は1サンプルにフィルターをかけて、ローパスとハイパスの出力を返す
+func (s *svfState) process(c *svfCoefs, x float64) (low, high float64) {
+	v3 := x - s.ic2eq
+	v1 := c.a1*s.ic1eq + c.a2*v3
+	v2 := s.ic2eq + c.a2*s.ic1eq + c.a3*v3
+	s.ic1eq = 2*v1 - s.ic1eq
+	s.ic2eq = 2*v2 - s.ic2eq
+	return v2, x - c.k*v1 - v2
+}
+
+// SetColor はつまみの位置を設定（-1.0 ～ 1.0）
+// 💡 以前の API（SetLowpass / SetHighpass）の設定はここで終わり、つまみの Resonance で動く
+func (f *Filter) SetColor(color float64) {
+	f.legacyQ.Store(0)
+	f.color.Store(math.Float64bits(clamp(color, -1, 1)))
+}
 
-	return output
+// SetResonance はレゾナンスを設定（0.0 ～ 1.0）
+func (f *Filter) SetResonance(resonance float64) {
+	f.resonance.Store(math.Float64bits(clamp(resonance, 0, 1)))
 }
 
-// SetLowpass はローパスフィルターを設定
+// GetColor はつまみの位置を返す
+func (f *Filter) GetColor() float64 {
+	return math.Float64frombits(f.color.Load())
+}
+
+// GetResonance はレゾナンスを返す
+func (f *Filter) GetResonance() float64 {
+	return math.Float64frombits(f.resonance.Load())
+}
+
+// SetLowpass はローパスフィルターを設定（以前の API との互換用。意味も以前と同じ）
+// cutoff 0.0 ～ 1.0 は 20Hz ～ 20kHz に直線で対応し、resonance 0.0 ～ 1.0 は Q 1 ～ 10
 func (f *Filter) SetLowpass(cutoff, resonance float64) {
-	f.Type = "lowpass"
-	f.Cutoff = clamp(cutoff, 0, 1)
-	f.Resonance = clamp(resonance, 0, 1)
+	ratio := FilterMaxFreq / legacyCutoffFreq(cutoff)
+	f.setLegacy(-math.Log(ratio)/math.Log(FilterMaxFreq/FilterMinFreq), resonance)
 }
 
-// SetHighpass はハイパスフィルターを設定
+// SetHighpass はハイパスフィルターを設定（以前の API との互換用。意味も以前と同じ）
+// cutoff 0.0 ～ 1.0 は 20Hz ～ 20kHz に直線で対応し、resonance 0.0 ～ 1.0 は Q 1 ～ 10
 func (f *Filter) SetHighpass(cutoff, resonance float64) {
-	f.Type = "highpass"
-	f.Cutoff = clamp(cutoff, 0, 1)
-	f.Resonance = clamp(resonance, 0, 1)
+	ratio := legacyCutoffFreq(cutoff) / FilterMinFreq
+	f.setLegacy(math.Log(ratio)/math.Log(FilterMaxFreq/FilterMinFreq), resonance)
+}
+
+// legacyCutoffFreq は以前の API の cutoff（0.0 ～ 1.0）をカットオフ周波数（Hz）にする
+func legacyCutoffFreq(cutoff float64) float64 {
+	return FilterMinFreq + (FilterMaxFreq-FilterMinFreq)*clamp(cutoff, 0, 1)
+}
+
+// setLegacy は以前の API の設定にする（つまみは cutoff の周波数になる位置、Q は以前と同じ）
+// 💡 Q を先に置く（オーディオスレッドが新しいつまみの位置を、以前の API の Q で読むように）
+func (f *Filter) setLegacy(color, resonance float64) {
+	resonance = clamp(resonance, 0, 1)
+	f.legacyQ.Store(math.Float64bits(legacyMinQ + (legacyMaxQ-legacyMinQ)*resonance))
+	f.resonance.Store(math.Float64bits(resonance))
+	f.color.Store(math.Float64bits(clamp(color, -1, 1)))
 }
 
-// Reset はフィルターをリセット
+// Reset はフィルターを素通しに戻す（レゾナンスはそのまま）
+// 💡 状態はオーディオスレッドが次にフィルターをかけるときに作り直す
 func (f *Filter) Reset() {
-	f.Type = "none"
-	f.buf0 = [2]float64{0, 0}
-	f.buf1 = [2]float64{0, 0}
+	f.SetColor(0)
 }
 
 func clamp(value, min, max float64) float64 {
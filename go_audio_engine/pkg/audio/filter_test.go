@@ -0,0 +1,77 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+// legacyFilterCoefs は以前の Filter（RBJ のバイカッド）の係数
+// 💡 以前の API の意味の基準：cutoff 0.0 ～ 1.0 は 20Hz ～ 20kHz に直線で対応し、resonance 0.0 ～ 1.0 は Q 1 ～ 10
+func legacyFilterCoefs(highpass bool, cutoff, resonance, sampleRate float64) biquadCoefs {
+	omega := 2 * math.Pi * (20 + 19980*cutoff) / sampleRate
+	cosOmega := math.Cos(omega)
+	alpha := math.Sin(omega) / (2 * (1 + 9*resonance))
+	a0 := 1 + alpha
+	c := biquadCoefs{a1: -2 * cosOmega / a0, a2: (1 - alpha) / a0}
+	if highpass {
+		c.b0, c.b1, c.b2 = (1+cosOmega)/2/a0, -(1+cosOmega)/a0, (1+cosOmega)/2/a0
+	} else {
+		c.b0, c.b1, c.b2 = (1-cosOmega)/2/a0, (1-cosOmega)/a0, (1-cosOmega)/2/a0
+	}
+	return c
+}
+
+// TestFilterLegacyAPI は以前の API（SetLowpass / SetHighpass）の周波数特性が、以前の Filter と同じであることを確認する
+func TestFilterLegacyAPI(t *testing.T) {
+	const (
+		sampleRate = 44100
+		tolerance  = 0.2 // dB
+	)
+
+	for _, highpass := range []bool{false, true} {
+		for _, cutoff := range []float64{0.02, 0.1, 0.4} {
+			for _, resonance := range []float64{0, 0.5, 1} {
+				want := legacyFilterCoefs(highpass, cutoff, resonance, sampleRate)
+				fc := 20 + 19980*cutoff
+
+				for _, ratio := range []float64{0.25, 0.5, 1, 2, 4} {
+					freq := fc * ratio
+					if freq > sampleRate*0.45 {
+						continue
+					}
+					f := NewFilter(sampleRate)
+					if highpass {
+						f.SetHighpass(cutoff, resonance)
+					} else {
+						f.SetLowpass(cutoff, resonance)
+					}
+					if got := f.Settings().Frequency(); math.Abs(got-fc) > 1e-6 {
+						t.Errorf("highpass %v cutoff %v: frequency = %v, want %v", highpass, cutoff, got, fc)
+					}
+
+					got := measureGainDB(f.Process, freq, sampleRate)
+					if w := magnitudeDB(want, freq, sampleRate); math.Abs(got-w) > tolerance {
+						t.Errorf("highpass %v cutoff %v resonance %v: gain at %.0f Hz = %.2f dB, want %.2f dB",
+							highpass, cutoff, resonance, freq, got, w)
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestFilterColorAfterLegacy は以前の API の後でつまみ（SetColor）を使うと、つまみの意味に戻ることを確認する
+func TestFilterColorAfterLegacy(t *testing.T) {
+	const sampleRate = 44100
+
+	f := NewFilter(sampleRate)
+	f.SetLowpass(0.1, 1)
+	f.SetColor(-0.5) // 632Hz のローパス、レゾナンス 1 は Q 8
+	f.SetResonance(0)
+
+	// レゾナンス 0 のつまみは Q 0.707（カットオフで -3dB）
+	freq := f.Settings().Frequency()
+	if got := measureGainDB(f.Process, freq, sampleRate); math.Abs(got+3.01) > 0.2 {
+		t.Errorf("gain at %.0f Hz = %.2f dB, want -3.01 dB", freq, got)
+	}
+}
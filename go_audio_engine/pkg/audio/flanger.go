@@ -0,0 +1,82 @@
+package audio
+
+import "math"
+
+// フランジャー
+//
+// 解説：ごく短いディレイ（0.1 ～ 4ms ほど）の長さをゆっくり揺らした音を出す。
+// 原音と混ぜると（dry/wet 0.5 で最も深い）、打ち消し合う周波数が櫛の歯のように並び、それが上下に動いて
+// 「シュワーッ」というジェット機のような音になる。揺れの周期は拍（テンポ同期）で指定する。
+// 💡 右チャンネルは揺れを 1/4 周期ずらして、左右に広がった音にする
+const (
+	flangerMinDelay = 0.0001 // 揺れの一番短いところ（秒）
+	flangerMaxDelay = 0.004  // depth 1.0 での揺れの一番長いところ（秒）
+)
+
+// Flanger はテンポ同期のフランジャー
+type Flanger struct {
+	beats    *EffectParam // 揺れの周期（拍）
+	depth    *EffectParam // 揺れの深さ
+	feedback *EffectParam // 櫛の歯の鋭さ
+
+	sampleRate float64
+
+	// 以下はオーディオスレッド専用
+	line  delayLine
+	phase float64 // 揺れの位置（0.0 ～ 1.0 未満）
+}
+
+// NewFlanger はフランジャーを作成
+func NewFlanger(sampleRate float64) *Flanger {
+	return &Flanger{
+		beats:      newEffectParam("beats", 0.5, 32, 8),
+		depth:      newEffectParam("depth", 0, 1, 0.7),
+		feedback:   newEffectParam("feedback", 0, 0.8, 0.5),
+		sampleRate: sampleRate,
+		line:       newDelayLine(int(sampleRate*flangerMaxDelay) + 4),
+	}
+}
+
+// Name はエフェクトの名前
+func (f *Flanger) Name() string {
+	return "flanger"
+}
+
+// Params はつまみの一覧
+func (f *Flanger) Params() []*EffectParam {
+	return []*EffectParam{f.beats, f.depth, f.feedback}
+}
+
+// Reset はディレイの中身を消し、揺れを最初からにする
+func (f *Flanger) Reset() {
+	f.line.reset()
+	f.phase = 0
+}
+
+// Process はフランジャーをかける（揺れるディレイの音だけを出す）
+func (f *Flanger) Process(samples []float32, beatSeconds float64) {
+	step := 1 / (f.beats.Get() * beatLength(beatSeconds) * f.sampleRate)
+	minDelay := flangerMinDelay * f.sampleRate
+	sweep := (flangerMaxDelay - flangerMinDelay) * f.sampleRate * f.depth.Get()
+	feedback := float32(f.feedback.Get())
+
+	for i := 0; i+1 < len(samples); i += 2 {
+		// 💡 揺れはコサインの形（一番短いところから始まり、なめらかに折り返す）
+		delayL := 1 + minDelay + sweep*lfo(f.phase)
+		delayR := 1 + minDelay + sweep*lfo(f.phase+0.25)
+		l, _ := f.line.read(delayL)
+		_, r := f.line.read(delayR)
+		f.line.write(samples[i]+l*feedback, samples[i+1]+r*feedback)
+		samples[i], samples[i+1] = l, r
+
+		f.phase += step
+		if f.phase >= 1 {
+			f.phase -= 1
+		}
+	}
+}
+
+// lfo は揺れの形（phase 0 で 0、0.5 で 1 の、なめらかな山）
+func lfo(phase float64) float64 {
+	return 0.5 - 0.5*math.Cos(2*math.Pi*phase)
+}
@@ -0,0 +1,189 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Interpolation は可変速再生（ピッチコントロール）の補間方式
+type Interpolation int
+
+const (
+	InterpolationLinear  Interpolation = iota // 直線補間（軽いが高域がこもる・エイリアスが残る）
+	InterpolationHermite                      // 3次エルミート補間（4点、軽くてそこそこ綺麗）
+	InterpolationSinc                         // 窓付き sinc（速度に応じてカットオフを下げ、エイリアシングを防ぐ）
+)
+
+// DefaultInterpolation は新しいトラックの補間方式
+var DefaultInterpolation = InterpolationSinc
+
+var interpolationNames = map[Interpolation]string{
+	InterpolationLinear:  "linear",
+	InterpolationHermite: "hermite",
+	InterpolationSinc:    "sinc",
+}
+
+func (i Interpolation) String() string {
+	if name, ok := interpolationNames[i]; ok {
+		return name
+	}
+	return fmt.Sprintf("Interpolation(%d)", int(i))
+}
+
+// ParseInterpolation は名前（"linear", "hermite", "sinc"）から補間方式を返す
+func ParseInterpolation(name string) (Interpolation, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for i, n := range interpolationNames {
+		if n == name {
+			return i, nil
+		}
+	}
+	if name == "cubic" {
+		return InterpolationHermite, nil
+	}
+	return 0, fmt.Errorf("unknown interpolation: %q", name)
+}
+
+// 可変速用 sinc カーネルの設定
+//
+// 解説：速度 s > 1 で再生すると、元の音の高域が出力のナイキスト周波数を超えて
+// 折り返し（エイリアス）になる。カットオフを 1/s 倍に下げて、先に高域を落としておく。
+// カーネルの形は速度によらず同じなので、正規化した1本のテーブルを伸縮して使う。
+const (
+	varispeedZeroCrossings = 8   // 片側のゼロ交差数
+	varispeedTableRes      = 256 // テーブルの分解能（ゼロ交差1つあたり）
+	varispeedRolloff       = 0.9 // ナイキスト周波数に対するカットオフ位置
+	varispeedKaiserBeta    = 7.0 // 阻止域 約 -70dB
+	varispeedMaxTaps       = 40  // 最大速度 2.0 でのタップ数 2×ceil(8/(0.9/2)) = 36 に余裕を持たせる
+)
+
+// varispeedTable は sinc(u)·kaiser(u) を u = 0 ～ varispeedZeroCrossings で並べたもの
+var varispeedTable = func() []float64 {
+	table := make([]float64, varispeedZeroCrossings*varispeedTableRes+2)
+	norm := besselI0(varispeedKaiserBeta)
+	for i := range table {
+		u := float64(i) / varispeedTableRes
+		if u >= varispeedZeroCrossings {
+			continue
+		}
+		x := u / varispeedZeroCrossings
+		table[i] = sinc(u) * besselI0(varispeedKaiserBeta*math.Sqrt(1-x*x)) / norm
+	}
+	return table
+}()
+
+// varispeedCutoff は速度に応じたカットオフ（入力ナイキスト = 1.0）
+func varispeedCutoff(speed float64) float64 {
+	if speed < 1 {
+		speed = 1
+	}
+	return varispeedRolloff / speed
+}
+
+// varispeedHalfWidth は sinc カーネルの片側の長さ（入力フレーム数）
+func varispeedHalfWidth(speed float64) int {
+	return int(math.Ceil(varispeedZeroCrossings / varispeedCutoff(speed)))
+}
+
+// interpolationReach は補間に必要な前後のフレーム数
+// （位置 frame+frac に対して frame-before ～ frame+after を参照する）
+func interpolationReach(mode Interpolation, speed float64) (before, after int) {
+	switch mode {
+	case InterpolationLinear:
+		return 0, 1
+	case InterpolationHermite:
+		return 1, 2
+	default:
+		w := varispeedHalfWidth(speed)
+		return w - 1, w
+	}
+}
+
+// stereoAt はステレオ・インターリーブの window から1フレーム取り出す（範囲外は無音）
+func stereoAt(window []float32, frame int) (float32, float32) {
+	if frame < 0 || frame*2+1 >= len(window) {
+		return 0, 0
+	}
+	return window[frame*2], window[frame*2+1]
+}
+
+// interpolateFrame は window（ステレオ・インターリーブ）上の位置 frame+frac の値を補間して返す
+// frame は window 先頭からのフレーム番号、frac は 0 ～ 1 未満
+func interpolateFrame(mode Interpolation, window []float32, frame int, frac, speed float64) (float32, float32) {
+	// 💡 ちょうどサンプル位置で等速以下なら補間しない（等速再生はビット単位で元データと一致する）
+	if frac == 0 && speed <= 1 {
+		return stereoAt(window, frame)
+	}
+
+	switch mode {
+	case InterpolationLinear:
+		l0, r0 := stereoAt(window, frame)
+		l1, r1 := stereoAt(window, frame+1)
+		f := float32(frac)
+		return l0 + (l1-l0)*f, r0 + (r1-r0)*f
+
+	case InterpolationHermite:
+		lm, rm := stereoAt(window, frame-1)
+		l0, r0 := stereoAt(window, frame)
+		l1, r1 := stereoAt(window, frame+1)
+		l2, r2 := stereoAt(window, frame+2)
+		f := float32(frac)
+		return hermite(lm, l0, l1, l2, f), hermite(rm, r0, r1, r2, f)
+
+	default:
+		return sincFrame(window, frame, frac, speed)
+	}
+}
+
+// hermite は4点3次エルミート（Catmull-Rom）補間
+func hermite(ym1, y0, y1, y2, f float32) float32 {
+	c1 := 0.5 * (y1 - ym1)
+	c2 := ym1 - 2.5*y0 + 2*y1 - 0.5*y2
+	c3 := 0.5*(y2-ym1) + 1.5*(y0-y1)
+	return ((c3*f+c2)*f+c1)*f + y0
+}
+
+// sincFrame は窓付き sinc で補間する
+func sincFrame(window []float32, frame int, frac, speed float64) (float32, float32) {
+	cutoff := varispeedCutoff(speed)
+	halfWidth := varispeedHalfWidth(speed)
+
+	var weights [varispeedMaxTaps]float64
+	var sum float64
+	taps := 2 * halfWidth
+	if taps > len(weights) {
+		taps = len(weights)
+		halfWidth = taps / 2
+	}
+	for j := 0; j < taps; j++ {
+		// タップ j は frame-halfWidth+1+j に対応（位置からの距離 d）
+		d := math.Abs(frac + float64(halfWidth-1-j))
+		weights[j] = varispeedKernel(d * cutoff)
+		sum += weights[j]
+	}
+
+	// 💡 係数の合計で割って、直流ゲインを常に 1 にする（速度によって音量が揺れないように）
+	if sum == 0 {
+		return 0, 0
+	}
+	var l, r float64
+	first := frame - halfWidth + 1
+	for j := 0; j < taps; j++ {
+		sl, sr := stereoAt(window, first+j)
+		l += float64(sl) * weights[j]
+		r += float64(sr) * weights[j]
+	}
+	return float32(l / sum), float32(r / sum)
+}
+
+// varispeedKernel はテーブルを線形補間してカーネル値を返す（u はゼロ交差単位の距離）
+func varispeedKernel(u float64) float64 {
+	pos := u * varispeedTableRes
+	i := int(pos)
+	if i+1 >= len(varispeedTable) {
+		return 0
+	}
+	f := pos - float64(i)
+	return varispeedTable[i] + (varispeedTable[i+1]-varispeedTable[i])*f
+}
@@ -0,0 +1,147 @@
+package audio
+
+import "math"
+
+// アイソレーター（DJミキサーのアイソレーター型EQ）
+//
+// 解説：Linkwitz-Riley（4次、24dB/oct）のクロスオーバーで音を低・中・高の3つに分け、
+// それぞれに音量をかけて足し戻す。つまみを下まで回すと、そのバンドが完全に消える（キル）。
+//
+//	low  = LP(低域側の境目) → AP(高域側の境目)（中・高域と位相をそろえる）
+//	mid  = HP(低域側の境目) → LP(高域側の境目)
+//	high = HP(低域側の境目) → HP(高域側の境目)
+//
+// 💡 LR4 のローパスとハイパスを足すとオールパスになるので、つまみがすべて 0（等倍）なら
+// 振幅特性はフラット（位相が回るだけ）
+const (
+	IsolatorMinFreq         = 20.0
+	IsolatorMaxFreq         = 16000.0
+	DefaultIsolatorLowFreq  = 300.0  // 低域と中域の境目
+	DefaultIsolatorHighFreq = 4000.0 // 中域と高域の境目
+
+	isolatorBoostDB = 6.0 // つまみを上まで回したときのブースト
+)
+
+// isolator はアイソレーターの係数と状態（オーディオスレッド専用）
+type isolator struct {
+	lowFreq, highFreq float64 // 係数を計算したときの境目（0 ならまだ計算していない）
+
+	lowLP, lowHP, highLP, highHP, highAP biquadCoefs
+
+	lowGain, midGain, highGain *Smoother // バンドの音量（倍率）
+
+	// 左右それぞれの状態（LR4 はバタワースの2段重ね）
+	lowBand  [2][3]biquadState // LP, LP, AP
+	split    [2][2]biquadState // HP, HP（中・高域の共通部分）
+	midBand  [2][2]biquadState // LP, LP
+	highBand [2][2]biquadState // HP, HP
+}
+
+// newIsolator はアイソレーターを作成（バンドの音量は等倍から）
+func newIsolator(sampleRate float64) isolator {
+	return isolator{
+		lowGain:  NewSmoother(sampleRate, SmoothSeconds, 1),
+		midGain:  NewSmoother(sampleRate, SmoothSeconds, 1),
+		highGain: NewSmoother(sampleRate, SmoothSeconds, 1),
+	}
+}
+
+// setCrossovers は境目が変わっていたら係数を計算し直す
+func (iso *isolator) setCrossovers(lowFreq, highFreq, sampleRate float64) {
+	if lowFreq == iso.lowFreq && highFreq == iso.highFreq {
+		return
+	}
+	iso.lowFreq, iso.highFreq = lowFreq, highFreq
+	iso.lowLP = lowpassCoefs(lowFreq, math.Sqrt2/2, sampleRate)
+	iso.lowHP = highpassCoefs(lowFreq, math.Sqrt2/2, sampleRate)
+	iso.highLP = lowpassCoefs(highFreq, math.Sqrt2/2, sampleRate)
+	iso.highHP = highpassCoefs(highFreq, math.Sqrt2/2, sampleRate)
+	iso.highAP = allpassCoefs(highFreq, math.Sqrt2/2, sampleRate)
+}
+
+// reset は状態を消す
+func (iso *isolator) reset() {
+	iso.lowBand = [2][3]biquadState{}
+	iso.split = [2][2]biquadState{}
+	iso.midBand = [2][2]biquadState{}
+	iso.highBand = [2][2]biquadState{}
+}
+
+// process は1サンプルを3つに分け、それぞれの音量をかけて足し戻す
+func (iso *isolator) process(ch int, x, lowGain, midGain, highGain float64) float64 {
+	low := iso.lowBand[ch][0].process(&iso.lowLP, x)
+	low = iso.lowBand[ch][1].process(&iso.lowLP, low)
+	low = iso.lowBand[ch][2].process(&iso.highAP, low)
+
+	rest := iso.split[ch][0].process(&iso.lowHP, x)
+	rest = iso.split[ch][1].process(&iso.lowHP, rest)
+
+	mid := iso.midBand[ch][0].process(&iso.highLP, rest)
+	mid = iso.midBand[ch][1].process(&iso.highLP, mid)
+
+	high := iso.highBand[ch][0].process(&iso.highHP, rest)
+	high = iso.highBand[ch][1].process(&iso.highHP, high)
+
+	return low*lowGain + mid*midGain + high*highGain
+}
+
+// isolatorGain はつまみの位置（-1.0 ～ 1.0）をバンドの音量（倍率）にする
+// -1.0 でキル（無音）、0 で等倍、+1.0 で +6dB
+// 💡 下げる側は2乗のカーブ（半分で約 -12dB）。最後の方で一気に消えすぎないようにする
+func isolatorGain(knob float64) float64 {
+	if knob <= -1 {
+		return 0
+	}
+	if knob < 0 {
+		return (1 + knob) * (1 + knob)
+	}
+	return math.Pow(10, knob*isolatorBoostDB/20)
+}
+
+// lowpassCoefs は2次のローパスの係数（q = √2/2 でバタワース）
+func lowpassCoefs(freq, q, sampleRate float64) biquadCoefs {
+	omega := 2 * math.Pi * freq / sampleRate
+	cosOmega := math.Cos(omega)
+	alpha := math.Sin(omega) / (2 * q)
+
+	a0 := 1 + alpha
+	return biquadCoefs{
+		b0: (1 - cosOmega) / 2 / a0,
+		b1: (1 - cosOmega) / a0,
+		b2: (1 - cosOmega) / 2 / a0,
+		a1: -2 * cosOmega / a0,
+		a2: (1 - alpha) / a0,
+	}
+}
+
+// highpassCoefs は2次のハイパスの係数
+func highpassCoefs(freq, q, sampleRate float64) biquadCoefs {
+	omega := 2 * math.Pi * freq / sampleRate
+	cosOmega := math.Cos(omega)
+	alpha := math.Sin(omega) / (2 * q)
+
+	a0 := 1 + alpha
+	return biquadCoefs{
+		b0: (1 + cosOmega) / 2 / a0,
+		b1: -(1 + cosOmega) / a0,
+		b2: (1 + cosOmega) / 2 / a0,
+		a1: -2 * cosOmega / a0,
+		a2: (1 - alpha) / a0,
+	}
+}
+
+// allpassCoefs は2次のオールパスの係数（振幅はそのまま、位相だけを回す）
+func allpassCoefs(freq, q, sampleRate float64) biquadCoefs {
+	omega := 2 * math.Pi * freq / sampleRate
+	cosOmega := math.Cos(omega)
+	alpha := math.Sin(omega) / (2 * q)
+
+	a0 := 1 + alpha
+	return biquadCoefs{
+		b0: (1 - alpha) / a0,
+		b1: -2 * cosOmega / a0,
+		b2: (1 + alpha) / a0,
+		a1: -2 * cosOmega / a0,
+		a2: (1 - alpha) / a0,
+	}
+}
@@ -0,0 +1,121 @@
+package audio
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+// isolatorResponseDB はアイソレーターの3つのバンドを gains の音量で足し戻したときの、freq（Hz）での利得（dB）
+// 💡 バンドごとの位相も含めて足すので、LR4 の和がフラットになるかを直接確かめられる
+func isolatorResponseDB(iso *isolator, gains [3]float64, freq, sampleRate float64) float64 {
+	h := func(c biquadCoefs) complex128 { return biquadResponse(c, freq, sampleRate) }
+	low := h(iso.lowLP) * h(iso.lowLP) * h(iso.highAP)
+	rest := h(iso.lowHP) * h(iso.lowHP)
+	mid := rest * h(iso.highLP) * h(iso.highLP)
+	high := rest * h(iso.highHP) * h(iso.highHP)
+	sum := low*complex(gains[0], 0) + mid*complex(gains[1], 0) + high*complex(gains[2], 0)
+	return 20 * math.Log10(cmplx.Abs(sum))
+}
+
+// TestIsolatorResponse はアイソレーターの特性を係数から計算して確認する
+//
+//	つまみがすべて 0：LR4 のローパスとハイパスの和はオールパスなので、どの周波数でも 0dB
+//	1つのバンドをキル：残りの2つの和は、キルしたバンドの境目でちょうど -6dB（LR4 の境目の利得）
+//	境目を動かすと、-6dB の点も一緒に動く
+func TestIsolatorResponse(t *testing.T) {
+	const sampleRate = 44100.0
+
+	for _, crossovers := range [][2]float64{{DefaultIsolatorLowFreq, DefaultIsolatorHighFreq}, {150, 3000}, {80, 12000}} {
+		lowFreq, highFreq := crossovers[0], crossovers[1]
+		iso := newIsolator(sampleRate)
+		iso.setCrossovers(lowFreq, highFreq, sampleRate)
+
+		for freq := 20.0; freq < 20000; freq *= 1.1 {
+			if got := isolatorResponseDB(&iso, [3]float64{1, 1, 1}, freq, sampleRate); math.Abs(got) > 0.001 {
+				t.Errorf("%.0f/%.0f Hz all knobs at 0: %.1f Hz = %.4f dB, want flat", lowFreq, highFreq, freq, got)
+			}
+		}
+
+		// -6dB の点はキルしたバンドの境目にあり、1/10 オクターブずらすと -6dB からはっきり離れる
+		tests := []struct {
+			name  string
+			gains [3]float64
+			freq  float64
+		}{
+			{"low kill", [3]float64{0, 1, 1}, lowFreq},
+			{"high kill", [3]float64{1, 1, 0}, highFreq},
+		}
+		for _, tt := range tests {
+			if got := isolatorResponseDB(&iso, tt.gains, tt.freq, sampleRate); math.Abs(got+6.02) > 0.05 {
+				t.Errorf("%.0f/%.0f Hz %s: %.0f Hz = %.3f dB, want -6.02 dB", lowFreq, highFreq, tt.name, tt.freq, got)
+			}
+			for _, shift := range []float64{math.Pow(2, -0.1), math.Pow(2, 0.1)} {
+				if got := isolatorResponseDB(&iso, tt.gains, tt.freq*shift, sampleRate); math.Abs(got+6.02) < 0.5 {
+					t.Errorf("%.0f/%.0f Hz %s: %.0f Hz = %.3f dB, want the -6 dB point only at %.0f Hz",
+						lowFreq, highFreq, tt.name, tt.freq*shift, got, tt.freq)
+				}
+			}
+		}
+	}
+}
+
+// TestIsolatorKnobs はアイソレーター特性の ThreeBandEQ に実際に音を通して、
+// つまみ 0 でフラット、-1 で深く消えること、境目を動かすと -6dB の点が動くことを確認する
+func TestIsolatorKnobs(t *testing.T) {
+	const (
+		sampleRate = 44100
+		tolerance  = 0.1 // dB（measureGainDB の窓の端数の分）
+	)
+
+	newIsolatorEQ := func(lowFreq, highFreq float64) *ThreeBandEQ {
+		eq := NewThreeBandEQ(sampleRate)
+		eq.SetMode(EQIsolator)
+		if err := eq.SetCrossovers(lowFreq, highFreq); err != nil {
+			t.Fatal(err)
+		}
+		return eq
+	}
+
+	// つまみがすべて 0：フラット
+	for _, freq := range []float64{50, 300, 1000, 4000, 10000} {
+		eq := newIsolatorEQ(DefaultIsolatorLowFreq, DefaultIsolatorHighFreq)
+		if got := measureGainDB(eq.Process, freq, sampleRate); math.Abs(got) > tolerance {
+			t.Errorf("all knobs at 0: %.0f Hz = %.3f dB, want 0 dB", freq, got)
+		}
+	}
+
+	// キル：消したバンドの真ん中では深く消える（LR4 は 24dB/oct なので、境目からの距離で深さが決まる）
+	kills := []struct {
+		name string
+		set  func(eq *ThreeBandEQ, knob float64)
+		freq float64
+		max  float64 // dB
+	}{
+		{"low", (*ThreeBandEQ).SetLow, 50, -60},      // 300Hz の約 2.6 オクターブ下
+		{"mid", (*ThreeBandEQ).SetMid, 1100, -36},    // 両側の境目から約 1.9 オクターブ（低域と高域の漏れが足し合わさる）
+		{"high", (*ThreeBandEQ).SetHigh, 16000, -60}, // 4kHz の2オクターブ上（ナイキストに近いのでさらに急になる）
+	}
+	for _, tt := range kills {
+		eq := newIsolatorEQ(DefaultIsolatorLowFreq, DefaultIsolatorHighFreq)
+		tt.set(eq, -1)
+		if got := measureGainDB(eq.Process, tt.freq, sampleRate); got > tt.max {
+			t.Errorf("%s kill: %.0f Hz = %.1f dB, want at most %.0f dB", tt.name, tt.freq, got, tt.max)
+		}
+	}
+
+	// 境目を動かすと、キルしたときの -6dB の点が動く
+	for _, crossovers := range [][2]float64{{DefaultIsolatorLowFreq, DefaultIsolatorHighFreq}, {150, 3000}} {
+		lowFreq, highFreq := crossovers[0], crossovers[1]
+		eq := newIsolatorEQ(lowFreq, highFreq)
+		eq.SetLow(-1)
+		if got := measureGainDB(eq.Process, lowFreq, sampleRate); math.Abs(got+6.02) > tolerance {
+			t.Errorf("low kill with %.0f/%.0f Hz: %.0f Hz = %.3f dB, want -6.02 dB", lowFreq, highFreq, lowFreq, got)
+		}
+		eq = newIsolatorEQ(lowFreq, highFreq)
+		eq.SetHigh(-1)
+		if got := measureGainDB(eq.Process, highFreq, sampleRate); math.Abs(got+6.02) > tolerance {
+			t.Errorf("high kill with %.0f/%.0f Hz: %.0f Hz = %.3f dB, want -6.02 dB", lowFreq, highFreq, highFreq, got)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package audio
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// マスターリミッター（先読み型ブリックウォール + トゥルーピーク検出）
+//
+// 解説：出力がシーリングを超えそうなら、その手前から少しずつ音量を下げておく。
+//  1. 各サンプルについて「シーリングに収めるのに必要なゲイン」を求める
+//     サンプル値だけでなく、サンプルの間（D/A変換後の波形）のピークも4倍オーバーサンプリングで見積もる
+//  2. 先読み区間（lookahead）の最小値をとる（この区間のどこかにピークがあれば、もう下げ始める）
+//  3. 同じ長さの移動平均でなめらかにする（最小値をとった区間の平均なので、ピークの位置では必ず必要量以下になる）
+//  4. ゲインを戻すときだけ release の時定数でゆっくり戻す
+//
+// 音声は先読みの分だけ遅らせてから、このゲインを掛ける（遅延は約2ms）
+// 💡 ハードクリップと違って波形を削らないので、2曲が重なって大きくなっても歪まない
+const (
+	limiterLookaheadMs    = 2.0
+	limiterTruePeakPhases = 4 // オーバーサンプリング倍率
+	limiterTruePeakTaps   = 8 // 補間フィルターのタップ数（1位相あたり）
+
+	LimiterMinCeiling = -12.0 // dB
+	LimiterMaxCeiling = 0.0
+	LimiterMinRelease = 10.0 // ms
+	LimiterMaxRelease = 1000.0
+
+	DefaultLimiterCeiling = -1.0 // dBTP（配信サービスでよく使われる値）
+	DefaultLimiterRelease = 150.0
+)
+
+// limiterDetectorDelay はトゥルーピーク検出に必要な先のサンプル数
+const limiterDetectorDelay = limiterTruePeakTaps / 2
+
+// truePeakKernel はサンプル間（1/4, 2/4, 3/4 の位置）を求める補間フィルター
+// カイザー窓付き sinc、各位相の合計を 1 に正規化
+var truePeakKernel = func() [limiterTruePeakPhases - 1][limiterTruePeakTaps]float64 {
+	var kernel [limiterTruePeakPhases - 1][limiterTruePeakTaps]float64
+	const beta = 5.0
+	half := float64(limiterTruePeakTaps / 2)
+	norm := besselI0(beta)
+	for p := range kernel {
+		frac := float64(p+1) / limiterTruePeakPhases
+		sum := 0.0
+		for j := range kernel[p] {
+			x := float64(j-limiterTruePeakTaps/2+1) - frac // -3-frac ～ 4-frac
+			w := 0.0
+			if r := x / half; r > -1 && r < 1 {
+				w = besselI0(beta*math.Sqrt(1-r*r)) / norm
+			}
+			kernel[p][j] = sinc(x) * w
+			sum += kernel[p][j]
+		}
+		for j := range kernel[p] {
+			kernel[p][j] /= sum
+		}
+	}
+	return kernel
+}()
+
+// Limiter はステレオリンクのブリックウォールリミッター
+type Limiter struct {
+	// 設定（API スレッドから変更される）
+	ceiling atomic.Uint64 // dB（float64 のビット列）
+	release atomic.Uint64 // ms（float64 のビット列）
+
+	// 直前のブロックで最も下げたゲイン（dB、0 以下）
+	gainReduction atomic.Uint64
+
+	sampleRate float64
+	lookahead  int
+
+	// 検出用の入力履歴（ステレオ、limiterTruePeakTaps フレーム）
+	history    [limiterTruePeakTaps * 2]float32
+	historyPos int
+
+	// 遅延用のリングバッファ（ステレオ）
+	delay    []float32
+	delayPos int
+
+	// 先読み区間の最小値（単調キュー）
+	minIndex []int64
+	minValue []float64
+	minHead  int64
+	minTail  int64
+	count    int64
+
+	// 移動平均
+	avgRing []float64
+	avgSum  float64
+	avgPos  int
+
+	envelope float64
+}
+
+// NewLimiter はリミッターを作成
+func NewLimiter(sampleRate float64) *Limiter {
+	lookahead := int(limiterLookaheadMs * sampleRate / 1000)
+	if lookahead < 1 {
+		lookahead = 1
+	}
+	l := &Limiter{
+		sampleRate: sampleRate,
+		lookahead:  lookahead,
+		delay:      make([]float32, (lookahead-1+limiterDetectorDelay)*2),
+		minIndex:   make([]int64, lookahead),
+		minValue:   make([]float64, lookahead),
+		avgRing:    make([]float64, lookahead),
+	}
+	l.SetCeiling(DefaultLimiterCeiling)
+	l.SetRelease(DefaultLimiterRelease)
+	l.Reset()
+	return l
+}
+
+// SetCeiling は出力の上限を dB で設定（-12 ～ 0dB）
+func (l *Limiter) SetCeiling(db float64) {
+	l.ceiling.Store(math.Float64bits(clamp(db, LimiterMinCeiling, LimiterMaxCeiling)))
+}
+
+// GetCeiling は出力の上限（dB）を返す
+func (l *Limiter) GetCeiling() float64 {
+	return math.Float64frombits(l.ceiling.Load())
+}
+
+// SetRelease はゲインを戻す速さを ms で設定（10 ～ 1000ms）
+func (l *Limiter) SetRelease(ms float64) {
+	l.release.Store(math.Float64bits(clamp(ms, LimiterMinRelease, LimiterMaxRelease)))
+}
+
+// GetRelease はゲインを戻す速さ（ms）を返す
+func (l *Limiter) GetRelease() float64 {
+	return math.Float64frombits(l.release.Load())
+}
+
+// GetGainReduction は直前のブロックで下げた量（dB、0 以下）を返す
+func (l *Limiter) GetGainReduction() float64 {
+	return math.Float64frombits(l.gainReduction.Load())
+}
+
+// Latency はリミッターの遅延（フレーム数）
+func (l *Limiter) Latency() int {
+	return l.lookahead - 1 + limiterDetectorDelay
+}
+
+// Reset は内部状態をクリア（設定はそのまま）
+func (l *Limiter) Reset() {
+	l.history = [limiterTruePeakTaps * 2]float32{}
+	l.historyPos = 0
+	for i := range l.delay {
+		l.delay[i] = 0
+	}
+	l.delayPos = 0
+	l.minHead, l.minTail, l.count = 0, 0, 0
+	for i := range l.avgRing {
+		l.avgRing[i] = 1
+	}
+	l.avgSum = float64(l.lookahead)
+	l.avgPos = 0
+	l.envelope = 1
+	l.gainReduction.Store(math.Float64bits(0))
+}
+
+// Process はリミッターを適用（ステレオ・インターリーブ）
+func (l *Limiter) Process(samples []float32) {
+	ceiling := math.Pow(10, l.GetCeiling()/20)
+	releaseCoef := math.Exp(-1000 / (l.GetRelease() * l.sampleRate))
+	lowest := 1.0
+
+	for i := 0; i+1 < len(samples); i += 2 {
+		// 1. 必要なゲイン（limiterDetectorDelay フレーム前のサンプルについて）
+		required := 1.0
+		if peak := l.detect(samples[i], samples[i+1]); peak > ceiling {
+			required = ceiling / peak
+		}
+
+		// 2. 先読み区間の最小値
+		held := l.slidingMin(required)
+
+		// 3. 移動平均
+		l.avgSum += held - l.avgRing[l.avgPos]
+		l.avgRing[l.avgPos] = held
+		l.avgPos++
+		if l.avgPos == l.lookahead {
+			l.avgPos = 0
+			// 💡 足し引きの誤差がたまらないように、一周ごとに合計を計算し直す
+			l.avgSum = 0
+			for _, v := range l.avgRing {
+				l.avgSum += v
+			}
+		}
+		smoothed := math.Min(l.avgSum/float64(l.lookahead), 1) // 誤差で 1 を超えないように
+
+		// 4. 下げるときはそのまま、戻すときはゆっくり
+		if smoothed < l.envelope {
+			l.envelope = smoothed
+		} else {
+			l.envelope = smoothed + (l.envelope-smoothed)*releaseCoef
+		}
+		lowest = math.Min(lowest, l.envelope)
+
+		// 遅らせた音声にゲインを掛ける
+		delayedL, delayedR := l.delay[l.delayPos*2], l.delay[l.delayPos*2+1]
+		l.delay[l.delayPos*2], l.delay[l.delayPos*2+1] = samples[i], samples[i+1]
+		l.delayPos++
+		if l.delayPos*2 >= len(l.delay) {
+			l.delayPos = 0
+		}
+		gain := float32(l.envelope)
+		samples[i] = delayedL * gain
+		samples[i+1] = delayedR * gain
+	}
+
+	l.gainReduction.Store(math.Float64bits(20 * math.Log10(lowest)))
+}
+
+// detect は新しいフレームを履歴に加え、limiterDetectorDelay フレーム前のサンプルと
+// その次のサンプルまでの間のピーク（L/R の大きい方）を返す
+func (l *Limiter) detect(left, right float32) float64 {
+	l.history[l.historyPos*2], l.history[l.historyPos*2+1] = left, right
+	l.historyPos = (l.historyPos + 1) % limiterTruePeakTaps
+
+	// 履歴を古い順に読む（historyPos が一番古い）
+	at := func(j, channel int) float64 {
+		return float64(l.history[((l.historyPos+j)%limiterTruePeakTaps)*2+channel])
+	}
+
+	center := limiterTruePeakTaps/2 - 1
+	peak := math.Max(math.Abs(at(center, 0)), math.Abs(at(center, 1)))
+	for p := range truePeakKernel {
+		for channel := 0; channel < 2; channel++ {
+			v := 0.0
+			for j, c := range truePeakKernel[p] {
+				v += c * at(j, channel)
+			}
+			peak = math.Max(peak, math.Abs(v))
+		}
+	}
+	return peak
+}
+
+// slidingMin は value を追加し、直近 lookahead 個の最小値を返す
+func (l *Limiter) slidingMin(value float64) float64 {
+	size := int64(l.lookahead)
+	index := l.count
+	l.count++
+
+	// 区間から外れた古い値を捨てる
+	for l.minHead < l.minTail && l.minIndex[l.minHead%size] <= index-size {
+		l.minHead++
+	}
+	// 新しい値以上の値は、もう最小値になることがないので捨てる
+	for l.minHead < l.minTail && l.minValue[(l.minTail-1)%size] >= value {
+		l.minTail--
+	}
+	l.minIndex[l.minTail%size] = index
+	l.minValue[l.minTail%size] = value
+	l.minTail++
+
+	return l.minValue[l.minHead%size]
+}
@@ -0,0 +1,141 @@
+package audio
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// processInBlocks は process を 512 フレームずつかける
+func processInBlocks(process func([]float32), samples []float32) {
+	for start := 0; start < len(samples); start += 512 * 2 {
+		process(samples[start:min(start+512*2, len(samples))])
+	}
+}
+
+// TestLimiterCeiling は音が重なってシーリングを大きく超えても、出力がシーリングを超えないことを確認する
+func TestLimiterCeiling(t *testing.T) {
+	const sampleRate = 44100
+
+	for _, ceilingDB := range []float64{0, DefaultLimiterCeiling, -6, LimiterMinCeiling} {
+		l := NewLimiter(sampleRate)
+		l.SetCeiling(ceilingDB)
+		if got := l.GetCeiling(); got != ceilingDB {
+			t.Fatalf("ceiling = %v, want %v", got, ceilingDB)
+		}
+
+		// 2曲が重なったような、周波数の違うサイン波の和（ピークは約 +15dB）。途中で急に大きくなる
+		samples := make([]float32, sampleRate*2)
+		for i := 0; i < sampleRate; i++ {
+			level := 0.5
+			if i > sampleRate/3 {
+				level = 2.0
+			}
+			v := level * (sine(440, sampleRate, i) + sine(554.37, sampleRate, i) + 0.8*sine(3001, sampleRate, i))
+			samples[i*2], samples[i*2+1] = float32(v), float32(-0.7*v)
+		}
+		processInBlocks(l.Process, samples)
+
+		ceiling := math.Pow(10, ceilingDB/20)
+		for i, v := range samples {
+			if math.Abs(float64(v)) > ceiling+1e-6 {
+				t.Fatalf("ceiling %v dB: sample %d = %v, exceeds %v", ceilingDB, i, v, ceiling)
+			}
+		}
+	}
+}
+
+// TestLimiterLatency は下げる必要のない音が、ちょうど Latency() フレーム遅れてそのまま出てくることを確認する
+func TestLimiterLatency(t *testing.T) {
+	const sampleRate = 48000
+	l := NewLimiter(sampleRate)
+	latency := l.Latency()
+	if latency <= 0 {
+		t.Fatalf("latency = %d, want positive", latency)
+	}
+
+	input := make([]float32, sampleRate/10*2)
+	rng := rand.New(rand.NewSource(1))
+	for i := range input {
+		input[i] = float32(rng.Float64() - 0.5) // ±0.5（-6dB）はシーリングより十分小さい
+	}
+	samples := append([]float32(nil), input...)
+	processInBlocks(l.Process, samples)
+
+	for i := 0; i < len(samples); i++ {
+		want := float32(0)
+		if i >= latency*2 {
+			want = input[i-latency*2]
+		}
+		if samples[i] != want {
+			t.Fatalf("sample %d = %v, want input delayed by %d frames (%v)", i, samples[i], latency, want)
+		}
+	}
+}
+
+// TestLimiterGainReduction は小さい音では下げず（0dB）、大きい音では下げた量を負の dB で報告することを確認する
+func TestLimiterGainReduction(t *testing.T) {
+	const sampleRate = 44100
+	l := NewLimiter(sampleRate)
+
+	quiet := stereoSine(440, 0.5, sampleRate, 512)
+	l.Process(quiet)
+	if got := l.GetGainReduction(); got != 0 {
+		t.Errorf("quiet input: gain reduction = %v dB, want 0", got)
+	}
+
+	// ピーク 4.0（+12dB）をシーリング -1dB に収めるには約 13dB 下げる
+	loud := stereoSine(440, 4, sampleRate, 4096)
+	processInBlocks(l.Process, loud)
+	want := DefaultLimiterCeiling - 20*math.Log10(4)
+	if got := l.GetGainReduction(); got >= 0 || math.Abs(got-want) > 0.5 {
+		t.Errorf("loud input: gain reduction = %.2f dB, want about %.2f dB", got, want)
+	}
+}
+
+// TestLimiterRelease は大きい音が終わった後、ゲインが設定したリリースの時定数で戻ることを確認する
+// 💡 直流を入れると、出力 ÷ 入力がそのままゲインになる
+func TestLimiterRelease(t *testing.T) {
+	const (
+		sampleRate = 44100
+		burst      = sampleRate / 10 // 大きい音の長さ（フレーム）
+		quietLevel = 0.1
+	)
+
+	for _, releaseMs := range []float64{50, DefaultLimiterRelease, 500} {
+		l := NewLimiter(sampleRate)
+		l.SetRelease(releaseMs)
+		if got := l.GetRelease(); got != releaseMs {
+			t.Fatalf("release = %v, want %v", got, releaseMs)
+		}
+
+		releaseFrames := int(releaseMs * sampleRate / 1000)
+		frames := burst + releaseFrames*4
+		samples := make([]float32, frames*2)
+		for i := 0; i < frames; i++ {
+			v := float32(quietLevel)
+			if i < burst {
+				v = 5
+			}
+			samples[i*2], samples[i*2+1] = v, v
+		}
+		processInBlocks(l.Process, samples)
+
+		// 大きい音が出力から抜けて、先読みの窓も過ぎた後のゲイン
+		gainAt := func(frame int) float64 {
+			return float64(samples[(frame+l.Latency())*2]) / quietLevel
+		}
+		start := burst + sampleRate/100
+		g0, g1 := gainAt(start), gainAt(start+releaseFrames)
+		if g0 >= 0.5 {
+			t.Fatalf("release %v ms: gain %v right after the burst, want still reduced", releaseMs, g0)
+		}
+		// 1 - gain は時定数 release で指数関数的に小さくなる（release 後に 1/e）
+		if ratio := (1 - g1) / (1 - g0); math.Abs(ratio-math.Exp(-1)) > 0.01 {
+			t.Errorf("release %v ms: remaining reduction after %v ms is %.3f, want %.3f", releaseMs, releaseMs, ratio, math.Exp(-1))
+		}
+		if last := gainAt(frames - l.Latency() - 1); last < 0.97 {
+			t.Errorf("release %v ms: gain %v after 4 time constants, want nearly 1", releaseMs, last)
+		}
+	}
+}
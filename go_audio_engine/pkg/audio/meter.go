@@ -0,0 +1,207 @@
+package audio
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// レベルメーター（ピーク・RMS・ラウドネス）
+//
+// 解説：
+//
+//	ピーク：サンプルの絶対値の最大。すぐ上がって 1.7 秒で 20dB 下がる（IEC のピークメーターと同じ戻り）
+//	RMS：   300ms の時定数で平均した実効値（VU メーターに近い動き）
+//	ラウドネス（EBU R128 / ITU-R BS.1770）：
+//	        K 特性（高域を少し持ち上げ、超低域を削る）をかけた二乗平均を LUFS で表したもの
+//	        モーメンタリー = 直近 400ms、ショートターム = 直近 3 秒
+//	        100ms ごとに区切って二乗和をためておき、その合計から求める
+//
+// オーディオスレッドが Process で計算し、結果は atomic で公開する（読む側はロック不要）
+const (
+	MeterFloorDB = -120.0 // 無音のときの値（-Inf は JSON にできないので）
+
+	meterPeakFallDB      = 20.0 // ピークが下がる量（dB）……
+	meterPeakFallSeconds = 1.7  // ……にかかる時間
+	meterRMSSeconds      = 0.3
+	meterBlockSeconds    = 0.1 // ラウドネスを区切る単位
+	meterMomentaryBlocks = 4   // 400ms
+	meterShortTermBlocks = 30  // 3s
+)
+
+// MeterReading はメーターの値（dBFS / LUFS）
+type MeterReading struct {
+	Peak      float64 // dBFS
+	RMS       float64 // dBFS
+	Momentary float64 // LUFS
+	ShortTerm float64 // LUFS
+}
+
+// Meter はステレオのレベルメーター
+type Meter struct {
+	// 公開する値（float64 のビット列）
+	peak      atomic.Uint64
+	rms       atomic.Uint64
+	momentary atomic.Uint64
+	shortTerm atomic.Uint64
+
+	peakFall float64 // 1フレームあたりのピークの減衰率
+	rmsCoef  float64 // RMS の平滑化係数
+
+	peakEnv float64
+	meanSq  float64
+
+	// K 特性フィルター（2段のバイカッド × 2ch）
+	kShelf [2]biquadState
+	kHigh  [2]biquadState
+	shelf  biquadCoefs
+	high   biquadCoefs
+
+	blockFrames int       // 100ms のフレーム数
+	blockPos    int       // 今の区切りに入ったフレーム数
+	blockSum    float64   // 今の区切りの二乗和
+	blocks      []float64 // 直近 30 区切り分の二乗和（リングバッファ）
+	blockIndex  int
+}
+
+// biquadCoefs は a0 で正規化したバイカッドの係数
+type biquadCoefs struct {
+	b0, b1, b2, a1, a2 float64
+}
+
+// biquadState は Direct Form I の状態
+type biquadState struct {
+	x1, x2, y1, y2 float64
+}
+
+func (s *biquadState) process(c *biquadCoefs, x float64) float64 {
+	y := c.b0*x + c.b1*s.x1 + c.b2*s.x2 - c.a1*s.y1 - c.a2*s.y2
+	s.x2, s.x1 = s.x1, x
+	s.y2, s.y1 = s.y1, y
+	return y
+}
+
+// NewMeter はメーターを作成
+func NewMeter(sampleRate float64) *Meter {
+	m := &Meter{
+		peakFall:    math.Pow(10, -meterPeakFallDB/20/(meterPeakFallSeconds*sampleRate)),
+		rmsCoef:     math.Exp(-1 / (meterRMSSeconds * sampleRate)),
+		blockFrames: int(meterBlockSeconds * sampleRate),
+		blocks:      make([]float64, meterShortTermBlocks),
+	}
+
+	// K 特性の係数（BS.1770 の 48kHz の係数を、どのサンプルレートでも同じ特性になるよう設計し直したもの）
+	// 1段目：高域シェルフ（+4dB）
+	{
+		const f0, gainDB, q = 1681.974450955533, 3.999843853973347, 0.7071752369554196
+		k := math.Tan(math.Pi * f0 / sampleRate)
+		vh := math.Pow(10, gainDB/20)
+		vb := math.Pow(vh, 0.4996667741545416)
+		a0 := 1 + k/q + k*k
+		m.shelf = biquadCoefs{
+			b0: (vh + vb*k/q + k*k) / a0,
+			b1: 2 * (k*k - vh) / a0,
+			b2: (vh - vb*k/q + k*k) / a0,
+			a1: 2 * (k*k - 1) / a0,
+			a2: (1 - k/q + k*k) / a0,
+		}
+	}
+	// 2段目：ハイパス（約 38Hz）
+	{
+		const f0, q = 38.13547087602444, 0.5003270373238773
+		k := math.Tan(math.Pi * f0 / sampleRate)
+		a0 := 1 + k/q + k*k
+		m.high = biquadCoefs{
+			b0: 1,
+			b1: -2,
+			b2: 1,
+			a1: 2 * (k*k - 1) / a0,
+			a2: (1 - k/q + k*k) / a0,
+		}
+	}
+
+	m.Reset()
+	return m
+}
+
+// Reset は計測をやり直す
+func (m *Meter) Reset() {
+	m.peakEnv = 0
+	m.meanSq = 0
+	m.kShelf = [2]biquadState{}
+	m.kHigh = [2]biquadState{}
+	m.blockPos = 0
+	m.blockSum = 0
+	for i := range m.blocks {
+		m.blocks[i] = 0
+	}
+	m.blockIndex = 0
+	for _, v := range []*atomic.Uint64{&m.peak, &m.rms, &m.momentary, &m.shortTerm} {
+		v.Store(math.Float64bits(MeterFloorDB))
+	}
+}
+
+// Process はサンプル（ステレオ・インターリーブ）を計測する（samples は変更しない）
+func (m *Meter) Process(samples []float32) {
+	for i := 0; i+1 < len(samples); i += 2 {
+		l, r := float64(samples[i]), float64(samples[i+1])
+
+		// ピーク
+		m.peakEnv *= m.peakFall
+		m.peakEnv = math.Max(m.peakEnv, math.Max(math.Abs(l), math.Abs(r)))
+
+		// RMS（左右の平均パワー）
+		m.meanSq = (l*l+r*r)/2 + (m.meanSq-(l*l+r*r)/2)*m.rmsCoef
+
+		// ラウドネス（K 特性をかけて二乗和をためる。左右の重みはどちらも 1.0）
+		kl := m.kHigh[0].process(&m.high, m.kShelf[0].process(&m.shelf, l))
+		kr := m.kHigh[1].process(&m.high, m.kShelf[1].process(&m.shelf, r))
+		m.blockSum += kl*kl + kr*kr
+		m.blockPos++
+		if m.blockPos == m.blockFrames {
+			m.finishBlock()
+		}
+	}
+
+	m.peak.Store(math.Float64bits(toDB(m.peakEnv)))
+	m.rms.Store(math.Float64bits(toDB(math.Sqrt(m.meanSq))))
+}
+
+// finishBlock は100msの区切りを確定し、ラウドネスを更新する
+func (m *Meter) finishBlock() {
+	m.blocks[m.blockIndex] = m.blockSum
+	m.blockIndex = (m.blockIndex + 1) % len(m.blocks)
+	m.blockSum = 0
+	m.blockPos = 0
+
+	loudness := func(count int) float64 {
+		sum := 0.0
+		for k := 1; k <= count; k++ {
+			sum += m.blocks[(m.blockIndex-k+len(m.blocks))%len(m.blocks)]
+		}
+		meanSquare := sum / float64(count*m.blockFrames)
+		if meanSquare <= 0 {
+			return MeterFloorDB
+		}
+		return math.Max(MeterFloorDB, -0.691+10*math.Log10(meanSquare))
+	}
+	m.momentary.Store(math.Float64bits(loudness(meterMomentaryBlocks)))
+	m.shortTerm.Store(math.Float64bits(loudness(meterShortTermBlocks)))
+}
+
+// Reading は最新の値を返す（どのスレッドからでも呼べる）
+func (m *Meter) Reading() MeterReading {
+	return MeterReading{
+		Peak:      math.Float64frombits(m.peak.Load()),
+		RMS:       math.Float64frombits(m.rms.Load()),
+		Momentary: math.Float64frombits(m.momentary.Load()),
+		ShortTerm: math.Float64frombits(m.shortTerm.Load()),
+	}
+}
+
+// toDB は振幅を dB にする（無音は MeterFloorDB）
+func toDB(amplitude float64) float64 {
+	if amplitude <= 0 {
+		return MeterFloorDB
+	}
+	return math.Max(MeterFloorDB, 20*math.Log10(amplitude))
+}
@@ -0,0 +1,74 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+// TestMeterLoudnessReference は BS.1770 の基準どおり、997Hz・-20dBFS のサイン波を左右に入れると
+// モーメンタリーもショートタームも -20 LUFS になることを確認する（サンプルレートによらない）
+// 💡 片方のチャンネルだけなら -23.01 LUFS（左右で 2 倍のパワー = +3.01dB）
+func TestMeterLoudnessReference(t *testing.T) {
+	const tolerance = 0.05 // LU
+
+	for _, sampleRate := range []int{44100, 48000} {
+		m := NewMeter(float64(sampleRate))
+		amplitude := math.Pow(10, -20.0/20)
+		samples := stereoSine(997, amplitude, sampleRate, sampleRate*4) // ショートターム（3秒）が埋まる長さ
+		processInBlocks(m.Process, samples)
+
+		reading := m.Reading()
+		if math.Abs(reading.Momentary+20) > tolerance {
+			t.Errorf("%d Hz: momentary = %.3f LUFS, want -20", sampleRate, reading.Momentary)
+		}
+		if math.Abs(reading.ShortTerm+20) > tolerance {
+			t.Errorf("%d Hz: short-term = %.3f LUFS, want -20", sampleRate, reading.ShortTerm)
+		}
+		if math.Abs(reading.Peak+20) > 0.01 {
+			t.Errorf("%d Hz: peak = %.3f dBFS, want -20", sampleRate, reading.Peak)
+		}
+		// サイン波の実効値は振幅の 1/√2（-3.01dB）
+		if math.Abs(reading.RMS+23.01) > 0.05 {
+			t.Errorf("%d Hz: RMS = %.3f dBFS, want -23.01", sampleRate, reading.RMS)
+		}
+
+		// 片方のチャンネルだけ
+		m.Reset()
+		for i := 1; i < len(samples); i += 2 {
+			samples[i] = 0
+		}
+		processInBlocks(m.Process, samples)
+		if got := m.Reading().Momentary; math.Abs(got+23.01) > tolerance {
+			t.Errorf("%d Hz left only: momentary = %.3f LUFS, want -23.01", sampleRate, got)
+		}
+	}
+}
+
+// TestMeterPeakFall はピークが 1.7 秒で 20dB 下がることを確認する
+func TestMeterPeakFall(t *testing.T) {
+	const sampleRate = 48000
+	m := NewMeter(sampleRate)
+
+	samples := make([]float32, int(meterPeakFallSeconds*sampleRate+1)*2)
+	samples[0], samples[1] = 1, -1 // 0dBFS の1フレームのあと無音
+	processInBlocks(m.Process, samples)
+
+	if got := m.Reading().Peak; math.Abs(got+meterPeakFallDB) > 0.01 {
+		t.Errorf("peak %.1f s after a 0 dBFS click = %.3f dBFS, want %.1f", meterPeakFallSeconds, got, -meterPeakFallDB)
+	}
+}
+
+// TestMeterSilence は無音（と計測前）ですべての値が MeterFloorDB になることを確認する
+func TestMeterSilence(t *testing.T) {
+	const sampleRate = 44100
+	m := NewMeter(sampleRate)
+	want := MeterReading{Peak: MeterFloorDB, RMS: MeterFloorDB, Momentary: MeterFloorDB, ShortTerm: MeterFloorDB}
+	if got := m.Reading(); got != want {
+		t.Errorf("before processing: %+v, want %+v", got, want)
+	}
+
+	processInBlocks(m.Process, make([]float32, sampleRate*4*2))
+	if got := m.Reading(); got != want {
+		t.Errorf("after 4 s of silence: %+v, want %+v", got, want)
+	}
+}
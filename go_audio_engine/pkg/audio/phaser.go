@@ -0,0 +1,104 @@
+package audio
+
+import "math"
+
+// フェイザー
+//
+// 解説：1次のオールパス（振幅はそのまま、位相だけを回す）を何段も通した音を出す。
+// 原音と混ぜると（dry/wet 0.5 で最も深い）、位相が逆になった周波数が打ち消されて谷ができ、
+// その谷を揺らすと「ウワンウワン」とうねる音になる。フランジャーより谷の数が少なく、柔らかい。
+// 揺れの周期は拍（テンポ同期）で指定する。
+// 💡 谷の周波数は耳に合わせて指数的に動かす。計算の重い係数は smoothBlockFrames ごとに計算し直す
+const (
+	phaserStages  = 6      // オールパスの段数（谷の数は半分）
+	phaserMinFreq = 200.0  // 揺れの一番低いところ（Hz）
+	phaserMaxFreq = 4000.0 // depth 1.0 での揺れの一番高いところ（Hz）
+)
+
+// Phaser はテンポ同期のフェイザー
+type Phaser struct {
+	beats    *EffectParam // 揺れの周期（拍）
+	depth    *EffectParam // 揺れの深さ
+	feedback *EffectParam // 谷の深さ・うねりの強さ
+
+	sampleRate float64
+
+	// 以下はオーディオスレッド専用
+	phase  float64                       // 揺れの位置（0.0 ～ 1.0 未満）
+	stages [2][phaserStages]allpassState // 左右それぞれのオールパスの状態
+	last   [2]float32                    // フィードバック用の直前の出力
+}
+
+// allpassState は1次のオールパスの状態
+type allpassState struct {
+	x1, y1 float32
+}
+
+// NewPhaser はフェイザーを作成
+func NewPhaser(sampleRate float64) *Phaser {
+	return &Phaser{
+		beats:      newEffectParam("beats", 0.5, 32, 8),
+		depth:      newEffectParam("depth", 0, 1, 0.7),
+		feedback:   newEffectParam("feedback", 0, 0.8, 0.5),
+		sampleRate: sampleRate,
+	}
+}
+
+// Name はエフェクトの名前
+func (p *Phaser) Name() string {
+	return "phaser"
+}
+
+// Params はつまみの一覧
+func (p *Phaser) Params() []*EffectParam {
+	return []*EffectParam{p.beats, p.depth, p.feedback}
+}
+
+// Reset は状態を消し、揺れを最初からにする
+func (p *Phaser) Reset() {
+	p.stages = [2][phaserStages]allpassState{}
+	p.last = [2]float32{}
+	p.phase = 0
+}
+
+// Process はフェイザーをかける（オールパスを通した音だけを出す）
+func (p *Phaser) Process(samples []float32, beatSeconds float64) {
+	step := 1 / (p.beats.Get() * beatLength(beatSeconds) * p.sampleRate)
+	ratio := math.Pow(phaserMaxFreq/phaserMinFreq, p.depth.Get())
+	feedback := float32(p.feedback.Get())
+
+	var coefs [2]float32
+	for i := 0; i+1 < len(samples); i += 2 {
+		if (i/2)%smoothBlockFrames == 0 {
+			// 💡 右チャンネルは揺れを 1/4 周期ずらす
+			coefs[0] = p.coef(phaserMinFreq * math.Pow(ratio, lfo(p.phase)))
+			coefs[1] = p.coef(phaserMinFreq * math.Pow(ratio, lfo(p.phase+0.25)))
+		}
+		for ch := 0; ch < 2; ch++ {
+			y := samples[i+ch] + p.last[ch]*feedback
+			for k := range p.stages[ch] {
+				y = p.stages[ch][k].process(coefs[ch], y)
+			}
+			p.last[ch] = y
+			samples[i+ch] = y
+		}
+
+		p.phase += step
+		if p.phase >= 1 {
+			p.phase -= 1
+		}
+	}
+}
+
+// coef は freq（Hz）で位相が 90° 回る1次のオールパスの係数
+func (p *Phaser) coef(freq float64) float32 {
+	t := math.Tan(math.Pi * freq / p.sampleRate)
+	return float32((t - 1) / (t + 1))
+}
+
+// process は1サンプル進める
+func (s *allpassState) process(a, x float32) float32 {
+	y := a*(x-s.y1) + s.x1
+	s.x1, s.y1 = x, y
+	return y
+}
@@ -0,0 +1,222 @@
+package audio
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// ピッチシフト（ディレイライン + 相関でつなぎ目を選ぶスプライス方式）
+//
+// 解説：入力をリングバッファに書き込みながら、読み出しヘッドを 2^(半音/12) 倍の速さで動かすと
+// ピッチが変わる。そのままでは書き込み位置に追いついたり（ピッチアップ）離されたり（ダウン）するので、
+// 遅延が範囲外に出たら読み出し位置を 15 ～ 35ms 前後にジャンプさせ、短いクロスフェードでつなぐ。
+// ジャンプ先は今のヘッドと波形が最も揃う位置を選ぶので、つなぎ目が目立たない。
+// テンポ（Track.Speed）とは独立しているので、キーロックと組み合わせれば曲のキーだけを合わせられる。
+const (
+	pitchMaxSemitones = 12.0
+	pitchFadeMs       = 10.0 // つなぎ目のクロスフェード
+	pitchJumpMs       = 25.0 // ジャンプ量の中心
+	pitchToleranceMs  = 10.0 // ジャンプ量の探索範囲（±）
+	pitchBufferMs     = 200  // リングバッファの長さ（最大遅延より十分長く）
+)
+
+// PitchShifter はテンポを変えずにピッチを半音単位で変える
+type PitchShifter struct {
+	semitones atomic.Uint64 // float64 のビット列（API スレッドから変更される）
+
+	ring     []float32 // 入力の履歴（ステレオ、2のべき乗フレーム）
+	mask     int64
+	writePos int64 // 書き込んだフレーム数
+
+	pos      float64 // 読み出しヘッド（入力上のフレーム位置）
+	fadePos  float64 // クロスフェード中の古いヘッド
+	fade     int     // クロスフェードの残りフレーム数
+	wet      float64 // 原音 → シフト音 の切り替え量（0 ～ 1）
+	active   bool    // 読み出しヘッドが動いているか
+	fadeLen  int
+	minDelay int
+	maxDelay int
+	jump     int
+	tol      int
+
+	monoRef []float32 // 作業用バッファ
+}
+
+// NewPitchShifter はピッチシフターを作成
+func NewPitchShifter(sampleRate float64) *PitchShifter {
+	ms := func(v float64) int { return int(v * sampleRate / 1000) }
+
+	capacity := 1
+	for capacity < ms(pitchBufferMs) {
+		capacity <<= 1
+	}
+
+	p := &PitchShifter{
+		ring:    make([]float32, capacity*2),
+		mask:    int64(capacity - 1),
+		fadeLen: ms(pitchFadeMs),
+		jump:    ms(pitchJumpMs),
+		tol:     ms(pitchToleranceMs),
+	}
+	// 💡 最大の +12半音（2倍速）でもクロスフェード中に古いヘッドが書き込み位置を追い越さない遅延
+	p.minDelay = p.fadeLen*2 + 4
+	p.maxDelay = p.minDelay + p.jump + p.tol + 2
+	return p
+}
+
+// SetSemitones はピッチを半音単位で設定（-12 ～ +12、小数も可）
+func (p *PitchShifter) SetSemitones(semitones float64) {
+	p.semitones.Store(math.Float64bits(clamp(semitones, -pitchMaxSemitones, pitchMaxSemitones)))
+}
+
+// GetSemitones は現在のピッチ（半音）を返す
+func (p *PitchShifter) GetSemitones() float64 {
+	return math.Float64frombits(p.semitones.Load())
+}
+
+// Reset は履歴をクリア（ピッチの設定はそのまま）
+func (p *PitchShifter) Reset() {
+	for i := range p.ring {
+		p.ring[i] = 0
+	}
+	p.writePos = 0
+	p.fade = 0
+	p.wet = 0
+	p.active = false
+}
+
+// Process はピッチシフトを適用
+func (p *PitchShifter) Process(samples []float32) {
+	semitones := p.GetSemitones()
+	ratio := math.Pow(2, semitones/12)
+	target := 0.0
+	if semitones != 0 {
+		target = 1
+	}
+	// 💡 0半音ならヘッドを止めて原音をそのまま通す（遅延なし）
+	//    切り替え時はクロスフェードするので、つまみを 0 に戻してもプチッとならない
+	step := 1.0 / float64(p.fadeLen)
+
+	for i := 0; i+1 < len(samples); i += 2 {
+		l, r := samples[i], samples[i+1]
+		slot := p.writePos & p.mask
+		p.ring[slot*2], p.ring[slot*2+1] = l, r
+		p.writePos++
+
+		if !p.active {
+			if target == 0 {
+				continue
+			}
+			p.active = true
+			p.fade = 0
+			p.pos = float64(p.writePos-1) - float64(p.minDelay+p.maxDelay)/2
+		}
+
+		// 遅延が範囲外に出たらジャンプ（クロスフェード中は待つ）
+		if p.fade == 0 {
+			delay := float64(p.writePos-1) - p.pos
+			if delay < float64(p.minDelay) {
+				p.splice(-1, ratio)
+			} else if delay > float64(p.maxDelay) {
+				p.splice(1, ratio)
+			}
+		}
+
+		sl, sr := p.read(p.pos)
+		if p.fade > 0 {
+			g := float32(p.fade) / float32(p.fadeLen)
+			ol, or := p.read(p.fadePos)
+			sl = ol*g + sl*(1-g)
+			sr = or*g + sr*(1-g)
+			p.fadePos += ratio
+			p.fade--
+		}
+		p.pos += ratio
+
+		// 原音とシフト音の切り替え
+		if p.wet < target {
+			p.wet = math.Min(target, p.wet+step)
+		} else if p.wet > target {
+			p.wet = math.Max(target, p.wet-step)
+		}
+		w := float32(p.wet)
+		samples[i] = l*(1-w) + sl*w
+		samples[i+1] = r*(1-w) + sr*w
+
+		if p.wet == 0 && target == 0 {
+			p.active = false
+		}
+	}
+}
+
+// read はリングバッファの位置 pos（小数）をエルミート補間して読む
+func (p *PitchShifter) read(pos float64) (float32, float32) {
+	i := int64(math.Floor(pos))
+	f := float32(pos - float64(i))
+	var l, r [4]float32
+	for k := int64(0); k < 4; k++ {
+		slot := (i - 1 + k) & p.mask
+		l[k], r[k] = p.ring[slot*2], p.ring[slot*2+1]
+	}
+	return hermite(l[0], l[1], l[2], l[3], f), hermite(r[0], r[1], r[2], r[3], f)
+}
+
+// splice は読み出しヘッドを direction（-1: 過去へ、+1: 未来へ）にジャンプさせ、クロスフェードを始める
+// ジャンプ量は jump ± tol の中から、直前の波形が今のヘッドと最も似ているものを選ぶ
+// 💡 ヘッドより先はまだ書き込まれていないことがあるので、比較は「直前」の区間で行う
+func (p *PitchShifter) splice(direction int, ratio float64) {
+	n := p.fadeLen
+	if cap(p.monoRef) < n {
+		p.monoRef = make([]float32, n)
+	}
+	ref := p.monoRef[:n]
+	for k := range ref {
+		l, r := p.read(p.pos - float64(n-k)*ratio)
+		ref[k] = l + r
+	}
+
+	// 💡 候補側のエネルギーで正規化する（音が大きいだけの位置を選ばないように）
+	correlate := func(offset, step int) float32 {
+		var sum, energy float32
+		base := p.pos + float64(direction*offset)
+		for k := 0; k < n; k += step {
+			l, r := p.read(base - float64(n-k)*ratio)
+			v := l + r
+			sum += ref[k] * v
+			energy += v * v
+		}
+		return sum / float32(math.Sqrt(float64(energy)+1e-9))
+	}
+
+	// 粗く探してから細かく探す
+	best, bestScore := p.jump, float32(math.Inf(-1))
+	for offset := p.jump - p.tol; offset <= p.jump+p.tol; offset += 4 {
+		if score := correlate(offset, 4); score > bestScore {
+			best, bestScore = offset, score
+		}
+	}
+	coarse := best
+	bestScore = float32(math.Inf(-1))
+	for offset := coarse - 3; offset <= coarse+3; offset++ {
+		if offset < p.jump-p.tol || offset > p.jump+p.tol {
+			continue
+		}
+		if score := correlate(offset, 1); score > bestScore {
+			best, bestScore = offset, score
+		}
+	}
+
+	// 💡 ピークの前後から放物線補間して、1フレーム未満のずれも合わせる
+	//    （整数フレームのジャンプだと、低音の位相が少しずつずれて音程が揺れる）
+	jump := float64(best)
+	before, after := correlate(best-1, 1), correlate(best+1, 1)
+	if denom := before - 2*bestScore + after; denom < 0 {
+		if frac := float64(0.5 * (before - after) / denom); math.Abs(frac) < 1 {
+			jump += frac
+		}
+	}
+
+	p.fadePos = p.pos
+	p.pos += float64(direction) * jump
+	p.fade = p.fadeLen
+}
@@ -0,0 +1,45 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+// TestPitchShiftMovesPitch はピッチシフトで音程だけが半音の分だけ変わり、長さは変わらないことを確認する
+func TestPitchShiftMovesPitch(t *testing.T) {
+	const (
+		sampleRate = 44100
+		block      = 512
+		duration   = 5.0 // tone_440hz.wav の長さ（秒）
+	)
+
+	tests := []struct {
+		semitones float64
+		want      float64 // Hz
+	}{
+		{12, 880},
+		{-12, 220},
+		{7, 440 * math.Pow(2, 7.0/12)}, // 約 659.26Hz（完全五度）
+		{0, 440},
+	}
+
+	for _, tt := range tests {
+		track := NewTrack(sampleRate)
+		if err := track.LoadWithMode(testdataDir+"/tone_440hz.wav", LoadMemory); err != nil {
+			t.Fatal(err)
+		}
+		track.PitchShift.SetSemitones(tt.semitones)
+		out := playToEnd(t, track, block)
+
+		want := duration * sampleRate
+		if frames := float64(len(out) / 2); frames < want || frames > want+block {
+			t.Errorf("%+.0f semitones: played %.0f frames, want %.0f", tt.semitones, frames, want)
+		}
+		for ch := 0; ch < 2; ch++ {
+			if got := peakFreq(out, ch, sampleRate); math.Abs(got-tt.want) > 1 {
+				t.Errorf("%+.0f semitones: channel %d peak %.2f Hz, want %.2f Hz", tt.semitones, ch, got, tt.want)
+			}
+		}
+		track.Close()
+	}
+}
@@ -0,0 +1,263 @@
+package audio
+
+import "math"
+
+// サンプルレート変換（windowed-sinc / Kaiser窓）
+//
+// 解説：出力1フレームごとに、入力上の位置 t の周りの 2×halfWidth 個の入力を
+// 窓付き sinc カーネルで重み付けして足し合わせる。
+// ダウンサンプリング時はカットオフを下げてエイリアシングを防ぐ。
+const (
+	resampleZeroCrossings = 32    // 片側のゼロ交差数（品質と計算量のトレードオフ）
+	resampleTableRes      = 512   // カーネルテーブルの1サンプルあたりの分解能
+	resampleRolloff       = 0.91  // ナイキスト周波数に対するカットオフ位置
+	resampleKaiserBeta    = 8.6   // 阻止域 約 -90dB
+	resampleMaxPhases     = 1024  // これ以下の位相数ならフィルタバンクを事前計算する
+	resampleCompactFrames = 16384 // 消費済みの入力がこれを超えたら履歴を詰める
+)
+
+// Resampler はストリーミング対応のサンプルレート変換器
+// 入力をチャンクごとに Process に渡し、最後に Flush を呼ぶ
+type Resampler struct {
+	fromRate int
+	toRate   int
+	channels int
+
+	// 出力 n フレーム目は入力の n × up / down の位置（整数で管理して誤差を溜めない）
+	up   int64 // toRate / gcd
+	down int64 // fromRate / gcd
+
+	cutoff    float64     // 正規化カットオフ（入力ナイキスト = 1.0）
+	halfWidth int         // カーネルの片側の長さ（入力フレーム数）
+	table     []float64   // カーネルの片側（0 ～ halfWidth を resampleTableRes 分割）
+	bank      [][]float64 // 位相ごとのタップ係数（up が小さい場合のみ）
+	weights   []float64   // 出力1フレーム分のタップ係数（bank がない場合の作業用）
+
+	history  []float32 // 未消費の入力（インターリーブ）
+	dropped  int64     // history から捨てた入力フレーム数
+	maxChunk int       // Reserve で予約した、1回の Process の入力フレーム数
+
+	framesIn  int64 // 受け取った入力フレーム数
+	framesOut int64 // 出力したフレーム数
+}
+
+// NewResampler はサンプルレート変換器を作成
+func NewResampler(fromRate, toRate, channels int) *Resampler {
+	g := gcd(fromRate, toRate)
+	r := &Resampler{
+		fromRate: fromRate,
+		toRate:   toRate,
+		channels: channels,
+		up:       int64(toRate / g),
+		down:     int64(fromRate / g),
+		cutoff:   resampleRolloff,
+	}
+	if toRate < fromRate {
+		r.cutoff = resampleRolloff * float64(toRate) / float64(fromRate)
+	}
+	r.halfWidth = int(math.Ceil(resampleZeroCrossings / r.cutoff))
+	r.weights = make([]float64, 2*r.halfWidth)
+
+	// カーネルテーブル（左右対称なので片側だけ持つ）
+	size := r.halfWidth*resampleTableRes + 2
+	r.table = make([]float64, size)
+	norm := besselI0(resampleKaiserBeta)
+	for i := range r.table {
+		d := float64(i) / resampleTableRes
+		if d > float64(r.halfWidth) {
+			continue
+		}
+		x := d / float64(r.halfWidth)
+		window := besselI0(resampleKaiserBeta*math.Sqrt(1-x*x)) / norm
+		r.table[i] = r.cutoff * sinc(r.cutoff*d) * window
+	}
+
+	// 44.1k ⇔ 48k（147:160）のような比なら位相の数が少ないので、全位相の係数を先に計算する
+	if r.up <= resampleMaxPhases {
+		r.bank = make([][]float64, r.up)
+		for phase := range r.bank {
+			r.bank[phase] = make([]float64, 2*r.halfWidth)
+			r.fillWeights(r.bank[phase], float64(phase)/float64(r.up))
+		}
+	}
+
+	r.Reset()
+	return r
+}
+
+// Reset は内部状態をクリア（シーク時などに使う）
+func (r *Resampler) Reset() {
+	// 先頭に halfWidth 分の無音を置き、出力0 が入力0 と揃うようにする
+	size := r.halfWidth * r.channels
+	if capacity := r.historyCapacity(); cap(r.history) < capacity {
+		r.history = make([]float32, size, capacity)
+	} else {
+		r.history = r.history[:size]
+		clear(r.history)
+	}
+	r.dropped = 0
+	r.framesIn = 0
+	r.framesOut = 0
+}
+
+// historyCapacity は history が伸びうる最大の長さ
+// 💡 詰めずに残る消費済みの入力（最大 resampleCompactFrames）+ カーネルの幅の未消費分 + 1回の入力
+func (r *Resampler) historyCapacity() int {
+	return (resampleCompactFrames + 2*r.halfWidth + 2 + r.maxChunk) * r.channels
+}
+
+// Reserve は1回の Process に渡す入力を frames フレーム以下にするとき、Process の中で
+// メモリを確保しないよう、履歴の容量を先に確保しておく（オーディオスレッドで使う前に呼ぶ）
+// 💡 出力先の out も MaxOutput(frames) フレーム分の空きを用意しておくこと
+func (r *Resampler) Reserve(frames int) {
+	r.maxChunk = frames
+	if capacity := r.historyCapacity(); cap(r.history) < capacity {
+		r.history = append(make([]float32, 0, capacity), r.history...)
+	}
+}
+
+// MaxOutput は frames フレーム以下の入力を1回 Process したときに出力されうる最大のフレーム数
+// （カーネルの幅の分だけ前の呼び出しで出せずに残っていた出力も含む）
+func (r *Resampler) MaxOutput(frames int) int {
+	return int((int64(frames+2*r.halfWidth+2)*r.up+r.down-1)/r.down) + 1
+}
+
+// Process は入力チャンクを変換し、out に追記して返す
+func (r *Resampler) Process(in []float32, out []float32) []float32 {
+	r.history = append(r.history, in...)
+	r.framesIn += int64(len(in) / r.channels)
+	return r.drain(out, r.expectedFrames())
+}
+
+// Flush は残りの入力をすべて出力する
+// 出力の総フレーム数は 入力フレーム数 × toRate / fromRate（四捨五入）になる
+func (r *Resampler) Flush(out []float32) []float32 {
+	r.history = append(r.history, make([]float32, (r.halfWidth+2)*r.channels)...)
+	return r.drain(out, r.expectedFrames())
+}
+
+// expectedFrames はこれまでの入力に対応する出力フレーム数
+func (r *Resampler) expectedFrames() int64 {
+	return int64(math.Round(float64(r.framesIn) * float64(r.toRate) / float64(r.fromRate)))
+}
+
+// drain は history から計算できる分だけ出力する（最大 limit フレームまで）
+func (r *Resampler) drain(out []float32, limit int64) []float32 {
+	available := len(r.history) / r.channels
+	ch := r.channels
+
+	for r.framesOut < limit {
+		// 入力上の位置 = 整数部 center + 小数部 phase / up
+		num := r.framesOut * r.down
+		center := int(num/r.up) + r.halfWidth - int(r.dropped)
+		phase := num % r.up
+		if center+r.halfWidth >= available {
+			break
+		}
+
+		var weights []float64
+		if r.bank != nil {
+			weights = r.bank[phase]
+		} else {
+			// 重みは全チャンネル共通なので先に計算しておく
+			weights = r.weights
+			r.fillWeights(weights, float64(phase)/float64(r.up))
+		}
+
+		start := center - r.halfWidth + 1
+		for c := 0; c < ch; c++ {
+			var sum float64
+			for j, w := range weights {
+				sum += float64(r.history[(start+j)*ch+c]) * w
+			}
+			out = append(out, float32(sum))
+		}
+
+		r.framesOut++
+	}
+
+	// 消費済みの入力を捨てる（たまにまとめて詰める）
+	next := int(r.framesOut*r.down/r.up) - int(r.dropped)
+	if next > resampleCompactFrames {
+		r.history = append(r.history[:0], r.history[next*ch:]...)
+		r.dropped += int64(next)
+	}
+
+	return out
+}
+
+// fillWeights は小数部 frac の位置に対するタップ係数を計算する
+// タップ j は入力 center-halfWidth+1+j に対応する
+func (r *Resampler) fillWeights(weights []float64, frac float64) {
+	for j := range weights {
+		weights[j] = r.kernel(frac + float64(r.halfWidth-1-j))
+	}
+}
+
+// kernel はテーブルを線形補間してカーネル値を返す
+func (r *Resampler) kernel(d float64) float64 {
+	if d < 0 {
+		d = -d
+	}
+	pos := d * resampleTableRes
+	i := int(pos)
+	if i+1 >= len(r.table) {
+		return 0
+	}
+	f := pos - float64(i)
+	return r.table[i] + (r.table[i+1]-r.table[i])*f
+}
+
+// Resample はインターリーブされたデータ全体を変換する
+func Resample(data []float32, channels, fromRate, toRate int) []float32 {
+	if fromRate == toRate || channels <= 0 || len(data) == 0 {
+		return data
+	}
+
+	r := NewResampler(fromRate, toRate, channels)
+	estimated := int(float64(len(data))*float64(toRate)/float64(fromRate)) + channels
+	out := make([]float32, 0, estimated)
+
+	// 大きなチャンクで少しずつ渡す（history が膨らみすぎないように）
+	chunk := resampleCompactFrames * channels
+	for start := 0; start < len(data); start += chunk {
+		end := start + chunk
+		if end > len(data) {
+			end = len(data)
+		}
+		out = r.Process(data[start:end], out)
+	}
+	return r.Flush(out)
+}
+
+// gcd は最大公約数
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// sinc は正規化 sinc 関数 sin(πx)/(πx)
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	px := math.Pi * x
+	return math.Sin(px) / px
+}
+
+// besselI0 は第1種変形ベッセル関数 I0（Kaiser窓の計算用）
+func besselI0(x float64) float64 {
+	sum := 1.0
+	term := 1.0
+	halfX := x / 2
+	for k := 1; k < 50; k++ {
+		term *= (halfX / float64(k)) * (halfX / float64(k))
+		sum += term
+		if term < sum*1e-12 {
+			break
+		}
+	}
+	return sum
+}
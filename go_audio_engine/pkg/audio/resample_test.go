@@ -0,0 +1,224 @@
+package audio
+
+import (
+	"math"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// stereoSine は frames フレームのステレオのサイン波（左右同じ）
+func stereoSine(freq, amplitude float64, sampleRate, frames int) []float32 {
+	data := make([]float32, frames*2)
+	for i := 0; i < frames; i++ {
+		v := float32(amplitude * sine(freq, sampleRate, i))
+		data[i*2], data[i*2+1] = v, v
+	}
+	return data
+}
+
+// rmsDB はステレオ・インターリーブの中央の半分の RMS（dBFS、振幅 1.0 のサイン波が -3dB）
+// 💡 両端のフィルターの立ち上がりを避ける
+func rmsDB(stereo []float32) float64 {
+	from, to := len(stereo)/4, len(stereo)*3/4
+	var sum float64
+	for _, v := range stereo[from:to] {
+		sum += float64(v) * float64(v)
+	}
+	return 10 * math.Log10(sum/float64(to-from))
+}
+
+// TestResampleKeepsPitchAndDuration は 48kHz → 44.1kHz（とその逆）で、周波数と秒数が変わらないことを確認する
+func TestResampleKeepsPitchAndDuration(t *testing.T) {
+	const (
+		freq    = 440.0
+		seconds = 2
+	)
+
+	for _, rates := range [][2]int{{48000, 44100}, {44100, 48000}, {22050, 44100}} {
+		from, to := rates[0], rates[1]
+		in := stereoSine(freq, 0.5, from, from*seconds)
+		out := Resample(in, 2, from, to)
+
+		if frames := len(out) / 2; frames != to*seconds {
+			t.Errorf("%d -> %d: frames = %d, want %d (%d seconds)", from, to, frames, to*seconds, seconds)
+		}
+		if got := zeroCrossingFreq(out, 0, to); math.Abs(got-freq) > 0.5 {
+			t.Errorf("%d -> %d: frequency = %.2f Hz, want %.0f Hz", from, to, got, freq)
+		}
+
+		// 出力 n フレーム目は入力の n × from / to フレーム目（遅れなし）
+		for i := to / 2; i < to; i += 997 {
+			want := 0.5 * sine(freq, to, i)
+			if math.Abs(float64(out[i*2])-want) > 1e-3 {
+				t.Fatalf("%d -> %d: frame %d = %v, want %v", from, to, i, out[i*2], want)
+			}
+		}
+	}
+}
+
+// TestResampleChunked は Process に細かく分けて渡しても、Resample でまとめて変換したのと同じになることを確認する
+func TestResampleChunked(t *testing.T) {
+	in := stereoSine(1000, 0.5, 48000, 48000)
+	want := Resample(in, 2, 48000, 44100)
+
+	r := NewResampler(48000, 44100, 2)
+	var got []float32
+	for start := 0; start < len(in); start += 333 * 2 {
+		got = r.Process(in[start:min(start+333*2, len(in))], got)
+	}
+	got = r.Flush(got)
+
+	if len(got) != len(want) {
+		t.Fatalf("frames = %d, want %d", len(got)/2, len(want)/2)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sample %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+// TestResamplerReserve は Reserve した長さ以下の入力なら、最初の呼び出しから Process がメモリを確保せず、
+// 1回の出力が MaxOutput を超えないことを確認する（出力デバイスのコールバックで使う場合）
+func TestResamplerReserve(t *testing.T) {
+	const block = MaxBlockFrames
+
+	for _, rates := range [][2]int{{44100, 48000}, {48000, 44100}, {8000, 192000}, {192000, 8000}} {
+		from, to := rates[0], rates[1]
+		r := NewResampler(from, to, 2)
+		r.Reserve(block)
+		in := stereoSine(440, 0.5, from, from+block) // 1秒分を繰り返し使う
+		out := make([]float32, 0, r.MaxOutput(block)*2)
+
+		// 長さの違うブロックを順に渡す（何秒か通すと、履歴を詰めるところも通る）
+		// 💡 AllocsPerRun は1回空回ししてから数えるので、最初の呼び出しで広げていないかは Mallocs で数える
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		next, maxOutput := 0, true
+		for next < from*4 {
+			frames := []int{block, 1, 333, block - 1, 512}[next%5]
+			chunk := in[(next%from)*2 : (next%from+frames)*2]
+			next += frames
+			out = r.Process(chunk, out[:0])
+			maxOutput = maxOutput && len(out) <= r.MaxOutput(frames)*2
+		}
+		runtime.ReadMemStats(&after)
+
+		if allocs := after.Mallocs - before.Mallocs; allocs != 0 {
+			t.Errorf("%d -> %d: Process allocated %d times, want 0", from, to, allocs)
+		}
+		if !maxOutput {
+			t.Errorf("%d -> %d: Process returned more frames than MaxOutput", from, to)
+		}
+	}
+}
+
+// TestResampleStopband はダウンサンプリングで、新しいナイキスト周波数より上の成分が消えることを確認する
+// （通過域はほぼ平坦なまま）
+func TestResampleStopband(t *testing.T) {
+	const from, to = 48000, 44100
+
+	tests := []struct {
+		freq float64
+		max  float64 // 入力に対する出力のレベル（dB）の上限
+		min  float64 // 下限
+	}{
+		{1000, 0.05, -0.05},
+		{15000, 0.05, -0.05},
+		{23000, -80, math.Inf(-1)}, // 44.1kHz では 21.1kHz に折り返してしまう成分
+		{23900, -80, math.Inf(-1)},
+	}
+
+	for _, tt := range tests {
+		in := stereoSine(tt.freq, 0.5, from, from)
+		out := Resample(in, 2, from, to)
+		gain := rmsDB(out) - rmsDB(in)
+		if gain > tt.max || gain < tt.min {
+			t.Errorf("%.0f Hz: gain %.2f dB, want between %.2f and %.2f dB", tt.freq, gain, tt.min, tt.max)
+		}
+	}
+}
+
+// readFrom は jump で再生位置を動かしてから1ブロック読む
+// 💡 ストリーミングは移動した直後のブロックが無音なので、先読みが ahead 秒溜まるのを待ってから読み直す
+// （この後に続けて読む分まで待たないと、-race のように遅いときに途中で先読みが切れる）
+func readFrom(track *Track, jump func(), out []float32, ahead float64) {
+	jump()
+	track.Play()
+	track.ReadSamples(out)
+	if !track.IsStreaming() {
+		return
+	}
+	for i := 0; i < 500; i++ {
+		if buffered, _ := track.StreamStats(); buffered >= ahead {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	jump()
+	track.ReadSamples(out)
+}
+
+// TestResampledCueAndLoop は 48kHz の曲を 44.1kHz のエンジンに読み込んでも、
+// キューポイントとループの位置（秒）が元の曲の同じ瞬間を指すことを確認する
+func TestResampledCueAndLoop(t *testing.T) {
+	const (
+		sampleRate = 44100
+		cueAt      = 0.1
+		loopEnd    = 0.2
+		blockSize  = 512
+	)
+
+	// tone_440hz_48k.flac：440Hz、0.25秒、左 0.5 倍・右 -0.25 倍
+	expect := func(t *testing.T, out []float32, position float64) {
+		t.Helper()
+		for i := 0; i < len(out)/2; i++ {
+			s := math.Sin(2 * math.Pi * 440 * (position + float64(i)/sampleRate))
+			if math.Abs(float64(out[i*2])-0.5*s) > 1e-3 || math.Abs(float64(out[i*2+1])+0.25*s) > 1e-3 {
+				t.Fatalf("%.4fs + frame %d = %v, %v, want %v, %v", position, i, out[i*2], out[i*2+1], 0.5*s, -0.25*s)
+			}
+		}
+	}
+
+	for _, tt := range []struct {
+		name string
+		mode LoadMode
+	}{{"memory", LoadMemory}, {"streaming", LoadStreaming}} {
+		t.Run(tt.name, func(t *testing.T) {
+			track := NewTrack(sampleRate)
+			if err := track.LoadWithMode(testdataDir+"/tone_440hz_48k.flac", tt.mode); err != nil {
+				t.Fatal(err)
+			}
+			defer track.Close()
+			if d := track.GetDuration(); math.Abs(d-0.25) > 1e-9 {
+				t.Errorf("duration = %v, want 0.25", d)
+			}
+
+			// キューポイント
+			track.CueManager.AddCuePoint("cue", cueAt, "red")
+			out := make([]float32, blockSize*2)
+			readFrom(track, func() { track.JumpToCuePoint(0) }, out, loopEnd-cueAt+float64(blockSize)/sampleRate)
+			expect(t, out, cueAt)
+
+			// ループ：終点を過ぎたブロックの次は、ちょうど始点から鳴る
+			track.CueManager.SetLoop(cueAt, loopEnd)
+			track.CueManager.ActivateLoop()
+			wraps := 0 // 始点に戻った回数
+			for block := 0; block < 40; block++ {
+				position := track.GetPosition()
+				if position < cueAt || position >= loopEnd+float64(blockSize)/sampleRate {
+					t.Fatalf("block %d: position %v outside loop %v - %v", block, position, cueAt, loopEnd)
+				}
+				if position == cueAt {
+					wraps++
+				}
+				track.ReadSamples(out)
+				expect(t, out, position)
+			}
+			if wraps < 2 {
+				t.Errorf("loop wrapped %d times, want at least 2", wraps)
+			}
+		})
+	}
+}
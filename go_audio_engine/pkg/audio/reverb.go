@@ -0,0 +1,130 @@
+package audio
+
+// リバーブ（Freeverb）
+//
+// 解説：部屋の壁で音が何度も跳ね返る響きを、フィードバック付きのディレイの組み合わせで作る（Jezar の Freeverb）
+//
+//	入力 → 8本のコムフィルター（並列、長さがばらばらのディレイ）→ 4本のオールパス（直列、響きを細かく散らす）→ 出力
+//
+// コムフィルターのフィードバックの中にローパスを入れて、高い音ほど早く消えるようにしている（damping）。
+// 右チャンネルはディレイを少しだけ長くして、左右に広がった響きにする。
+// 💡 ディレイの長さは 44.1kHz での値。ほかのサンプルレートでは同じ時間になるように伸び縮みさせる
+var (
+	reverbCombTuning    = [...]int{1116, 1188, 1277, 1356, 1422, 1491, 1557, 1617}
+	reverbAllpassTuning = [...]int{556, 441, 341, 225}
+)
+
+const (
+	reverbStereoSpread = 23    // 右チャンネルのディレイを長くするサンプル数
+	reverbInputGain    = 0.015 // コムフィルターに入れる前の音量（8本を足しても大きくなりすぎない）
+	reverbAllpassGain  = 0.5
+)
+
+// Reverb はリバーブ
+type Reverb struct {
+	size    *EffectParam // 部屋の大きさ（響きの長さ）
+	damping *EffectParam // 高い音の消えやすさ
+
+	// 以下はオーディオスレッド専用
+	combs     [2][len(reverbCombTuning)]reverbComb
+	allpasses [2][len(reverbAllpassTuning)]reverbAllpass
+}
+
+// reverbComb はローパス入りのコムフィルター
+type reverbComb struct {
+	buffer []float32
+	pos    int
+	store  float32 // ローパスの状態
+}
+
+// reverbAllpass はオールパス（響きの密度を上げる）
+type reverbAllpass struct {
+	buffer []float32
+	pos    int
+}
+
+// NewReverb はリバーブを作成
+func NewReverb(sampleRate float64) *Reverb {
+	r := &Reverb{
+		size:    newEffectParam("size", 0, 1, 0.5),
+		damping: newEffectParam("damping", 0, 1, 0.5),
+	}
+	scale := sampleRate / 44100
+	for ch := 0; ch < 2; ch++ {
+		spread := ch * reverbStereoSpread
+		for i, length := range reverbCombTuning {
+			r.combs[ch][i].buffer = make([]float32, int(float64(length+spread)*scale))
+		}
+		for i, length := range reverbAllpassTuning {
+			r.allpasses[ch][i].buffer = make([]float32, int(float64(length+spread)*scale))
+		}
+	}
+	return r
+}
+
+// Name はエフェクトの名前
+func (r *Reverb) Name() string {
+	return "reverb"
+}
+
+// Params はつまみの一覧
+func (r *Reverb) Params() []*EffectParam {
+	return []*EffectParam{r.size, r.damping}
+}
+
+// Reset は響きを消す
+func (r *Reverb) Reset() {
+	for ch := 0; ch < 2; ch++ {
+		for i := range r.combs[ch] {
+			clear(r.combs[ch][i].buffer)
+			r.combs[ch][i].store = 0
+		}
+		for i := range r.allpasses[ch] {
+			clear(r.allpasses[ch][i].buffer)
+		}
+	}
+}
+
+// Process はリバーブをかける（響きだけを出す）
+func (r *Reverb) Process(samples []float32, beatSeconds float64) {
+	feedback := float32(0.7 + 0.28*r.size.Get())
+	damp := float32(0.4 * r.damping.Get())
+
+	for i := 0; i+1 < len(samples); i += 2 {
+		// 💡 響きの元は左右を混ぜたモノラル（左右の響きの違いはディレイの長さの違いで作る）
+		input := (samples[i] + samples[i+1]) * reverbInputGain
+		for ch := 0; ch < 2; ch++ {
+			var out float32
+			for k := range r.combs[ch] {
+				out += r.combs[ch][k].process(input, feedback, damp)
+			}
+			for k := range r.allpasses[ch] {
+				out = r.allpasses[ch][k].process(out)
+			}
+			samples[i+ch] = out
+		}
+	}
+}
+
+// process は1サンプル進める
+func (c *reverbComb) process(input, feedback, damp float32) float32 {
+	output := c.buffer[c.pos]
+	c.store = output*(1-damp) + c.store*damp
+	c.buffer[c.pos] = input + c.store*feedback
+	c.pos++
+	if c.pos >= len(c.buffer) {
+		c.pos = 0
+	}
+	return output
+}
+
+// process は1サンプル進める
+func (a *reverbAllpass) process(input float32) float32 {
+	delayed := a.buffer[a.pos]
+	a.buffer[a.pos] = input + delayed*reverbAllpassGain
+	a.pos++
+	if a.pos >= len(a.buffer) {
+		a.pos = 0
+	}
+	return delayed - input
+}
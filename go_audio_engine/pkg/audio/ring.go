@@ -0,0 +1,69 @@
+package audio
+
+import "sync/atomic"
+
+// RingBuffer は書き手1つ・読み手1つ（SPSC）のロックフリーなリングバッファ
+// オーディオスレッドから別のスレッド（別デバイスのコールバックやファイル書き込み）へ音声を渡すのに使う
+// 💡 どちらの側もブロックしない。満杯なら書き込めなかった分を捨て、空なら読めた分だけ返す
+type RingBuffer struct {
+	buf      []float32
+	mask     uint64
+	writePos atomic.Uint64 // 書き込んだサンプル数の合計
+	readPos  atomic.Uint64 // 読み出したサンプル数の合計
+}
+
+// NewRingBuffer は capacity サンプル以上（2のべき乗に切り上げ）のリングバッファを作成
+func NewRingBuffer(capacity int) *RingBuffer {
+	size := 1
+	for size < capacity {
+		size <<= 1
+	}
+	return &RingBuffer{
+		buf:  make([]float32, size),
+		mask: uint64(size - 1),
+	}
+}
+
+// Capacity は格納できるサンプル数
+func (r *RingBuffer) Capacity() int {
+	return len(r.buf)
+}
+
+// Available は読み出せるサンプル数
+func (r *RingBuffer) Available() int {
+	return int(r.writePos.Load() - r.readPos.Load())
+}
+
+// Free は書き込めるサンプル数
+func (r *RingBuffer) Free() int {
+	return len(r.buf) - r.Available()
+}
+
+// Write は samples を書き込み、書き込めたサンプル数を返す（書き手のスレッドから呼ぶ）
+func (r *RingBuffer) Write(samples []float32) int {
+	write := r.writePos.Load()
+	free := uint64(len(r.buf)) - (write - r.readPos.Load())
+	n := uint64(len(samples))
+	if n > free {
+		n = free
+	}
+	for i := uint64(0); i < n; i++ {
+		r.buf[(write+i)&r.mask] = samples[i]
+	}
+	r.writePos.Store(write + n)
+	return int(n)
+}
+
+// Read は dst に読み出し、読めたサンプル数を返す（読み手のスレッドから呼ぶ）
+func (r *RingBuffer) Read(dst []float32) int {
+	read := r.readPos.Load()
+	n := r.writePos.Load() - read
+	if n > uint64(len(dst)) {
+		n = uint64(len(dst))
+	}
+	for i := uint64(0); i < n; i++ {
+		dst[i] = r.buf[(read+i)&r.mask]
+	}
+	r.readPos.Store(read + n)
+	return int(n)
+}
@@ -0,0 +1,96 @@
+package audio
+
+// パラメーターのスムージング
+//
+// 解説：つまみを回すと、値は API から飛び飛び（階段状）に届く。そのままゲインや係数に使うと
+// 段差のたびに波形が折れて「ジジジ」というノイズ（ジッパーノイズ）になる。
+// Smoother は新しい目標値に向かって、決まった時間をかけて直線で近づける。
+//
+//	ゲイン：毎サンプル Next で進めて掛ける
+//	フィルターの係数：計算が重いので smoothBlockFrames ごとに Advance で進め、そのたびに計算し直す
+//
+// 💡 オーディオスレッド専用（目標値は各ブロックの頭で atomic の設定から読んで Set する）
+const (
+	SmoothSeconds      = 0.02  // つまみ（EQ・フィルター・音量）がなめらかに変わる時間
+	FaderSmoothSeconds = 0.003 // フェーダー・クロスフェーダー（カットの鋭さを損なわないよう短く）
+	smoothBlockFrames  = 16    // 係数を計算し直す間隔（フレーム）
+)
+
+// Smoother は値を目標値までなめらかに変化させる
+type Smoother struct {
+	value  float64
+	target float64
+	step   float64 // 1サンプルあたりの変化量
+	left   int     // 目標に着くまでの残りサンプル数
+	frames int     // 目標に着くまでにかけるサンプル数
+}
+
+// NewSmoother は seconds 秒かけて目標に近づく Smoother を作成（最初は initial のまま）
+func NewSmoother(sampleRate, seconds, initial float64) *Smoother {
+	frames := int(sampleRate * seconds)
+	if frames < 1 {
+		frames = 1
+	}
+	return &Smoother{
+		value:  initial,
+		target: initial,
+		frames: frames,
+	}
+}
+
+// Set は目標値を変える（今の値からやり直す）
+func (s *Smoother) Set(target float64) {
+	if target == s.target {
+		return
+	}
+	s.target = target
+	s.left = s.frames
+	s.step = (target - s.value) / float64(s.frames)
+}
+
+// Jump は目標値に一気に変える（再生していないときなど、なめらかにする必要がないとき）
+func (s *Smoother) Jump(value float64) {
+	s.value = value
+	s.target = value
+	s.left = 0
+}
+
+// Next は1サンプル進めて、そのサンプルで使う値を返す
+func (s *Smoother) Next() float64 {
+	if s.left > 0 {
+		s.left--
+		if s.left == 0 {
+			s.value = s.target // 丸め誤差を残さない
+		} else {
+			s.value += s.step
+		}
+	}
+	return s.value
+}
+
+// Advance は n サンプル進めて、その時点の値を返す
+func (s *Smoother) Advance(n int) float64 {
+	if s.left <= n {
+		s.value = s.target
+		s.left = 0
+	} else {
+		s.left -= n
+		s.value += s.step * float64(n)
+	}
+	return s.value
+}
+
+// Value は今の値を返す
+func (s *Smoother) Value() float64 {
+	return s.value
+}
+
+// Target は目標値を返す
+func (s *Smoother) Target() float64 {
+	return s.target
+}
+
+// Ramping は目標に向かって変化している途中か
+func (s *Smoother) Ramping() bool {
+	return s.left > 0
+}
@@ -0,0 +1,111 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+// TestSmootherStep は目標を段差で変えたとき、1サンプルあたりの変化が一定量に収まり、
+// SmoothSeconds 以内に目標に着くことを確認する
+func TestSmootherStep(t *testing.T) {
+	const sampleRate = 44100.0
+	rampFrames := int(sampleRate * SmoothSeconds)
+
+	tests := []struct {
+		name            string
+		initial, target float64
+	}{
+		{"up", 0, 1},
+		{"down", 1, 0},
+		{"bipolar", -1, 1},
+		{"small", 0.5, 0.51},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSmoother(sampleRate, SmoothSeconds, tt.initial)
+			s.Set(tt.target)
+
+			// 段差を rampFrames サンプルに均等に分けた量より大きく動かない
+			maxStep := math.Abs(tt.target-tt.initial)/float64(rampFrames) + 1e-12
+			prev := tt.initial
+			arrived := -1
+			for i := 0; i < rampFrames*2; i++ {
+				v := s.Next()
+				if d := math.Abs(v - prev); d > maxStep {
+					t.Fatalf("sample %d: step %v, want <= %v", i, d, maxStep)
+				}
+				if (v-prev)*(tt.target-tt.initial) < 0 {
+					t.Fatalf("sample %d: moved away from the target (%v -> %v)", i, prev, v)
+				}
+				prev = v
+				if arrived < 0 && v == tt.target {
+					arrived = i + 1
+				}
+			}
+
+			if arrived < 0 || arrived > rampFrames {
+				t.Errorf("reached target after %d samples, want within %d (%v s)", arrived, rampFrames, SmoothSeconds)
+			}
+			if s.Ramping() || s.Value() != tt.target {
+				t.Errorf("after ramp: value %v ramping %v, want %v and not ramping", s.Value(), s.Ramping(), tt.target)
+			}
+		})
+	}
+}
+
+// TestSmootherRetarget はランプの途中で目標を変えても、今の値から段差なく向かい直すことを確認する
+func TestSmootherRetarget(t *testing.T) {
+	const sampleRate = 44100.0
+	rampFrames := int(sampleRate * SmoothSeconds)
+
+	s := NewSmoother(sampleRate, SmoothSeconds, 0)
+	s.Set(1)
+	for i := 0; i < rampFrames/2; i++ {
+		s.Next()
+	}
+	middle := s.Value()
+
+	s.Set(-1)
+	maxStep := (middle+1)/float64(rampFrames) + 1e-12
+	prev := middle
+	for i := 0; i < rampFrames; i++ {
+		v := s.Next()
+		if d := math.Abs(v - prev); d > maxStep {
+			t.Fatalf("sample %d: step %v, want <= %v", i, d, maxStep)
+		}
+		prev = v
+	}
+	if s.Value() != -1 || s.Ramping() {
+		t.Errorf("value %v ramping %v, want -1 within %d samples", s.Value(), s.Ramping(), rampFrames)
+	}
+}
+
+// TestSmootherAdvance は Advance（係数の計算し直し用）が Next を n 回呼んだのと同じ値になることを確認する
+func TestSmootherAdvance(t *testing.T) {
+	const sampleRate = 44100.0
+	a := NewSmoother(sampleRate, SmoothSeconds, 0)
+	b := NewSmoother(sampleRate, SmoothSeconds, 0)
+	a.Set(1)
+	b.Set(1)
+
+	for block := 0; a.Ramping() || b.Ramping(); block++ {
+		got := a.Advance(smoothBlockFrames)
+		var want float64
+		for i := 0; i < smoothBlockFrames; i++ {
+			want = b.Next()
+		}
+		if math.Abs(got-want) > 1e-9 {
+			t.Fatalf("block %d: Advance = %v, Next = %v", block, got, want)
+		}
+		if block > int(sampleRate*SmoothSeconds)/smoothBlockFrames+1 {
+			t.Fatalf("still ramping after %d blocks", block)
+		}
+	}
+
+	// Jump はなめらかにせずに一気に変える
+	a.Jump(0.25)
+	if a.Value() != 0.25 || a.Target() != 0.25 || a.Ramping() {
+		t.Errorf("after Jump: value %v target %v ramping %v, want 0.25", a.Value(), a.Target(), a.Ramping())
+	}
+}
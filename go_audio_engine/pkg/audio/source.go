@@ -0,0 +1,363 @@
+package audio
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Source はトラックのPCMデータの供給元
+// データは常にエンジンのサンプルレートのステレオ（インターリーブ）
+type Source interface {
+	// Frames は総フレーム数
+	Frames() int64
+
+	// Fetch は frame から最大 frames 個分のデータを返す
+	// 戻り値は dst か内部データのスライスで、長さは「読めたフレーム数 × 2」
+	// 💡 オーディオスレッドから呼ばれるので、絶対にブロックしてはいけない
+	Fetch(dst []float32, frame int64, frames int) []float32
+
+	// Streaming はディスクから逐次読み込んでいるか
+	Streaming() bool
+
+	Close() error
+}
+
+// memorySource は全データをメモリに持つ（短いサンプル向け）
+type memorySource struct {
+	data []float32
+}
+
+func (s *memorySource) Frames() int64 { return int64(len(s.data) / 2) }
+
+func (s *memorySource) Fetch(dst []float32, frame int64, frames int) []float32 {
+	if frame < 0 || frame >= s.Frames() {
+		return dst[:0]
+	}
+	end := frame + int64(frames)
+	if end > s.Frames() {
+		end = s.Frames()
+	}
+	// コピー不要：元データをそのまま返す
+	return s.data[frame*2 : end*2]
+}
+
+func (s *memorySource) Streaming() bool { return false }
+
+func (s *memorySource) Close() error { return nil }
+
+// ストリーミング再生の設定
+const (
+	streamBufferSeconds  = 30   // リングバッファの長さ（再生位置より後ろは短いループ用に残る）
+	streamAheadSeconds   = 8    // 再生位置からどこまで先読みするか
+	streamSeekSlackSec   = 1.0  // 書き込み位置よりこれ以上先を要求されたら読み直す
+	streamChunkFrames    = 4096 // 1回にデコードするフレーム数（元ファイルのレート）
+	streamPrerollFrames  = 64   // シーク時にリサンプラーを馴染ませるための助走
+	streamRefillInterval = 5 * time.Millisecond
+)
+
+// streamSource はディスクから先読みしながら再生するソース
+//
+// 解説：バックグラウンドのゴルーチン（書き手）がデコード→ステレオ化→リサンプルして
+// リングバッファに書き込み、オーディオスレッド（読み手）はそこから読むだけ。
+// 書き手と読み手はアトミック変数だけでやり取りするので、読み手は決してブロックしない。
+// 読み手が1つ（オーディオスレッド）であることが前提。
+type streamSource struct {
+	file   io.Closer
+	stream PCMStream
+
+	mixer     *StereoMixer // nil ならステレオのまま
+	resampler *Resampler   // nil ならレート変換なし
+	srcRate   int
+	outRate   int
+	frames    int64
+
+	ring     []float32 // capacity フレーム分（インターリーブ）
+	capacity int64
+	ahead    int64
+	guard    int64 // 書き手が1回に書く最大フレーム数（上書き競合の余裕）
+	slack    int64
+
+	// 書き手 → 読み手
+	start    atomic.Int64 // 現在のバッファの先頭（シーク先）
+	writePos atomic.Int64 // 次に書き込むフレーム
+	ackGen   atomic.Int64 // 処理済みのシーク要求番号
+	eof      atomic.Bool
+
+	// 読み手 → 書き手
+	readPos    atomic.Int64 // 読み手が必要としている最小のフレーム
+	seekTarget atomic.Int64
+	reqGen     atomic.Int64 // シーク要求番号
+
+	underruns atomic.Int64
+
+	// 読み手だけが触る
+	pendingTarget int64
+
+	// 書き手だけが触る
+	decodeBuf []float32
+	stereoBuf []float32
+	resampBuf []float32
+	discard   int64 // シーク直後に捨てるフレーム数（助走分）
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newStreamSource はストリームからストリーミングソースを作り、先読みを開始する
+func newStreamSource(file io.Closer, stream PCMStream, outRate int) (*streamSource, error) {
+	length := stream.Length()
+	if length <= 0 {
+		return nil, fmt.Errorf("streaming requires a known length")
+	}
+
+	srcRate := stream.SampleRate()
+	ratio := float64(outRate) / float64(srcRate)
+
+	s := &streamSource{
+		file:      file,
+		stream:    stream,
+		srcRate:   srcRate,
+		outRate:   outRate,
+		frames:    int64(math.Round(float64(length) * ratio)),
+		capacity:  int64(streamBufferSeconds * outRate),
+		ahead:     int64(streamAheadSeconds * outRate),
+		guard:     int64(float64(streamChunkFrames+256)*ratio) + 256,
+		slack:     int64(streamSeekSlackSec * float64(outRate)),
+		decodeBuf: make([]float32, streamChunkFrames*stream.Channels()),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	s.ring = make([]float32, s.capacity*2)
+
+	if stream.Channels() != 2 {
+		s.mixer = NewStereoMixer(StreamChannelLayout(stream))
+	}
+	if srcRate != outRate {
+		s.resampler = NewResampler(srcRate, outRate, 2)
+	}
+
+	go s.run()
+	return s, nil
+}
+
+func (s *streamSource) Frames() int64 { return s.frames }
+
+func (s *streamSource) Streaming() bool { return true }
+
+// Underruns は先読みが間に合わなかった回数
+func (s *streamSource) Underruns() int64 { return s.underruns.Load() }
+
+// BufferedSeconds は再生位置より先に読み込み済みの長さ（秒）
+func (s *streamSource) BufferedSeconds() float64 {
+	if s.reqGen.Load() != s.ackGen.Load() {
+		return 0
+	}
+	ahead := s.writePos.Load() - s.readPos.Load()
+	if ahead < 0 {
+		ahead = 0
+	}
+	return float64(ahead) / float64(s.outRate)
+}
+
+func (s *streamSource) Close() error {
+	close(s.stop)
+	<-s.done
+	return s.file.Close()
+}
+
+// lowWater は安全に読めるバッファ内の最小フレーム
+func (s *streamSource) lowWater(start, writePos int64) int64 {
+	low := writePos - s.capacity + s.guard
+	if low < start {
+		low = start
+	}
+	return low
+}
+
+// Fetch はリングバッファからデータを読む（読み手＝オーディオスレッド専用）
+// バッファにない位置を要求されたら書き手に読み直しを依頼し、今回は無音（0フレーム）を返す
+func (s *streamSource) Fetch(dst []float32, frame int64, frames int) []float32 {
+	if frame < 0 || frame >= s.frames {
+		return dst[:0]
+	}
+
+	// シーク処理待ち
+	if s.reqGen.Load() != s.ackGen.Load() {
+		if frame < s.pendingTarget || frame > s.pendingTarget+s.slack {
+			s.requestSeek(frame)
+		}
+		return dst[:0]
+	}
+
+	start := s.start.Load()
+	writePos := s.writePos.Load()
+	if frame < s.lowWater(start, writePos) || frame > writePos+s.slack {
+		s.requestSeek(frame)
+		return dst[:0]
+	}
+
+	// 💡 先に読む位置を公開してから、上書きされていないか再確認する
+	s.readPos.Store(frame)
+	writePos = s.writePos.Load()
+	if frame < s.lowWater(start, writePos) {
+		s.requestSeek(frame)
+		return dst[:0]
+	}
+
+	n := writePos - frame
+	if n > int64(frames) {
+		n = int64(frames)
+	}
+	if n < 0 {
+		n = 0
+	}
+	if n < int64(frames) && frame+n < s.frames && !s.eof.Load() {
+		s.underruns.Add(1)
+	}
+
+	// リングバッファからコピー（折り返しに注意）
+	dst = dst[:n*2]
+	for copied := int64(0); copied < n; {
+		slot := (frame + copied) % s.capacity
+		count := n - copied
+		if slot+count > s.capacity {
+			count = s.capacity - slot
+		}
+		copy(dst[copied*2:(copied+count)*2], s.ring[slot*2:(slot+count)*2])
+		copied += count
+	}
+	return dst
+}
+
+// requestSeek は書き手に読み直しを依頼する（読み手専用）
+func (s *streamSource) requestSeek(frame int64) {
+	s.pendingTarget = frame
+	s.seekTarget.Store(frame)
+	s.reqGen.Add(1)
+}
+
+// run は書き手のゴルーチン
+func (s *streamSource) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(streamRefillInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+
+		// シーク要求があれば読み直す
+		if req := s.reqGen.Load(); req != s.ackGen.Load() {
+			s.reposition(s.seekTarget.Load())
+			s.ackGen.Store(req)
+			continue
+		}
+
+		// 十分先読みできていれば少し待つ
+		if s.eof.Load() || s.writePos.Load()-s.readPos.Load() >= s.ahead {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+			continue
+		}
+
+		s.fill()
+	}
+}
+
+// reposition はデコード位置を target（出力フレーム）に移動する（書き手専用）
+func (s *streamSource) reposition(target int64) {
+	srcFrame := int64(float64(target) * float64(s.srcRate) / float64(s.outRate))
+	preroll := int64(streamPrerollFrames)
+	if s.resampler != nil {
+		// 💡 助走はカーネルの片側より長くないと、最初の出力が無音を混ぜて計算される
+		preroll = max(preroll, int64(s.resampler.halfWidth))
+	}
+	from := srcFrame - preroll
+	if from < 0 {
+		from = 0
+	}
+	if s.resampler != nil {
+		// 解説：リサンプラーは Reset した位置を出力0 として、そこから down:up の格子で出力する。
+		// from が格子の上（down の倍数）にないと、出力が本来の位置から最大半フレームずれてしまう
+		from -= from % s.resampler.down
+	}
+
+	s.eof.Store(false)
+	if err := s.stream.SeekFrame(from); err != nil {
+		log.Printf("❌ [Stream] Seek failed: %v", err)
+		s.eof.Store(true)
+	}
+	if s.resampler != nil {
+		s.resampler.Reset()
+	}
+
+	// 助走分（from ～ target）は出力から捨てる
+	outStart := int64(math.Round(float64(from) * float64(s.outRate) / float64(s.srcRate)))
+	s.discard = target - outStart
+	if s.discard < 0 {
+		s.discard = 0
+	}
+
+	s.start.Store(target)
+	s.writePos.Store(target)
+	s.readPos.Store(target)
+}
+
+// fill は1チャンク分デコードしてリングバッファに書き込む（書き手専用）
+func (s *streamSource) fill() {
+	n, err := s.stream.Read(s.decodeBuf)
+	data := s.decodeBuf[:n]
+
+	if s.mixer != nil {
+		s.stereoBuf = s.mixer.Process(data, s.stereoBuf[:0])
+		data = s.stereoBuf
+	}
+	if s.resampler != nil {
+		s.resampBuf = s.resampler.Process(data, s.resampBuf[:0])
+		if err == io.EOF {
+			s.resampBuf = s.resampler.Flush(s.resampBuf)
+		}
+		data = s.resampBuf
+	}
+
+	if err != nil {
+		if err != io.EOF {
+			log.Printf("❌ [Stream] Decode error: %v", err)
+		}
+		s.eof.Store(true)
+	}
+
+	if s.discard > 0 {
+		skip := int64(len(data) / 2)
+		if skip > s.discard {
+			skip = s.discard
+		}
+		data = data[skip*2:]
+		s.discard -= skip
+	}
+
+	writePos := s.writePos.Load()
+	frames := int64(len(data) / 2)
+	for written := int64(0); written < frames; {
+		slot := (writePos + written) % s.capacity
+		count := frames - written
+		if slot+count > s.capacity {
+			count = s.capacity - slot
+		}
+		copy(s.ring[slot*2:(slot+count)*2], data[written*2:(written+count)*2])
+		written += count
+	}
+	// 💡 データを書き終えてから位置を進める（読み手はこの値までしか読まない）
+	s.writePos.Store(writePos + frames)
+}
@@ -0,0 +1,151 @@
+package audio
+
+import (
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+// memoryStream はメモリ上のデータを返す PCMStream（デコーダーの代わり）
+type memoryStream struct {
+	data       []float32
+	channels   int
+	sampleRate int
+	pos        int // 次に読むサンプル
+}
+
+func (s *memoryStream) SampleRate() int { return s.sampleRate }
+
+func (s *memoryStream) Channels() int { return s.channels }
+
+func (s *memoryStream) Length() int64 { return int64(len(s.data) / s.channels) }
+
+func (s *memoryStream) Read(dst []float32) (int, error) {
+	if s.pos >= len(s.data) {
+		return 0, io.EOF
+	}
+	n := copy(dst, s.data[s.pos:])
+	s.pos += n
+	return n, nil
+}
+
+func (s *memoryStream) SeekFrame(frame int64) error {
+	s.pos = int(frame) * s.channels
+	return nil
+}
+
+// nopCloser はファイルの代わり
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// fetchFull は先読みが追いつくのを待ちながら、frame から frames フレームを読む（読み手として振る舞う）
+func fetchFull(t *testing.T, source Source, frame int64, frames int) []float32 {
+	t.Helper()
+	frames = int(min(int64(frames), source.Frames()-frame))
+	dst := make([]float32, frames*2)
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if got := source.Fetch(dst, frame, frames); len(got) == frames*2 {
+			return got
+		}
+	}
+	t.Fatalf("frame %d: stream did not catch up within 5 seconds", frame)
+	return nil
+}
+
+// TestStreamSourceMatchesMemory はストリーミングとメモリ再生で、同じ位置から同じサンプルが読めることを確認する
+// 順に読む・シークする（シーク要求の追い越しも含む）・リングバッファの折り返しをまたぐループの3通り
+// 💡 リングバッファの長さは streamBufferSeconds 秒なので、レートを下げて短い曲でも一周させる
+func TestStreamSourceMatchesMemory(t *testing.T) {
+	const (
+		outRate = 1000
+		seconds = 60 // リングバッファ（30秒）の2倍
+		block   = 512
+	)
+
+	tests := []struct {
+		name     string
+		srcRate  int
+		channels int
+	}{
+		{"stereo", outRate, 2},
+		{"mono resampled", 1200, 1}, // ステレオ化とレート変換も通す
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 音程の違う成分を重ねて、どの位置でも波形が違うようにする
+			data := make([]float32, tt.srcRate*seconds*tt.channels)
+			for i := range data {
+				frame, ch := i/tt.channels, i%tt.channels
+				data[i] = float32(0.3*sine(97+float64(ch)*10, tt.srcRate, frame) +
+					0.2*sine(263, tt.srcRate, frame) + 0.1*math.Sin(float64(frame)*float64(frame)*1e-7))
+			}
+			stream := &memoryStream{data: data, channels: tt.channels, sampleRate: tt.srcRate}
+			engine, err := decodeToEngineFormat(stream, outRate, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			memory := &memorySource{data: engine}
+
+			streaming, err := newStreamSource(nopCloser{}, &memoryStream{data: data, channels: tt.channels, sampleRate: tt.srcRate}, outRate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer streaming.Close()
+			if streaming.Frames() != memory.Frames() {
+				t.Fatalf("frames = %d, want %d", streaming.Frames(), memory.Frames())
+			}
+
+			compare := func(frame int64, frames int) {
+				t.Helper()
+				got := fetchFull(t, streaming, frame, frames)
+				want := memory.Fetch(make([]float32, frames*2), frame, frames)
+				if len(got) != len(want) {
+					t.Fatalf("frame %d: %d samples, want %d", frame, len(got), len(want))
+				}
+				for i := range want {
+					if math.Abs(float64(got[i]-want[i])) > 1e-6 {
+						t.Fatalf("frame %d + sample %d = %v, want %v", frame, i, got[i], want[i])
+					}
+				}
+			}
+
+			// 順に読む（最後のブロックは途中で終わる）
+			for frame := int64(0); frame < memory.Frames(); frame += block {
+				compare(frame, block)
+			}
+
+			// シーク：先読みの外へ飛ぶと読み直しになる
+			for _, frame := range []int64{45000, 123, 59001, 20000, 20007, 37777} {
+				compare(frame, block)
+			}
+
+			// 読み直しが終わる前に別の位置へ飛んだら、古い要求の位置ではなく新しい位置が読める
+			before := streaming.reqGen.Load()
+			streaming.Fetch(make([]float32, block*2), 5000, block)
+			streaming.Fetch(make([]float32, block*2), 50000, block)
+			compare(50000, block)
+			if gen := streaming.reqGen.Load(); gen-before < 2 {
+				t.Errorf("seek generation advanced %d, want at least 2", gen-before)
+			}
+
+			// リングバッファの折り返し（30000 フレーム目）をまたぐループ：読み直さずに続けて読める
+			const loopStart, loopEnd = 28000, 33000
+			compare(loopStart-1000, block)
+			for frame := int64(loopStart - 1000); frame < loopStart; frame += block {
+				compare(frame, block)
+			}
+			gen := streaming.reqGen.Load()
+			for round := 0; round < 3; round++ {
+				for frame := int64(loopStart); frame < loopEnd; frame += block {
+					compare(frame, int(min(block, loopEnd-frame)))
+				}
+			}
+			if streaming.reqGen.Load() != gen {
+				t.Errorf("loop across the ring boundary re-read from disk (%d seeks)", streaming.reqGen.Load()-gen)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package audio
+
+import "math"
+
+// タイムストレッチ（WSOLA: Waveform Similarity Overlap-Add）
+//
+// 解説：元の音を約40msの「粒（グレイン）」に切り出し、窓をかけて半分ずつ重ねて並べる。
+// 出力側の間隔（hop）は一定のまま、入力側で取り出す位置を再生位置（Speed で進む）に合わせると、
+// 1粒の中は元の速さで再生されるのでピッチは変わらず、テンポだけが変わる。
+// 粒のつなぎ目で波形がずれるとザラついた音になるので、前の粒の「自然な続き」と
+// 最も似ている位置を ±10ms の範囲で探してから重ねる。
+const (
+	stretchHopMs       = 20 // 出力側の粒の間隔（粒の長さはこの2倍）
+	stretchToleranceMs = 10 // つなぎ目を探す範囲（±）
+	stretchCoarseStep  = 4  // 粗い探索の間隔（フレーム）
+)
+
+// TimeStretcher はピッチを変えずにテンポを変える（キーロック / マスターテンポ）
+// Source から直接粒を取り出すので、再生位置さえ渡せばシークやループにもそのまま追従する
+type TimeStretcher struct {
+	hop       int       // 出力側の間隔（フレーム）
+	grain     int       // 粒の長さ = hop × 2
+	tolerance int       // 探索範囲（±フレーム）
+	window    []float32 // ハン窓（50%重ねると合計がちょうど 1 になる）
+
+	acc      []float32 // 重ね合わせ用のバッファ（grain フレーム分、ステレオ）
+	ready    []float32 // 完成した出力（hop フレーム分、ステレオ）
+	readyPos int       // ready の読み出し位置（フレーム）
+
+	prevPos int64 // 直前の粒の入力上の位置（-1 ならリセット直後）
+
+	// 作業用バッファ（オーディオスレッド専用）
+	searchBuf []float32
+	refBuf    []float32
+	monoWin   []float32
+	monoRef   []float32
+}
+
+// NewTimeStretcher はタイムストレッチャーを作成
+func NewTimeStretcher(sampleRate int) *TimeStretcher {
+	hop := sampleRate * stretchHopMs / 1000
+	s := &TimeStretcher{
+		hop:       hop,
+		grain:     hop * 2,
+		tolerance: sampleRate * stretchToleranceMs / 1000,
+		window:    make([]float32, hop*2),
+		acc:       make([]float32, hop*4),
+		ready:     make([]float32, hop*2),
+	}
+	for i := range s.window {
+		s.window[i] = float32(0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(s.grain)))
+	}
+	s.Reset()
+	return s
+}
+
+// Reset は内部状態をクリア（トラックの差し替えやキーロックの切り替え時に使う）
+func (s *TimeStretcher) Reset() {
+	for i := range s.acc {
+		s.acc[i] = 0
+	}
+	s.readyPos = s.hop // 空
+	s.prevPos = -1
+}
+
+// Next は次の1フレームを返す
+// position は現在の再生位置（フレーム）で、呼び出し側は毎フレーム Speed ずつ進める
+func (s *TimeStretcher) Next(source Source, position float64) (float32, float32) {
+	if s.readyPos >= s.hop {
+		s.generate(source, int64(math.Round(position)))
+	}
+	l, r := s.ready[s.readyPos*2], s.ready[s.readyPos*2+1]
+	s.readyPos++
+	return l, r
+}
+
+// generate は位置 target 付近から1粒取り出して重ね合わせ、hop フレーム分の出力を作る
+func (s *TimeStretcher) generate(source Source, target int64) {
+	// 探索範囲と粒全体をまとめて取得
+	searchStart := target - int64(s.tolerance)
+	searchFrames := 2*s.tolerance + s.grain
+	s.searchBuf = fetchFrames(source, s.searchBuf, searchStart, searchFrames)
+
+	pos := target
+	if s.prevPos < 0 {
+		// 💡 リセット直後は1つ前の粒も重ねておき、最初から正しい音量で鳴るようにする
+		s.addGrain(source, target-int64(s.hop))
+		s.shift(nil)
+	} else {
+		pos = s.search(source, target, s.prevPos+int64(s.hop))
+	}
+
+	offset := int(pos - searchStart)
+	for i := 0; i < s.grain; i++ {
+		l, r := stereoAt(s.searchBuf, offset+i)
+		s.acc[i*2] += l * s.window[i]
+		s.acc[i*2+1] += r * s.window[i]
+	}
+	s.shift(s.ready)
+	s.readyPos = 0
+	s.prevPos = pos
+}
+
+// addGrain は位置 start から1粒取り出して重ね合わせる（探索なし）
+func (s *TimeStretcher) addGrain(source Source, start int64) {
+	s.refBuf = fetchFrames(source, s.refBuf, start, s.grain)
+	for i := 0; i < s.grain; i++ {
+		l, r := stereoAt(s.refBuf, i)
+		s.acc[i*2] += l * s.window[i]
+		s.acc[i*2+1] += r * s.window[i]
+	}
+}
+
+// shift は完成した先頭 hop フレームを dst に移し、残りを前に詰める
+func (s *TimeStretcher) shift(dst []float32) {
+	n := s.hop * 2
+	if dst != nil {
+		copy(dst, s.acc[:n])
+	}
+	copy(s.acc, s.acc[n:])
+	for i := len(s.acc) - n; i < len(s.acc); i++ {
+		s.acc[i] = 0
+	}
+}
+
+// search は target ± tolerance の中から、natural（前の粒の自然な続き）と最も波形が似ている位置を返す
+// 💡 まず粗い間隔で探してから、見つかった付近を細かく探す（計算量を抑えるため）
+func (s *TimeStretcher) search(source Source, target, natural int64) int64 {
+	// 比較はモノラル（L+R）で十分
+	s.refBuf = fetchFrames(source, s.refBuf, natural, s.hop)
+	s.monoRef = toMono(s.monoRef, s.refBuf, s.hop)
+	s.monoWin = toMono(s.monoWin, s.searchBuf, 2*s.tolerance+s.hop)
+
+	// 💡 候補側のエネルギーで正規化する（音が大きいだけの位置を選ばないように）
+	correlate := func(delta, step int) float32 {
+		var sum, energy float32
+		base := s.tolerance + delta
+		for i := 0; i < s.hop; i += step {
+			v := s.monoWin[base+i]
+			sum += s.monoRef[i] * v
+			energy += v * v
+		}
+		return sum / float32(math.Sqrt(float64(energy)+1e-9))
+	}
+
+	// 粗い探索の格子は 0 を含むようにする（等速付近では 0 がそのまま最適になる）
+	best, bestScore := 0, float32(math.Inf(-1))
+	first := -(s.tolerance / stretchCoarseStep) * stretchCoarseStep
+	for delta := first; delta <= s.tolerance; delta += stretchCoarseStep {
+		if score := correlate(delta, stretchCoarseStep); score > bestScore {
+			best, bestScore = delta, score
+		}
+	}
+
+	coarse := best
+	bestScore = float32(math.Inf(-1))
+	for delta := coarse - stretchCoarseStep + 1; delta < coarse+stretchCoarseStep; delta++ {
+		if delta < -s.tolerance || delta > s.tolerance {
+			continue
+		}
+		if score := correlate(delta, 1); score > bestScore {
+			best, bestScore = delta, score
+		}
+	}
+	return target + int64(best)
+}
+
+// fetchFrames は Source から frame ～ frame+frames を取得する
+// 範囲外（先頭より前・末尾より後・先読みが間に合わない部分）は無音で埋める
+func fetchFrames(source Source, buf []float32, frame int64, frames int) []float32 {
+	if cap(buf) < frames*2 {
+		buf = make([]float32, frames*2)
+	}
+	buf = buf[:frames*2]
+
+	skip := 0
+	if frame < 0 {
+		skip = int(-frame)
+		if skip > frames {
+			skip = frames
+		}
+	}
+	got := source.Fetch(buf[skip*2:], frame+int64(skip), frames-skip)
+	// 💡 メモリ上のデータはコピーせずに返ってくるので、ここで buf に揃える
+	copy(buf[skip*2:], got)
+	for i := 0; i < skip*2; i++ {
+		buf[i] = 0
+	}
+	for i := skip*2 + len(got); i < len(buf); i++ {
+		buf[i] = 0
+	}
+	return buf
+}
+
+// toMono はステレオの先頭 frames フレームを L+R のモノラルにする
+func toMono(dst, stereo []float32, frames int) []float32 {
+	if cap(dst) < frames {
+		dst = make([]float32, frames)
+	}
+	dst = dst[:frames]
+	for i := range dst {
+		dst[i] = stereo[i*2] + stereo[i*2+1]
+	}
+	return dst
+}
@@ -0,0 +1,86 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+// peakFreq はステレオ・インターリーブの ch チャンネルの中央付近を FFT して、一番強い周波数（Hz）を返す
+// 💡 ハン窓をかけ、ピークの前後のビンから放物線で補間する（ビンの間隔 1.35Hz より細かく求める）
+func peakFreq(stereo []float32, ch, sampleRate int) float64 {
+	const size = 32768
+	start := max(len(stereo)/2/2-size/2, 0)
+	re := make([]float64, size)
+	im := make([]float64, size)
+	for i := 0; i < size && start+i < len(stereo)/2; i++ {
+		window := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/size)
+		re[i] = float64(stereo[(start+i)*2+ch]) * window
+	}
+	fft(re, im)
+
+	magnitude := func(bin int) float64 { return math.Log(math.Hypot(re[bin], im[bin]) + 1e-12) }
+	peak := 1
+	for bin := 1; bin < size/2-1; bin++ {
+		if magnitude(bin) > magnitude(peak) {
+			peak = bin
+		}
+	}
+	a, b, c := magnitude(peak-1), magnitude(peak), magnitude(peak+1)
+	offset := 0.5 * (a - c) / (a - 2*b + c)
+	return (float64(peak) + offset) * float64(sampleRate) / size
+}
+
+// playToEnd はトラックを頭から最後まで再生し、止まるまでの出力を返す
+func playToEnd(t *testing.T, track *Track, block int) []float32 {
+	t.Helper()
+	var out []float32
+	buf := make([]float32, block*2)
+	track.Play()
+	for track.IsPlaying() {
+		track.ReadSamples(buf)
+		out = append(out, buf...)
+		if len(out) > 60*track.SampleRate*2 {
+			t.Fatal("track did not stop within 60 seconds")
+		}
+	}
+	return out
+}
+
+// TestKeyLockKeepsPitch はキーロック中に速度を変えても、音程はそのままで長さだけが 1/速度 になることを確認する
+// （キーロックなしなら音程も速度の分だけ変わる）
+func TestKeyLockKeepsPitch(t *testing.T) {
+	const (
+		sampleRate = 44100
+		block      = 512
+		duration   = 5.0 // tone_440hz.wav の長さ（秒）
+	)
+
+	for _, speed := range []float64{0.8, 1.25} {
+		for _, keyLock := range []bool{true, false} {
+			track := NewTrack(sampleRate)
+			if err := track.LoadWithMode(testdataDir+"/tone_440hz.wav", LoadMemory); err != nil {
+				t.Fatal(err)
+			}
+			track.SetSpeed(speed)
+			track.SetKeyLock(keyLock)
+			out := playToEnd(t, track, block)
+
+			// 最後のブロックの途中で止まるので、長さはブロック1つ分の誤差で比べる
+			want := duration / speed * sampleRate
+			if frames := float64(len(out) / 2); frames < want || frames > want+block {
+				t.Errorf("speed %v key lock %v: played %.0f frames, want %.0f (%.3f s)", speed, keyLock, frames, want, want/sampleRate)
+			}
+
+			wantFreq := 440.0
+			if !keyLock {
+				wantFreq *= speed
+			}
+			for ch := 0; ch < 2; ch++ {
+				if got := peakFreq(out, ch, sampleRate); math.Abs(got-wantFreq) > 1 {
+					t.Errorf("speed %v key lock %v: channel %d peak %.2f Hz, want %.2f Hz", speed, keyLock, ch, got, wantFreq)
+				}
+			}
+			track.Close()
+		}
+	}
+}
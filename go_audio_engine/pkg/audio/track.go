@@ -2,263 +2,661 @@ package audio
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"sync"
-
-	"github.com/go-audio/wav"
+	"sync/atomic"
+	"time"
 )
 
 // Track は拡張されたオーディオトラック
 // 全ての機能を統合
+//
+// 解説：オーディオスレッド（ReadSamples）はロックを一切取らない。
+//
+//	再生の設定（再生・停止、音量、速度など）：API のスレッドが atomic に書き、ブロックの頭で読む
+//	再生位置：オーディオスレッドだけが持つ。Seek は「次のブロックの頭でここへ移動」という要求を置くだけ
+//	曲のデータ（Source）：ロードで atomic に差し替え、オーディオスレッドは差し替わったら頭から再生する
+//	  古い Source は、オーディオスレッドが使い終わった（reading の印が外れた）ことを確かめてから閉じる
 type Track struct {
-	// 基本情報
-	FilePath      string
-	SampleRate    int
-	Channels      int
-	Data          []float32
-	Position      int     // 廃止予定だが、互換性のために残す
-	floatPosition float64 // 💡 追加: 正確な再生位置
-	IsPlaying     bool
-	Volume        float64
-
-	// DJ機能
-	Speed float64 // ピッチコントロール（0.5 ～ 2.0）
-
-	// エフェクト
+	// 基本情報（ロード時に決まる。mu で保護）
+	FilePath         string
+	SampleRate       int       // Data のサンプルレート（常にエンジンのレート。NewTrack で決まり、以後変わらない）
+	SourceSampleRate int       // 元ファイルのサンプルレート
+	Channels         int       // Data のチャンネル数（常に2 = ステレオ）
+	SourceChannels   int       // 元ファイルのチャンネル数
+	Data             []float32 // メモリ再生時の全データ（ストリーミング時は nil）
+	Position         int       // 廃止予定だが、互換性のために残す
+
+	source  atomic.Pointer[sourceRef] // PCMデータの供給元（メモリ or ストリーミング、ロード前は nil）
+	reading atomic.Pointer[sourceRef] // オーディオスレッドがこのブロックで使っている Source（ブロックの外では nil）
+
+	// 再生の設定（API のスレッドから変更される）
+	isPlaying     atomic.Bool
+	volume        atomic.Uint64 // float64 のビット列
+	speed         atomic.Uint64 // ピッチコントロール（0.5 ～ 2.0、float64 のビット列）
+	interpolation atomic.Int32  // 可変速再生の補間方式（Interpolation）
+	keyLock       atomic.Bool   // キーロック（テンポを変えてもピッチを変えない）
+	seekTo        atomic.Uint64 // 次のブロックの頭で移動する位置（秒、float64 のビット列。noSeek なら移動しない）
+
+	// オーディオスレッド専用
+	floatPosition float64        // 正確な再生位置（フレーム単位、小数部は補間に使う）
+	current       *sourceRef     // 直前のブロックで再生したデータ（差し替えの検出用）
+	stretching    bool           // 直前のブロックでキーロックを使ったか
+	volumeRamp    *Smoother      // 音量をなめらかに変える
+	fetchBuf      []float32      // ReadSamples 用の作業バッファ（maxFetchFrames 分を NewTrack で確保）
+	stretcher     *TimeStretcher // キーロック用のタイムストレッチ
+
+	// エフェクト（曲を替えても TakeOverProcessing で次のトラックに引き継ぐ）
+	PitchShift *PitchShifter    // ピッチシフト（テンポとは独立、±12半音）
 	EQ         *ThreeBandEQ     // イコライザー
 	Filter     *Filter          // フィルター
+	Effects    *EffectRack      // インサートエフェクト（エコー、リバーブなど）
 	BPM        *BPMDetector     // BPM検出器
 	CueManager *CuePointManager // キューポイント管理
 
+	// 💡 以下はミキサーの同期エンジンがオーディオスレッドからロックなしで読み書きする
+	beatGrid      atomic.Pointer[BeatGrid] // ビートグリッド（解析前は nil）
+	syncSpeed     atomic.Uint64            // 同期エンジンが決めた速度（float64 のビット列、0 なら Speed を使う）
+	playhead      atomic.Uint64            // 直前のブロック終了時の再生位置（秒、float64 のビット列）
+	playheadSpeed atomic.Uint64            // 直前のブロックの実際の再生速度（float64 のビット列）
+	playing       atomic.Bool              // 直前のブロックで再生中だったか
+
 	// 同期制御（並行処理の安全性）
+	// 💡 基本情報（FilePath など）だけを守る。オーディオスレッドは使わない
 	mu sync.RWMutex // RWMutex: 読み書きロック
 }
 
+// sourceRef は Source を atomic.Pointer で差し替えるための入れ物
+type sourceRef struct {
+	Source
+}
+
+// noSeek は seekTo に移動の要求がないことを表す値（float64 としては NaN）
+const noSeek = math.MaxUint64
+
 // NewTrack は新しいトラックを作成
 func NewTrack(sampleRate int) *Track {
-	return &Track{
-		Volume:     1.0,
-		Speed:      1.0,
+	t := &Track{
+		volumeRamp: NewSmoother(float64(sampleRate), SmoothSeconds, 1.0),
+		stretcher:  NewTimeStretcher(sampleRate),
+		fetchBuf:   make([]float32, maxFetchFrames*2),
 		SampleRate: sampleRate,
+		PitchShift: NewPitchShifter(float64(sampleRate)),
 		EQ:         NewThreeBandEQ(float64(sampleRate)),
 		Filter:     NewFilter(float64(sampleRate)),
+		Effects:    NewEffectRack(float64(sampleRate)),
 		BPM:        NewBPMDetector(sampleRate),
 		CueManager: NewCuePointManager(),
 	}
+	t.SetVolume(1.0)
+	t.SetSpeed(1.0)
+	t.SetInterpolation(DefaultInterpolation)
+	t.seekTo.Store(noSeek)
+	return t
 }
 
-// LoadWAV はWAVファイルをロード
-func (t *Track) LoadWAV(filePath string) error {
+// LoadMode はトラックの読み込み方法
+type LoadMode int
+
+const (
+	LoadAuto      LoadMode = iota // 長い曲はストリーミング、短い曲はメモリ
+	LoadMemory                    // 全体をデコードしてメモリに置く（短いサンプル向け）
+	LoadStreaming                 // ディスクから先読みしながら再生（長いミックス向け）
+)
+
+// StreamingThreshold は LoadAuto でストリーミング再生にする曲の長さ（秒）
+var StreamingThreshold = 10 * 60.0
+
+// 再生速度の範囲（ピッチフェーダーと同期エンジンで共通）
+const (
+	minSpeed = 0.5
+	maxSpeed = 2.0
+)
+
+// MaxBlockFrames は ReadSamples（ミキサーの1ブロック）のフレーム数の上限
+// 💡 作業バッファはこの長さで先に確保しておくので、これ以下ならオーディオスレッドでメモリを確保しない
+const MaxBlockFrames = 8192
+
+// maxFetchFrames は fetchWindow が1ブロックで取る最大のフレーム数
+// MaxBlockFrames フレームを最高速度で、一番広い補間（sinc）の前後の分まで読む場合
+// 💡 同期の速度も、位相合わせの上乗せを含めて maxSpeed で止まる（SetSyncSpeed）
+var maxFetchFrames = func() int {
+	before, after := interpolationReach(InterpolationSinc, maxSpeed)
+	return before + int(math.Ceil(MaxBlockFrames*maxSpeed)) + after + 2
+}()
+
+// bpmAnalysisSeconds はストリーミング時にBPM解析に使う先頭部分の長さ（秒）
+const bpmAnalysisSeconds = 120
+
+// Load はオーディオファイルをロード
+// フォーマットはマジックバイトと拡張子から自動判定（WAV / FLAC / MP3 / OGG）
+func (t *Track) Load(filePath string) error {
+	return t.LoadWithMode(filePath, LoadAuto)
+}
+
+// LoadWithMode は読み込み方法を指定してロード
+func (t *Track) LoadWithMode(filePath string, mode LoadMode) error {
 	// 1. ファイルをオープン（ロックの外）
 	file, err := os.Open(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %v", err)
 	}
-	defer file.Close()
 
-	decoder := wav.NewDecoder(file)
-	if !decoder.IsValidFile() {
-		return fmt.Errorf("invalid WAV file")
+	stream, decoder, err := OpenStream(filePath, file)
+	if err != nil {
+		file.Close()
+		return err
 	}
 
 	// 基本情報の取得
-	sampleRate := int(decoder.SampleRate)
-	channels := int(decoder.NumChans)
+	sampleRate := stream.SampleRate()
+	channels := stream.Channels()
 
-	fmt.Printf("⏳ Loading WAV: %s (SR:%d, Ch:%d)\n", filePath, sampleRate, channels)
+	// 💡 Data は常にエンジンのレートなので、秒⇔サンプルの変換は t.SampleRate だけで済む
+	engineRate := t.SampleRate
+	if engineRate <= 0 {
+		engineRate = sampleRate
+	}
 
-	// 2. デコード実行（重い処理・ロックの外）
-	buf, err := decoder.FullPCMBuffer()
-	if err != nil {
-		return fmt.Errorf("failed to decode audio: %v", err)
+	// 長さが分かっていて長い曲ならストリーミング
+	if mode == LoadAuto {
+		mode = LoadMemory
+		if n := stream.Length(); n > 0 && float64(n)/float64(sampleRate) > StreamingThreshold {
+			mode = LoadStreaming
+		}
 	}
 
-	// 3. float32への変換と正規化（重い処理・ロックの外）
-	// 💡 正規化 (/ 32768.0) を忘れると、爆音でノイズが発生したり計算負荷が上がります
-	convertedData := make([]float32, len(buf.Data))
-	for i, sample := range buf.Data {
-		convertedData[i] = float32(sample) / 32768.0
+	var source Source
+	var data []float32
+	if mode == LoadStreaming {
+		fmt.Printf("⏳ Streaming %s: %s (SR:%d, Ch:%d)\n", decoder.Name(), filePath, sampleRate, channels)
+
+		// 💡 ファイルは streamSource が閉じる
+		streaming, err := newStreamSource(file, stream, engineRate)
+		if err != nil {
+			file.Close()
+			return fmt.Errorf("failed to start streaming: %v", err)
+		}
+		source = streaming
+	} else {
+		fmt.Printf("⏳ Loading %s: %s (SR:%d, Ch:%d)\n", decoder.Name(), filePath, sampleRate, channels)
+
+		// 2. デコード実行（重い処理・ロックの外）
+		data, err = decodeToEngineFormat(stream, engineRate, -1)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("failed to decode audio: %v", err)
+		}
+		source = &memorySource{data: data}
 	}
-	fmt.Printf("⏳ Loading WAV: Conversion completed, samples: %d\n", len(convertedData))
 
-	// 4. データの差し替え（最小限のロック）
+	// 3. データの差し替え（最小限のロック）
 	t.mu.Lock()
 	// 💡 deferを使わず、必要な代入が終わったらすぐUnlockするのが最も安全です
-	t.Data = convertedData
-	t.SampleRate = sampleRate
-	t.Channels = channels
+	t.Data = data
+	t.SourceSampleRate = sampleRate
+	t.Channels = 2
+	t.SourceChannels = channels
 	t.FilePath = filePath
 	t.Position = 0
-	t.floatPosition = 0.0 // 💡 追加
-	t.IsPlaying = false
 	t.mu.Unlock()
+	t.beatGrid.Store(nil)
+
+	// 💡 再生位置とタイムストレッチは、オーディオスレッドが差し替えに気づいたときに頭に戻す
+	t.isPlaying.Store(false)
+	t.seekTo.Store(noSeek)
+	t.playhead.Store(0)
+	if err := t.replaceSource(source); err != nil {
+		fmt.Printf("❌ Failed to close previous source: %v\n", err)
+	}
 
-	fmt.Printf("✅ Loaded: %s (%.2f seconds)\n", filePath, float64(len(convertedData))/float64(channels)/float64(sampleRate))
+	fmt.Printf("✅ Loaded: %s (%.2f seconds)\n", filePath, float64(source.Frames())/float64(engineRate))
 
 	return nil
 }
 
+// replaceSource は Source を差し替え（nil なら外すだけ）、古い Source をオーディオスレッドが使い終わってから閉じる
+// 💡 オーディオスレッドが古い Source でブロックを処理している最中なら、そのブロックが終わるまで（数ms）待つ。
+// オーディオスレッドからは呼ばないこと
+func (t *Track) replaceSource(source Source) error {
+	var ref *sourceRef
+	if source != nil {
+		ref = &sourceRef{source}
+	}
+	old := t.source.Swap(ref)
+	if old == nil {
+		return nil
+	}
+	for t.reading.Load() == old {
+		time.Sleep(time.Millisecond)
+	}
+	return old.Close()
+}
+
+// acquireSource はこのブロックで使う Source を読み、使用中の印（reading）をつける（オーディオスレッド専用）
+// 解説：印をつける前に差し替えられると、差し替えた側は印を見ずに古い Source を閉じてしまう。
+// そこで印をつけた後にもう一度読み、差し替えと入れ違っていたらやり直す（ハザードポインタ）。
+// 差し替えた側は Swap の後に印を見るので、どちらかが必ず相手に気づく
+func (t *Track) acquireSource() *sourceRef {
+	for {
+		ref := t.source.Load()
+		t.reading.Store(ref)
+		if t.source.Load() == ref {
+			return ref
+		}
+	}
+}
+
+// decodeToEngineFormat はストリームをデコードし、ステレオ・エンジンのレートに揃える
+// maxFrames が正ならその長さ（元ファイルのフレーム数）で打ち切る
+func decodeToEngineFormat(stream PCMStream, engineRate int, maxFrames int64) ([]float32, error) {
+	data, err := ReadFrames(stream, maxFrames)
+	if err != nil {
+		return nil, err
+	}
+
+	// ステレオに揃える（Filter/EQ/ReadSamples はすべて2chインターリーブ前提）
+	// 💡 先にチャンネル数を減らしておくと、次のリサンプルも軽くなる
+	if stream.Channels() != 2 {
+		data = ToStereo(data, StreamChannelLayout(stream))
+	}
+
+	// エンジンのサンプルレートに変換（48kHzの曲が速く・高く再生されないように）
+	if stream.SampleRate() != engineRate {
+		data = Resample(data, 2, stream.SampleRate(), engineRate)
+	}
+
+	return data, nil
+}
+
+// TakeOverProcessing は前のトラックのピッチシフト・フィルター・EQ・エフェクトラックをそのまま引き継ぐ
+// 解説：曲をロードするたびに Track は作り直されるが、これらのつまみはデッキのもの（曲を替えても動かない）。
+// 設定（EQ の特性やエフェクトの順番なども）だけでなく、リバーブの残響などの状態もそのまま続く
+// 💡 ミキサーがオーディオスレッドで、新しいトラックをデッキに載せる直前に呼ぶ（ロックもメモリ確保もしない）
+func (t *Track) TakeOverProcessing(prev *Track) {
+	t.PitchShift = prev.PitchShift
+	t.Filter = prev.Filter
+	t.EQ = prev.EQ
+	t.Effects = prev.Effects
+}
+
+// LoadWAV はWAVファイルをロード
+// 互換性のために残している。新しいコードは Load を使う
+func (t *Track) LoadWAV(filePath string) error {
+	return t.Load(filePath)
+}
+
 // DetectBPMAsync はBPMを非同期で検出
 // goroutineの例：並行処理
 func (t *Track) DetectBPMAsync() {
 	// 読み取りロック（他の読み取りと並行可能）
 	t.mu.RLock()
 	data := t.Data
+	filePath := t.FilePath
+	engineRate := t.SampleRate
 	t.mu.RUnlock()
 
+	// ストリーミング中は全体がメモリにないので、先頭部分だけ別にデコードして解析する
+	if data == nil && filePath != "" {
+		var err error
+		data, err = decodeHead(filePath, engineRate, bpmAnalysisSeconds)
+		if err != nil {
+			fmt.Printf("❌ BPM analysis failed: %v\n", err)
+			return
+		}
+	}
+
 	// BPM検出（時間がかかる処理）
 	bpm := t.BPM.DetectBPM(data)
+	grid := t.BPM.GetBeatGrid()
+	if bpm == 0 || grid == nil {
+		fmt.Printf("❌ BPM detection failed: %s\n", filePath)
+		return
+	}
+
+	// 解析中に別の曲がロードされていなければ、グリッドをトラックに保存
+	t.mu.RLock()
+	if t.FilePath == filePath {
+		t.beatGrid.Store(grid)
+	}
+	t.mu.RUnlock()
+
+	fmt.Printf("🎵 BPM detected: %.2f (confidence: %.2f, first beat: %.3fs, first downbeat: %.3fs)\n",
+		bpm, t.BPM.GetConfidence(), grid.FirstBeat, grid.FirstDownbeat)
+}
+
+// GetBeatGrid はビートグリッドを返す（解析前は nil）
+// 💡 ロックを取らないので、オーディオスレッドからも呼べる
+func (t *Track) GetBeatGrid() *BeatGrid {
+	return t.beatGrid.Load()
+}
+
+// decodeHead はファイルの先頭 seconds 秒をエンジンの形式でデコードする
+func decodeHead(filePath string, engineRate int, seconds float64) ([]float32, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
 
-	fmt.Printf("🎵 BPM detected: %.1f (confidence: %.2f)\n",
-		bpm, t.BPM.GetConfidence())
+	stream, _, err := OpenStream(filePath, file)
+	if err != nil {
+		return nil, err
+	}
+	return decodeToEngineFormat(stream, engineRate, int64(seconds*float64(stream.SampleRate())))
+}
+
+// fetchWindow は位置 frame から frames フレーム分の出力に必要なデータを取得する
+// 補間で前後のフレームも参照するので、その分を含めて取る
+// 戻り値は取得したデータ（ステレオ・インターリーブ）と、その先頭のフレーム番号
+func (t *Track) fetchWindow(source Source, frame, frames int, speed float64, mode Interpolation) ([]float32, int) {
+	before, after := interpolationReach(mode, speed)
+	start := frame - before
+	if start < 0 {
+		start = 0 // 先頭より前は無音として扱う
+	}
+	count := frame + int(math.Ceil(float64(frames)*speed)) + after + 2 - start
+	if cap(t.fetchBuf) < count*2 {
+		t.fetchBuf = make([]float32, count*2)
+	}
+	return source.Fetch(t.fetchBuf[:count*2], int64(start), count), start
 }
 
 // ReadSamples はサンプルを読み取り、エフェクトを適用
+// 💡 再生位置はフレーム単位で進めるので、速度を変えても左右が入れ替わらない
+//
+//	オーディオスレッドから呼ぶ。ロックもメモリ確保もしない（作業バッファは最初のブロックで確保したものを使い回す）
 func (t *Track) ReadSamples(out []float32) {
-	// 💡 最終修正: ループの各イテレーションでロックを取得し、データ競合を完全に防ぐ
-	for i := range out {
-		t.mu.Lock() // ループ内でロック
-
-		if !t.IsPlaying || len(t.Data) == 0 {
-			out[i] = 0
-			t.mu.Unlock() // アンロックして次のイテレーションへ
-			continue
-		}
+	frames := len(out) / 2
+
+	// 曲が差し替えられたら頭から
+	// 💡 ブロックを処理し終わるまで、この Source は閉じられない
+	ref := t.acquireSource()
+	defer t.reading.Store(nil)
+	if ref != t.current {
+		t.current = ref
+		t.floatPosition = 0
+		t.stretcher.Reset()
+	}
+
+	// このブロックの設定をまとめて読む（ブロックの途中では変わらない）
+	speed := t.GetSpeed()
+	if syncSpeed := t.SyncSpeed(); syncSpeed > 0 {
+		speed = syncSpeed // 同期中は同期エンジンの速度が優先
+	}
+	mode := t.GetInterpolation()
+	keyLock := t.keyLock.Load()
+	t.volumeRamp.Set(t.GetVolume())
+	if keyLock != t.stretching {
+		t.stretching = keyLock
+		t.stretcher.Reset()
+	}
+
+	var source Source
+	totalFrames := 0.0
+	if ref != nil {
+		source = ref.Source
+		totalFrames = float64(source.Frames())
+	}
+
+	// Seek の要求があれば移動する
+	if bits := t.seekTo.Swap(noSeek); bits != noSeek {
+		t.moveTo(math.Float64frombits(bits), totalFrames)
+	}
 
-		currentSampleIndex := int(t.floatPosition)
-		if currentSampleIndex >= len(t.Data) {
-			// トラック終了
-			out[i] = 0
-			t.IsPlaying = false
-			t.floatPosition = 0.0
-		} else {
-			// 正常な再生
-			out[i] = t.Data[currentSampleIndex] * float32(t.Volume)
-			t.floatPosition += t.Speed
+	playing := t.isPlaying.Load() && source != nil
+	if !playing {
+		clear(out)
+		t.volumeRamp.Jump(t.volumeRamp.Target()) // 音が出ていないので、なめらかにする必要はない
+	} else {
+		// 今回のブロックで必要になる範囲をまとめて取得しておく
+		// 💡 ストリーミングでもここはブロックしない（間に合わなければ無音）
+		// 💡 キーロック中はタイムストレッチが自分で粒を取り出すので、ここでは取得しない
+		var window []float32
+		windowBase := 0
+		if !keyLock {
+			window, windowBase = t.fetchWindow(source, int(t.floatPosition), frames, speed, mode)
 		}
 
-		t.mu.Unlock() // ループ内でアンロック
+		for i := 0; i < frames; i++ {
+			if t.floatPosition >= totalFrames {
+				// トラック終了（残りは無音）
+				clear(out[i*2:])
+				t.floatPosition = 0
+				t.isPlaying.Store(false)
+				playing = false
+				break
+			}
+
+			var l, r float32
+			if keyLock {
+				// キーロック：位置は Speed で進むが、ピッチは元のまま
+				l, r = t.stretcher.Next(source, t.floatPosition)
+			} else {
+				frame := int(t.floatPosition)
+				frac := t.floatPosition - float64(frame)
+
+				// 正常な再生（先読みが間に合わなかった部分は無音）
+				l, r = interpolateFrame(mode, window, frame-windowBase, frac, speed)
+			}
+			volume := float32(t.volumeRamp.Next())
+			out[i*2], out[i*2+1] = l*volume, r*volume
+			t.floatPosition += speed
+		}
 	}
 
 	// エフェクト適用（順番が重要）
-	t.Filter.Process(out) // 1. フィルター
-	t.EQ.Process(out)     // 2. EQ
-
-	// --- ループチェック ---
-	// この処理はロックの外で行う
-	t.mu.RLock()
-	// ループチェックに必要な値をコピー
-	pos := t.floatPosition
-	channels := t.Channels
-	sampleRate := t.SampleRate
-	t.mu.RUnlock()
+	t.PitchShift.Process(out) // 1. ピッチシフト
+	t.Filter.Process(out)     // 2. フィルター
+	t.EQ.Process(out)         // 3. EQ
+
+	// 4. インサートエフェクト
+	// 💡 テンポ同期の1拍は、ビートグリッドのテンポを実際の再生速度で割った長さ（テンポ不明なら 0）
+	beatSeconds := 0.0
+	if interval := t.GetBeatGrid().Interval(); interval > 0 && speed > 0 {
+		beatSeconds = interval / speed
+	}
+	t.Effects.Process(out, beatSeconds)
 
 	// ゼロ除算を防止
-	if channels == 0 || sampleRate == 0 {
+	if t.SampleRate == 0 {
 		return
 	}
 
+	// --- ループチェック ---
 	// 現在位置を秒に変換
-	currentPosInSeconds := pos / float64(channels) / float64(sampleRate)
+	currentPosInSeconds := t.floatPosition / float64(t.SampleRate)
 
 	// ループをチェック
 	shouldLoop, newPos := t.CueManager.CheckLoop(currentPosInSeconds)
 	if shouldLoop {
-		t.Seek(newPos)
+		t.moveTo(newPos, totalFrames)
+		currentPosInSeconds = t.floatPosition / float64(t.SampleRate)
 	}
+
+	// 同期エンジンと GetPosition 用に再生状態を公開
+	t.playhead.Store(math.Float64bits(currentPosInSeconds))
+	t.playheadSpeed.Store(math.Float64bits(speed))
+	t.playing.Store(playing)
 }
 
-// Seek は指定位置にジャンプ
-func (t *Track) Seek(seconds float64) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
+// moveTo は再生位置を seconds 秒に移動する（オーディオスレッド専用）
+func (t *Track) moveTo(seconds, totalFrames float64) {
+	position := seconds * float64(t.SampleRate)
+	if position >= totalFrames {
+		position = totalFrames - 1
+	}
+	if position < 0 {
+		position = 0
+	}
+	t.floatPosition = position
+}
+
+// Playhead は直前のブロック終了時の再生位置（秒）・実際の再生速度・再生中かを返す
+// 💡 ロックを取らないので、オーディオスレッドからも呼べる（ReadSamples と同じスレッドで使う想定）
+func (t *Track) Playhead() (position, speed float64, playing bool) {
+	return math.Float64frombits(t.playhead.Load()),
+		math.Float64frombits(t.playheadSpeed.Load()),
+		t.playing.Load()
+}
 
-	// 💡 修正: floatPosition を更新
-	newPosition := seconds * float64(t.SampleRate) * float64(t.Channels)
-	if newPosition < 0 {
-		newPosition = 0
+// SetSyncSpeed は同期エンジンが決めた速度を設定（0 で解除して Speed に戻す）
+// 💡 ユーザーの Speed は書き換えないので、同期を解除すると元のピッチフェーダーの値に戻る
+func (t *Track) SetSyncSpeed(speed float64) {
+	if speed > 0 {
+		speed = clamp(speed, minSpeed, maxSpeed)
 	}
-	if int(newPosition) >= len(t.Data) {
-		newPosition = float64(len(t.Data) - 1)
+	t.syncSpeed.Store(math.Float64bits(speed))
+}
+
+// SyncSpeed は同期エンジンが決めた速度を返す（同期していなければ 0）
+func (t *Track) SyncSpeed() float64 {
+	return math.Float64frombits(t.syncSpeed.Load())
+}
+
+// Seek は指定位置にジャンプ
+// 💡 実際に移動するのは次のブロックの頭（オーディオスレッドが移動する）
+func (t *Track) Seek(seconds float64) {
+	if seconds < 0 {
+		seconds = 0
 	}
-	t.floatPosition = newPosition
+	t.seekTo.Store(math.Float64bits(seconds))
 }
 
 // GetPosition は現在位置（秒）を返す
+// 💡 移動の要求がまだ処理されていなければ、移動先を返す（Seek の直後に読んでも食い違わない）
 func (t *Track) GetPosition() float64 {
-	// 💡 修正: ロック時間を最小化するため、必要な値を即座にコピーする
-	t.mu.RLock()
-	position := t.floatPosition // 💡 修正: floatPosition を使用
-	dataLen := len(t.Data)
-	t.mu.RUnlock()
-
-	if dataLen == 0 {
+	if t.totalFrames() == 0 {
 		return 0
 	}
-	return position / float64(t.Channels) / float64(t.SampleRate)
+	if bits := t.seekTo.Load(); bits != noSeek {
+		return min(math.Float64frombits(bits), t.GetDuration())
+	}
+	return math.Float64frombits(t.playhead.Load())
 }
 
 // GetDuration はトラックの長さ（秒）を返す
 func (t *Track) GetDuration() float64 {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
-
 	// 💡 修正: ゼロ除算を確実に防ぐ
-	if len(t.Data) == 0 || t.Channels == 0 || t.SampleRate == 0 {
+	if t.totalFrames() == 0 || t.SampleRate == 0 {
 		return 0.0
 	}
-	return float64(len(t.Data)) / float64(t.Channels) / float64(t.SampleRate)
+	return float64(t.totalFrames()) / float64(t.SampleRate)
 }
 
-// SetVolume は音量を設定
-func (t *Track) SetVolume(volume float64) {
+// totalFrames は総フレーム数
+func (t *Track) totalFrames() int {
+	ref := t.source.Load()
+	if ref == nil {
+		return 0
+	}
+	return int(ref.Frames())
+}
+
+// IsStreaming はストリーミング再生中か
+func (t *Track) IsStreaming() bool {
+	ref := t.source.Load()
+	return ref != nil && ref.Streaming()
+}
+
+// StreamStats はストリーミングのバッファ状況（先読み済みの秒数、アンダーラン回数）
+func (t *Track) StreamStats() (bufferedSeconds float64, underruns int64) {
+	ref := t.source.Load()
+	if ref == nil {
+		return 0, 0
+	}
+	if s, ok := ref.Source.(*streamSource); ok {
+		return s.BufferedSeconds(), s.Underruns()
+	}
+	return 0, 0
+}
+
+// Close はファイルや先読みゴルーチンなどのリソースを解放
+// 💡 オーディオスレッドが読んでいる最中の Source は、そのブロックが終わるのを待ってから閉じる
+func (t *Track) Close() error {
+	t.isPlaying.Store(false)
+
 	t.mu.Lock()
-	defer t.mu.Unlock()
+	t.Data = nil
+	t.mu.Unlock()
 
+	return t.replaceSource(nil)
+}
+
+// SetVolume は音量を設定
+func (t *Track) SetVolume(volume float64) {
 	if volume < 0 {
 		volume = 0
 	}
 	if volume > 1.0 {
 		volume = 1.0
 	}
-	t.Volume = volume
+	t.volume.Store(math.Float64bits(volume))
+}
+
+// GetVolume は音量を返す
+func (t *Track) GetVolume() float64 {
+	return math.Float64frombits(t.volume.Load())
 }
 
 // SetSpeed はピッチ/スピードを設定
 func (t *Track) SetSpeed(speed float64) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-
 	// 0.5倍速 ～ 2.0倍速
-	if speed < 0.5 {
-		speed = 0.5
+	if speed < minSpeed {
+		speed = minSpeed
 	}
-	if speed > 2.0 {
-		speed = 2.0
+	if speed > maxSpeed {
+		speed = maxSpeed
 	}
-	t.Speed = speed
+	t.speed.Store(math.Float64bits(speed))
+}
+
+// GetSpeed はピッチコントロールの速度を返す（同期中の速度は SyncSpeed）
+func (t *Track) GetSpeed() float64 {
+	return math.Float64frombits(t.speed.Load())
+}
+
+// SetInterpolation は可変速再生の補間方式を設定
+func (t *Track) SetInterpolation(mode Interpolation) {
+	t.interpolation.Store(int32(mode))
+}
+
+// GetInterpolation は可変速再生の補間方式を返す
+func (t *Track) GetInterpolation() Interpolation {
+	return Interpolation(t.interpolation.Load())
+}
+
+// SetKeyLock はキーロック（マスターテンポ）を切り替える
+// 💡 タイムストレッチのリセットは、切り替わりに気づいたオーディオスレッドが行う
+func (t *Track) SetKeyLock(enabled bool) {
+	t.keyLock.Store(enabled)
+}
+
+// GetKeyLock はキーロックが有効か
+func (t *Track) GetKeyLock() bool {
+	return t.keyLock.Load()
+}
+
+// IsPlaying は再生中か
+func (t *Track) IsPlaying() bool {
+	return t.isPlaying.Load()
 }
 
 // Play は再生開始
 func (t *Track) Play() {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	t.IsPlaying = true
+	t.isPlaying.Store(true)
 }
 
 // Pause は一時停止
 func (t *Track) Pause() {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	t.IsPlaying = false
+	t.isPlaying.Store(false)
 }
 
 // Stop は停止して先頭に戻る
 func (t *Track) Stop() {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	t.IsPlaying = false
-	t.floatPosition = 0 // 💡 修正
+	t.isPlaying.Store(false)
+	t.Seek(0)
 }
 
 // AddCuePoint はキューポイントを追加
@@ -0,0 +1,91 @@
+package audio
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// closeCheckSource は閉じた後に読まれたら記録する Source
+type closeCheckSource struct {
+	memorySource
+	closed         atomic.Bool
+	readAfterClose *atomic.Int64
+}
+
+func (s *closeCheckSource) Fetch(dst []float32, frame int64, frames int) []float32 {
+	if s.closed.Load() {
+		s.readAfterClose.Add(1)
+	}
+	// 💡 読んでいる途中で差し替えられやすいように、少しだけ時間をかける
+	time.Sleep(20 * time.Microsecond)
+	if s.closed.Load() {
+		s.readAfterClose.Add(1)
+	}
+	return s.memorySource.Fetch(dst, frame, frames)
+}
+
+func (s *closeCheckSource) Close() error {
+	s.closed.Store(true)
+	return nil
+}
+
+// TestReplaceSourceWhilePlaying は再生中に曲を差し替えても、オーディオスレッドが読んでいる Source を
+// 閉じないことを確認する（go test -race で実行すること）
+func TestReplaceSourceWhilePlaying(t *testing.T) {
+	const sampleRate = 44100
+	track := NewTrack(sampleRate)
+	data := make([]float32, sampleRate*2)
+	var readAfterClose atomic.Int64
+	newSource := func() *closeCheckSource {
+		return &closeCheckSource{memorySource: memorySource{data: data}, readAfterClose: &readAfterClose}
+	}
+
+	if err := track.replaceSource(newSource()); err != nil {
+		t.Fatal(err)
+	}
+
+	// オーディオスレッド
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		out := make([]float32, 256*2)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			track.Play()
+			track.ReadSamples(out)
+		}
+	}()
+
+	// 0.2秒の間、差し替え続ける
+	sources := []*closeCheckSource{}
+	for start := time.Now(); time.Since(start) < 200*time.Millisecond; {
+		s := newSource()
+		sources = append(sources, s)
+		if err := track.replaceSource(s); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(50 * time.Microsecond)
+	}
+	if err := track.Close(); err != nil {
+		t.Fatal(err)
+	}
+	close(stop)
+	wg.Wait()
+
+	if n := readAfterClose.Load(); n > 0 {
+		t.Errorf("closed sources were read %d times", n)
+	}
+	for i, s := range sources {
+		if !s.closed.Load() {
+			t.Errorf("source %d was not closed", i)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package mixer
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync/atomic"
+)
+
+// CrossfaderAssign はチャンネルをクロスフェーダーのどちら側に割り当てるか
+type CrossfaderAssign int
+
+const (
+	AssignThru CrossfaderAssign = iota // クロスフェーダーを通さない（常にフェーダーの音量のまま）
+	AssignA                            // 左側（クロスフェーダーを A に倒すと聞こえる）
+	AssignB                            // 右側（クロスフェーダーを B に倒すと聞こえる）
+)
+
+// String はAPIやステータスで使う名前を返す
+func (a CrossfaderAssign) String() string {
+	switch a {
+	case AssignA:
+		return "A"
+	case AssignB:
+		return "B"
+	default:
+		return "THRU"
+	}
+}
+
+// ParseCrossfaderAssign は "A", "B", "THRU" から割り当てを返す（大文字・小文字は区別しない）
+func ParseCrossfaderAssign(s string) (CrossfaderAssign, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "A":
+		return AssignA, nil
+	case "B":
+		return AssignB, nil
+	case "THRU":
+		return AssignThru, nil
+	}
+	return 0, fmt.Errorf("invalid crossfader assign: %q (A, B or THRU)", s)
+}
+
+// トリムの範囲（dB）
+const (
+	TrimMinDB = -24.0
+	TrimMaxDB = 12.0
+)
+
+// ChannelStrip はミキサーの1チャンネル分の設定
+// 解説：クラブミキサーと同じく、信号は トリム → チャンネルフェーダー → クロスフェーダー の順に通る
+//
+//	トリム：曲ごとの音量差をそろえる入力ゲイン（dB）
+//	チャンネルフェーダー：曲を出し入れするための音量（0.0 ～ 1.0）
+//	クロスフェーダー割り当て：A / B / THRU（THRU ならクロスフェーダーの影響を受けない）
+//	PFL（キュー）：フェーダーの前の音をヘッドフォンに送る（フロアに出す前に曲を確認できる）
+//
+// 💡 オーディオスレッドがロックなしで読むので、値はすべて atomic で持つ
+type ChannelStrip struct {
+	trimDB atomic.Uint64 // float64 のビット列
+	fader  atomic.Uint64 // float64 のビット列
+	assign atomic.Int32  // CrossfaderAssign
+	pfl    atomic.Bool
+}
+
+// NewChannelStrip はチャンネルストリップを作成（トリム 0dB、フェーダー全開）
+func NewChannelStrip(assign CrossfaderAssign) *ChannelStrip {
+	s := &ChannelStrip{}
+	s.SetTrim(0)
+	s.SetFader(1.0)
+	s.SetAssign(assign)
+	return s
+}
+
+// SetTrim はトリムを dB で設定（-24 ～ +12dB）
+func (s *ChannelStrip) SetTrim(db float64) {
+	if db < TrimMinDB {
+		db = TrimMinDB
+	}
+	if db > TrimMaxDB {
+		db = TrimMaxDB
+	}
+	s.trimDB.Store(math.Float64bits(db))
+}
+
+// GetTrim はトリム（dB）を返す
+func (s *ChannelStrip) GetTrim() float64 {
+	return math.Float64frombits(s.trimDB.Load())
+}
+
+// SetFader はチャンネルフェーダーを設定（0.0 ～ 1.0）
+func (s *ChannelStrip) SetFader(value float64) {
+	if value < 0 {
+		value = 0
+	}
+	if value > 1.0 {
+		value = 1.0
+	}
+	s.fader.Store(math.Float64bits(value))
+}
+
+// GetFader はチャンネルフェーダーの値を返す
+func (s *ChannelStrip) GetFader() float64 {
+	return math.Float64frombits(s.fader.Load())
+}
+
+// SetAssign はクロスフェーダーの割り当てを設定
+func (s *ChannelStrip) SetAssign(assign CrossfaderAssign) {
+	s.assign.Store(int32(assign))
+}
+
+// GetAssign はクロスフェーダーの割り当てを返す
+func (s *ChannelStrip) GetAssign() CrossfaderAssign {
+	return CrossfaderAssign(s.assign.Load())
+}
+
+// SetPFL はヘッドフォンへのキュー（PFL）を切り替える
+func (s *ChannelStrip) SetPFL(enabled bool) {
+	s.pfl.Store(enabled)
+}
+
+// GetPFL はキュー（PFL）が有効か
+func (s *ChannelStrip) GetPFL() bool {
+	return s.pfl.Load()
+}
+
+// TrimGain はトリムのゲイン（倍率）
+func (s *ChannelStrip) TrimGain() float64 {
+	return math.Pow(10, s.GetTrim()/20)
+}
+
+// Gain はクロスフェーダーを除いたチャンネルのゲイン（トリム × フェーダー）
+func (s *ChannelStrip) Gain() float64 {
+	return s.TrimGain() * s.GetFader()
+}
+
+// CrossfaderGain は割り当てに応じたクロスフェーダー側のゲインを選ぶ
+func (s *ChannelStrip) CrossfaderGain(gainA, gainB float64) float64 {
+	switch s.GetAssign() {
+	case AssignA:
+		return gainA
+	case AssignB:
+		return gainB
+	default:
+		return 1.0
+	}
+}
@@ -0,0 +1,102 @@
+package mixer
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// CrossfaderCurve はクロスフェーダーのカーブ（フェーダー位置 → 左右のゲイン）
+type CrossfaderCurve int
+
+const (
+	CurvePower   CrossfaderCurve = iota // 等パワー（中央で両方 0.707、聴感上の音量が一定）
+	CurveLinear                         // 直線（中央で両方 0.5、少し音量が下がる）
+	CurveDipless                        // ディップレス（中央で両方 1.0、端に向かって直線で下がる）
+	CurveScratch                        // スクラッチ（ほぼ端まで 1.0 のまま、最後の数%で急にカット）
+)
+
+var crossfaderCurveNames = map[CrossfaderCurve]string{
+	CurvePower:   "power",
+	CurveLinear:  "linear",
+	CurveDipless: "dipless",
+	CurveScratch: "scratch",
+}
+
+func (c CrossfaderCurve) String() string {
+	if name, ok := crossfaderCurveNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("CrossfaderCurve(%d)", int(c))
+}
+
+// ParseCrossfaderCurve は名前（"power", "linear", "dipless", "scratch"）からカーブを返す
+func ParseCrossfaderCurve(name string) (CrossfaderCurve, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for c, n := range crossfaderCurveNames {
+		if n == name {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown crossfader curve: %q", name)
+}
+
+// UsesSlope はスロープ（カットの鋭さ）が効くカーブか（スクラッチだけ）
+func (c CrossfaderCurve) UsesSlope() bool {
+	return c == CurveScratch
+}
+
+// スクラッチカーブのカット幅（フェーダーの全長に対する割合）
+// 💡 スロープ 0 で 20%、1 で 1%。間は対数的に変える（つまみの感触が均等になるように）
+const (
+	scratchCutWidest   = 0.2
+	scratchCutSharpest = 0.01
+)
+
+// DefaultCrossfaderSlope はスロープの初期値
+const DefaultCrossfaderSlope = 0.5
+
+// CrossfaderGains はフェーダー位置からA側・B側のゲインを計算する
+// 解説：
+//
+//	position: -1.0 (A) ～ 0.0 (中央) ～ 1.0 (B)
+//	slope:    0.0 ～ 1.0（スクラッチカーブのカットの鋭さ。他のカーブでは使わない）
+//	reverse:  左右を入れ替える（ハムスタースイッチ）
+//
+// 各側のゲインは「自分の側への開き具合 open（反対側の端で 0 ～ 自分の端で 1）」だけで決まるので、
+// A と B は左右対称になる
+//
+//	power:   sin(open × π/2)
+//	linear:  open
+//	dipless: min(1, 2 × open)
+//	scratch: open が width 以上なら 1.0、それより反対側では sin カーブで 0 へ
+func CrossfaderGains(position float64, curve CrossfaderCurve, slope float64, reverse bool) (gainA, gainB float64) {
+	position = math.Max(-1, math.Min(1, position))
+	if reverse {
+		position = -position
+	}
+
+	// 正規化: -1～1 を 0～1 に変換（A 側の端からの距離）
+	normalized := (position + 1.0) / 2.0
+
+	return curveGain(1-normalized, curve, slope), curveGain(normalized, curve, slope)
+}
+
+// curveGain は開き具合 open からゲインを返す
+func curveGain(open float64, curve CrossfaderCurve, slope float64) float64 {
+	switch curve {
+	case CurveLinear:
+		return open
+	case CurveDipless:
+		return math.Min(1, 2*open)
+	case CurveScratch:
+		slope = math.Max(0, math.Min(1, slope))
+		width := scratchCutWidest * math.Pow(scratchCutSharpest/scratchCutWidest, slope)
+		if open >= width {
+			return 1
+		}
+		return math.Sin(open / width * math.Pi / 2)
+	default:
+		return math.Sin(open * math.Pi / 2)
+	}
+}
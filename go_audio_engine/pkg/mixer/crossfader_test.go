@@ -0,0 +1,71 @@
+package mixer
+
+import (
+	"math"
+	"testing"
+)
+
+// TestCrossfaderGains は各カーブの端と中央のゲインを確認する
+func TestCrossfaderGains(t *testing.T) {
+	const tolerance = 1e-9
+
+	tests := []struct {
+		curve CrossfaderCurve
+		want  [3][2]float64 // 位置 -1, 0, +1 での (A, B)
+	}{
+		{CurvePower, [3][2]float64{{1, 0}, {math.Sqrt2 / 2, math.Sqrt2 / 2}, {0, 1}}},
+		{CurveLinear, [3][2]float64{{1, 0}, {0.5, 0.5}, {0, 1}}},
+		{CurveDipless, [3][2]float64{{1, 0}, {1, 1}, {0, 1}}},
+		{CurveScratch, [3][2]float64{{1, 0}, {1, 1}, {0, 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.curve.String(), func(t *testing.T) {
+			for i, position := range []float64{-1, 0, 1} {
+				for _, slope := range []float64{0, DefaultCrossfaderSlope, 1} {
+					gainA, gainB := CrossfaderGains(position, tt.curve, slope, false)
+					want := tt.want[i]
+					if math.Abs(gainA-want[0]) > tolerance || math.Abs(gainB-want[1]) > tolerance {
+						t.Errorf("position %v slope %v: gains = (%v, %v), want (%v, %v)",
+							position, slope, gainA, gainB, want[0], want[1])
+					}
+
+					// ハムスター：左右が入れ替わる
+					revA, revB := CrossfaderGains(position, tt.curve, slope, true)
+					if revA != gainB || revB != gainA {
+						t.Errorf("position %v reversed: gains = (%v, %v), want (%v, %v)", position, revA, revB, gainB, gainA)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestCrossfaderSlope はスロープがスクラッチカーブのカット幅だけを変えることを確認する
+func TestCrossfaderSlope(t *testing.T) {
+	// B 側の端から 5% の位置：カット幅 20%（slope 0）ならカットの途中、1%（slope 1）なら全開
+	const position = -0.9
+
+	_, wide := CrossfaderGains(position, CurveScratch, 0, false)
+	_, sharp := CrossfaderGains(position, CurveScratch, 1, false)
+	if wantWide := math.Sin(0.05 / scratchCutWidest * math.Pi / 2); math.Abs(wide-wantWide) > 1e-9 {
+		t.Errorf("scratch slope 0: gain B = %v, want %v", wide, wantWide)
+	}
+	if sharp != 1 {
+		t.Errorf("scratch slope 1: gain B = %v, want 1", sharp)
+	}
+
+	for _, curve := range []CrossfaderCurve{CurvePower, CurveLinear, CurveDipless} {
+		if curve.UsesSlope() {
+			t.Errorf("%s: UsesSlope = true, want false", curve)
+		}
+		a0, b0 := CrossfaderGains(position, curve, 0, false)
+		a1, b1 := CrossfaderGains(position, curve, 1, false)
+		if a0 != a1 || b0 != b1 {
+			t.Errorf("%s: slope changed gains (%v, %v) -> (%v, %v)", curve, a0, b0, a1, b1)
+		}
+	}
+	if !CurveScratch.UsesSlope() {
+		t.Error("scratch: UsesSlope = false, want true")
+	}
+}
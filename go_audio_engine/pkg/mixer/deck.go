@@ -0,0 +1,82 @@
+package mixer
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"go_audio_engine/pkg/audio"
+)
+
+// DeckID はデッキの番号（0 から始まる）
+// API やステータスでは "a", "b", "c" ... の文字で表す
+type DeckID int
+
+const (
+	DeckA DeckID = iota
+	DeckB
+	DeckC
+	DeckD
+)
+
+// MaxDecks は1つのミキサーで扱えるデッキの最大数
+const MaxDecks = 8
+
+// String はデッキを表す文字（"a", "b", ...）を返す
+func (id DeckID) String() string {
+	if id < 0 || id >= MaxDecks {
+		return fmt.Sprintf("deck(%d)", int(id))
+	}
+	return string(rune('a' + id))
+}
+
+// ParseDeckID は "a" や "B" などの文字からデッキ番号を返す
+func ParseDeckID(s string) (DeckID, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) != 1 || s[0] < 'a' || s[0] >= 'a'+MaxDecks {
+		return 0, fmt.Errorf("invalid deck id: %q", s)
+	}
+	return DeckID(s[0] - 'a'), nil
+}
+
+// Deck はミキサーの1チャンネル
+// 曲をロードするたびに Track は差し替わるが、Deck（チャンネル側の設定）はそのまま残る
+type Deck struct {
+	ID    DeckID
+	Strip *ChannelStrip // チャンネルストリップ（トリム・フェーダー・クロスフェーダー割り当て）
+	Meter *audio.Meter  // チャンネルのレベルメーター（トリムの後・フェーダーの前）
+
+	track atomic.Pointer[audio.Track] // 再生中のトラック（差し替えはオーディオスレッドが行う）
+
+	// Mix 用（オーディオスレッド専用）
+	buffer   []float32       // デッキの音（audio.MaxBlockFrames 分を newDeck で確保）
+	trimRamp *audio.Smoother // トリム（倍率）
+	gainRamp *audio.Smoother // チャンネルフェーダー × クロスフェーダー
+	pflRamp  *audio.Smoother // キューに送る量（PFL のオン = 1、オフ = 0）
+}
+
+// Track は再生中のトラックを返す
+func (d *Deck) Track() *audio.Track {
+	return d.track.Load()
+}
+
+// newDeck はデッキを作成
+// 💡 クロスフェーダーの割り当ては、2デッキなら A が左・B が右、4デッキなら A/C が左・B/D が右（一般的な4デッキの配置）
+func newDeck(id DeckID, sampleRate int) *Deck {
+	assign := AssignA
+	if id%2 == 1 {
+		assign = AssignB
+	}
+	rate := float64(sampleRate)
+	d := &Deck{
+		ID:       id,
+		Strip:    NewChannelStrip(assign),
+		Meter:    audio.NewMeter(rate),
+		trimRamp: audio.NewSmoother(rate, audio.SmoothSeconds, 1),
+		gainRamp: audio.NewSmoother(rate, audio.FaderSmoothSeconds, 0),
+		pflRamp:  audio.NewSmoother(rate, audio.FaderSmoothSeconds, 0),
+		buffer:   make([]float32, audio.MaxBlockFrames*2),
+	}
+	d.track.Store(audio.NewTrack(sampleRate))
+	return d
+}
@@ -1,19 +1,14 @@
 package mixer
 
 import (
+	"fmt"
 	"log"
 	"math"
-	"sync"
+	"strings"
+	"sync/atomic"
 
 	"go_audio_engine/pkg/audio"
-)
-
-// 💡 追加: Deckを識別するための型と定数
-type DeckID int
-
-const (
-	DeckA DeckID = iota
-	DeckB
+	"go_audio_engine/pkg/recorder"
 )
 
 // 💡 追加: ファイルロードリクエストを表す構造体
@@ -28,39 +23,92 @@ type loadedTrack struct {
 	trackData *audio.Track // 新しいTrackオブジェクトをそのまま渡す
 }
 
+// 💡 追加: 差し替えで外したトラック（オーディオスレッドからローダーのゴルーチンへ返す）
+type retiredTrack struct {
+	deckID   DeckID
+	track    *audio.Track
+	rejected bool // デッキが存在せず、差し替えなかった（track はロードしたトラック）
+}
+
 // DJMixer はプロフェッショナルDJミキサー
+// 💡 オーディオスレッド（Mix）はロックを取らない。API から変わる設定は atomic で持ち、
+// トラックの差し替えはチャンネル経由でオーディオスレッド自身が行う
 type DJMixer struct {
-	DeckA        *audio.Track
-	DeckB        *audio.Track
-	Crossfader   float64 // -1.0 (A) ～ 0.0 (Center) ～ 1.0 (B)
-	MasterVolume float64
+	Decks       []*Deck            // デッキ（数は NewDJMixer で決まり、その後は変わらない）
+	Limiter     *audio.Limiter     // マスターのリミッター（ハードクリップの代わり）
+	MasterMeter *audio.Meter       // マスター出力のレベルメーター（リミッターの後）
+	Recorder    *recorder.Recorder // マスター出力の録音
+
+	crossfader atomic.Uint64 // -1.0 (A) ～ 0.0 (Center) ～ 1.0 (B)、float64 のビット列
+	// クロスフェーダーの特性
+	crossfaderCurve   atomic.Int32  // CrossfaderCurve
+	crossfaderSlope   atomic.Uint64 // 0.0 ～ 1.0（スクラッチカーブのカットの鋭さ）
+	crossfaderReverse atomic.Bool   // 左右反転（ハムスター）
+	masterVolume      atomic.Uint64
+
+	// ヘッドフォン（キュー）
+	cueMix          atomic.Uint64 // 0.0 (キューのみ) ～ 1.0 (マスターのみ)
+	headphoneVolume atomic.Uint64 // 0.0 ～ 1.0
+	cueLimiter      *audio.Limiter
+
+	// 💡 つまみの値は段差のまま掛けるとジッパーノイズになるので、なめらかに動かして掛ける（オーディオスレッド専用）
+	masterRamp    *audio.Smoother // マスターボリューム
+	cueRamp       *audio.Smoother // ヘッドフォンのキューの音量
+	cueMasterRamp *audio.Smoother // ヘッドフォンのマスターの音量
 
 	// 新機能
-	SyncEnabled bool   // BPM同期が有効か
-	SyncMaster  string // "a" or "b" - どちらがマスターか
-
-	mu sync.RWMutex
+	// 💡 同期の設定はオーディオスレッドがロックなしで読むので atomic で持つ
+	syncEnabled atomic.Bool  // BPM同期が有効か
+	syncMaster  atomic.Int32 // どちらがマスターか（DeckID）
 
 	// 💡 追加: 非同期ロードのためのチャンネル
-	loadRequestChan chan loadRequest // UIスレッドからMixスレッドへ
-	loadedTrackChan chan loadedTrack // Mixスレッド内で安全に適用するため
-	sampleRate      int              // Track生成時に必要なので保持
+	loadRequestChan chan loadRequest  // UIスレッドからMixスレッドへ
+	loadedTrackChan chan loadedTrack  // Mixスレッド内で安全に適用するため
+	retiredChan     chan retiredTrack // 外したトラックをMixスレッドの外で閉じるため
+	sampleRate      int               // Track生成時に必要なので保持
+
+	mixTracks []*audio.Track // Mix 用のトラックのコピー（オーディオスレッド専用）
 }
 
 // NewDJMixer は新しいDJミキサーを作成
-func NewDJMixer(sampleRate int) *DJMixer {
+// deckCount はデッキの数（1 ～ MaxDecks）
+func NewDJMixer(sampleRate int, deckCount int) *DJMixer {
+	if deckCount < 1 {
+		deckCount = 1
+	}
+	if deckCount > MaxDecks {
+		deckCount = MaxDecks
+	}
+
+	decks := make([]*Deck, deckCount)
+	for i := range decks {
+		decks[i] = newDeck(DeckID(i), sampleRate)
+	}
+
 	m := &DJMixer{
-		DeckA:        audio.NewTrack(sampleRate),
-		DeckB:        audio.NewTrack(sampleRate),
-		Crossfader:   0.0,
-		MasterVolume: 1.0,
-		SyncEnabled:  false,
-		SyncMaster:   "a",
+		Decks:       decks,
+		Limiter:     audio.NewLimiter(float64(sampleRate)),
+		MasterMeter: audio.NewMeter(float64(sampleRate)),
+		Recorder:    recorder.NewRecorder(sampleRate),
+		cueLimiter:  audio.NewLimiter(float64(sampleRate)),
+
+		masterRamp:    audio.NewSmoother(float64(sampleRate), audio.SmoothSeconds, 1),
+		cueRamp:       audio.NewSmoother(float64(sampleRate), audio.SmoothSeconds, 0),
+		cueMasterRamp: audio.NewSmoother(float64(sampleRate), audio.SmoothSeconds, 1),
 		// 💡 追加: チャンネルの初期化
 		loadRequestChan: make(chan loadRequest, 10), // バッファを持たせる
 		loadedTrackChan: make(chan loadedTrack, 10),
+		retiredChan:     make(chan retiredTrack, 10),
 		sampleRate:      sampleRate,
+		mixTracks:       make([]*audio.Track, deckCount),
 	}
+	m.SetCrossfader(0.0)
+	// 💡 初期値は従来どおり等パワーカーブ
+	m.SetCrossfaderCurve(CurvePower)
+	m.SetCrossfaderSlope(DefaultCrossfaderSlope)
+	m.SetMasterVolume(1.0)
+	m.SetCueMix(0.0)
+	m.SetHeadphoneVolume(1.0)
 
 	// 💡 修正: DJミキサー自身のゴルーチンをコンストラクタで起動する
 	go m.processLoadRequests()
@@ -68,6 +116,34 @@ func NewDJMixer(sampleRate int) *DJMixer {
 	return m
 }
 
+// Deck は指定したデッキの現在のトラックを返す（存在しないデッキなら nil）
+// 💡 ロードのたびにトラックは差し替わるので、操作のたびにこれで取り直すこと
+func (m *DJMixer) Deck(id DeckID) *audio.Track {
+	if id < 0 || int(id) >= len(m.Decks) {
+		return nil
+	}
+	return m.Decks[id].Track()
+}
+
+// Strip は指定したデッキのチャンネルストリップを返す（存在しないデッキなら nil）
+// 💡 チャンネルストリップはトラックと違って差し替わらないので、ロックは不要
+func (m *DJMixer) Strip(id DeckID) *ChannelStrip {
+	if id < 0 || int(id) >= len(m.Decks) {
+		return nil
+	}
+	return m.Decks[id].Strip
+}
+
+// DeckCount はデッキの数を返す
+func (m *DJMixer) DeckCount() int {
+	return len(m.Decks)
+}
+
+// SampleRate はミキサーの処理レート（作成時に決まり、以後変わらない）
+func (m *DJMixer) SampleRate() int {
+	return m.sampleRate
+}
+
 // 💡 追加: 非同期でトラックをロードするメソッド
 func (m *DJMixer) LoadTrackAsync(deckID DeckID, filePath string) {
 	// リクエストをチャンネルに送信するだけ。重い処理は行わない。
@@ -75,250 +151,596 @@ func (m *DJMixer) LoadTrackAsync(deckID DeckID, filePath string) {
 }
 
 // 💡 追加: 実際にファイルをデコードする内部メソッド
+// このゴルーチンは、ロードリクエストを待ち受けてデコードし、オーディオスレッドが外したトラックを閉じる
 func (m *DJMixer) processLoadRequests() {
-	// このゴルーチンは、ロードリクエストを待ち受け、デコード処理を行う
-	for req := range m.loadRequestChan {
-		log.Printf("🎵 [Decoder] Start decoding: %s for Deck %d", req.filePath, req.deckID)
-
-		// 新しいTrackオブジェクトを作成し、ファイルをロードする
-		newTrack := audio.NewTrack(m.sampleRate)
-		err := newTrack.LoadWAV(req.filePath) // ここが重い処理
-		if err != nil {
-			log.Printf("❌ [Decoder] Failed to load WAV for Deck %d: %v", req.deckID, err)
-			continue // エラーが発生したら次のリクエストへ
+	for {
+		select {
+		case req := <-m.loadRequestChan:
+			m.loadTrack(req)
+		case retired := <-m.retiredChan:
+			m.closeRetired(retired)
 		}
+	}
+}
+
+// loadTrack はファイルをデコードして、オーディオスレッドに渡す
+func (m *DJMixer) loadTrack(req loadRequest) {
+	log.Printf("🎵 [Decoder] Start decoding: %s for Deck %s", req.filePath, req.deckID)
+
+	// 新しいTrackオブジェクトを作成し、ファイルをロードする
+	newTrack := audio.NewTrack(m.sampleRate)
+	err := newTrack.Load(req.filePath) // ここが重い処理
+	if err != nil {
+		log.Printf("❌ [Decoder] Failed to load track for Deck %s: %v", req.deckID, err)
+		return // エラーが発生したら次のリクエストへ
+	}
 
-		// 💡 追加: デコード成功後、同じゴルーチン内でBPM検出を実行
-		go newTrack.DetectBPMAsync()
+	// 💡 追加: デコード成功後、同じゴルーチン内でBPM検出を実行
+	go newTrack.DetectBPMAsync()
+
+	log.Printf("✅ [Decoder] Finished decoding: %s. Sending to mixer.", req.filePath)
+	// デコード成功後、結果をloadedTrackChanに送信
+	// 💡 待っている間も外したトラックを受け取る（オーディオスレッドの retiredChan を詰まらせない）
+	loaded := loadedTrack{deckID: req.deckID, trackData: newTrack}
+	for {
+		select {
+		case m.loadedTrackChan <- loaded:
+			return
+		case retired := <-m.retiredChan:
+			m.closeRetired(retired)
+		}
+	}
+}
 
-		log.Printf("✅ [Decoder] Finished decoding: %s. Sending to mixer.", req.filePath)
-		// デコード成功後、結果をloadedTrackChanに送信
-		m.loadedTrackChan <- loadedTrack{deckID: req.deckID, trackData: newTrack}
+// closeRetired はオーディオスレッドが外したトラックを閉じる
+// 💡 ストリーミングの先読みゴルーチンの停止待ちやログの出力があるので、オーディオスレッドでは行わない
+func (m *DJMixer) closeRetired(retired retiredTrack) {
+	if retired.rejected {
+		log.Printf("❌ [Mixer] Unknown deck: %s", retired.deckID)
+	} else {
+		log.Printf("🔄 [Mixer] Swapped track for Deck %s", retired.deckID)
+	}
+	if err := retired.track.Close(); err != nil {
+		log.Printf("❌ [Mixer] Failed to close track for Deck %s: %v", retired.deckID, err)
 	}
 }
 
-// Mix は2つのデッキをミックス
-// 解説：DJミキサーの心臓部
+// Mix はすべてのデッキをミックス（マスター出力のみ）
 func (m *DJMixer) Mix(out []float32) {
+	m.MixWithCue(out, nil)
+}
+
+// MixWithCue はマスター出力とヘッドフォン（キュー）出力を同時に作る
+// 解説：DJミキサーの心臓部
+//
+//	master: マスター出力（ステレオ・インターリーブ）
+//	cue:    ヘッドフォン出力（master と同じ長さ）。nil ならキューは作らない
+//
+// 💡 出力デバイスがなくても、バッファに書き出すだけなのでオフラインで確認できる
+func (m *DJMixer) MixWithCue(out, cue []float32) {
+	if cue != nil && len(cue) != len(out) {
+		cue = nil // 長さが違うバッファには書かない（オーディオスレッドで panic させない）
+	}
+
 	// 💡 追加: デッドロックを避けるため、Mixループ内で安全にトラックを入れ替える
-	select {
-	case loaded := <-m.loadedTrackChan:
-		m.swapTrack(loaded)
-	default:
-		// 新しいトラックがなければ何もしない (ノンブロッキング)
-	}
-
-	m.mu.RLock()
-	crossfader := m.Crossfader
-	masterVolume := m.MasterVolume
-	// syncEnabled := m.SyncEnabled
-	// syncMaster := m.SyncMaster
-	m.mu.RUnlock()
-
-	// BPM同期処理
-	// if syncEnabled {
-	// 	m.applySyncSpeed(syncMaster)
-	// }
-
-	// デッキA/Bの音声を取得
-	bufferA := make([]float32, len(out))
-	bufferB := make([]float32, len(out))
-
-	m.DeckA.ReadSamples(bufferA)
-	m.DeckB.ReadSamples(bufferB)
-
-	// クロスフェーダーカーブの計算
-	// 解説：等パワークロスフェード（聴感上の音量が一定）
-	//
-	// -1.0 → A側フル: gainA=1.0, gainB=0.0
-	//  0.0 → 中央:    gainA=0.707, gainB=0.707 (√2/2)
-	//  1.0 → B側フル: gainA=0.0, gainB=1.0
-
-	// 正規化: -1～1 を 0～1 に変換
-	normalized := (crossfader + 1.0) / 2.0
-
-	// 等パワーカーブ（三角関数を使用）
-	// angleA := (1.0 - normalized) * math.Pi / 2.0 // π/2 ～ 0
-	angleB := normalized * math.Pi / 2.0 // 0 ～ π/2
-
-	gainA := math.Cos(angleB) // B側の角度でA側のゲイン
-	gainB := math.Sin(angleB) // B側の角度でB側のゲイン
-
-	// ミックス実行
+	// 外したトラックを返す retiredChan に空きがあるときだけ受け取る（オーディオスレッドは送信で待たない）
+	if len(m.retiredChan) < cap(m.retiredChan) {
+		select {
+		case loaded := <-m.loadedTrackChan:
+			m.swapTrack(loaded)
+		default:
+			// 新しいトラックがなければ何もしない (ノンブロッキング)
+		}
+	}
+
+	// このブロックの設定をまとめて読む（ロックなし）
+	tracks := m.mixTracks
+	for i, deck := range m.Decks {
+		tracks[i] = deck.Track()
+	}
+	crossfader := m.GetCrossfader()
+	curve := m.GetCrossfaderCurve()
+	slope := m.GetCrossfaderSlope()
+	reverse := m.GetCrossfaderReverse()
+	masterVolume := m.GetMasterVolume()
+	cueMix := m.GetCueMix()
+	headphoneVolume := m.GetHeadphoneVolume()
+
+	// BPM同期処理（ロックなし）
+	// マスター以外のすべてのデッキがマスターに合わせる
+	syncEnabled := m.syncEnabled.Load()
+	master := DeckID(m.syncMaster.Load())
+	for i, track := range tracks {
+		if !syncEnabled || DeckID(i) == master || int(master) >= len(tracks) {
+			track.SetSyncSpeed(0)
+			continue
+		}
+		m.applySyncSpeed(tracks[master], track)
+	}
+
+	// クロスフェーダーカーブの計算（カーブごとの形は CrossfaderGains を参照）
+	gainA, gainB := CrossfaderGains(crossfader, curve, slope, reverse)
+
 	for i := range out {
-		mixed := bufferA[i]*float32(gainA) + bufferB[i]*float32(gainB)
-		out[i] = mixed * float32(masterVolume)
-
-		// ハードクリッピング防止
-		// 解説：音割れを防ぐため±1.0に制限
-		if out[i] > 1.0 {
-			out[i] = 1.0
-		} else if out[i] < -1.0 {
-			out[i] = -1.0
+		out[i] = 0
+	}
+	for i := range cue {
+		cue[i] = 0
+	}
+	pflCount := 0
+
+	// 各デッキの音声を取得して、チャンネルストリップを通して足し合わせる
+	// トリム → （メーター）→ チャンネルフェーダー → クロスフェーダー（割り当てた側のゲイン）
+	for i, deck := range m.Decks {
+		if cap(deck.buffer) < len(out) {
+			deck.buffer = make([]float32, len(out))
+		}
+		buffer := deck.buffer[:len(out)]
+		tracks[i].ReadSamples(buffer)
+
+		// 💡 チャンネルのメーターはフェーダーの前で測る（フェーダーを下げたままでもトリムを合わせられる）
+		deck.trimRamp.Set(deck.Strip.TrimGain())
+		scaleRamp(buffer, deck.trimRamp)
+		deck.Meter.Process(buffer)
+
+		// PFL：フェーダーの前の音をキューに足す
+		pfl := deck.Strip.GetPFL()
+		if pfl {
+			pflCount++
+		}
+		if cue != nil {
+			if pfl {
+				deck.pflRamp.Set(1)
+			} else {
+				deck.pflRamp.Set(0)
+			}
+			mixRamp(cue, buffer, deck.pflRamp)
+		}
+
+		deck.gainRamp.Set(deck.Strip.GetFader() * deck.Strip.CrossfaderGain(gainA, gainB))
+		mixRamp(out, buffer, deck.gainRamp)
+	}
+
+	// マスターボリューム
+	m.masterRamp.Set(masterVolume)
+	scaleRamp(out, m.masterRamp)
+
+	// ヘッドフォン：キューとマスターを CueMix で混ぜる
+	// 💡 マスターはリミッターの前の音を使う（リミッターの遅延でキューとずれて音が濁らないように）
+	//    PFL が1つもなければマスターをそのまま聞く（一般的なミキサーと同じ）
+	if cue != nil {
+		if pflCount == 0 {
+			cueMix = 1.0
+		}
+		m.cueRamp.Set((1 - cueMix) * headphoneVolume)
+		m.cueMasterRamp.Set(cueMix * headphoneVolume)
+		for i := 0; i+1 < len(cue); i += 2 {
+			cueGain := float32(m.cueRamp.Next())
+			masterGain := float32(m.cueMasterRamp.Next())
+			cue[i] = cue[i]*cueGain + out[i]*masterGain
+			cue[i+1] = cue[i+1]*cueGain + out[i+1]*masterGain
+		}
+		m.cueLimiter.Process(cue)
+	}
+
+	// リミッター
+	// 解説：±1.0 で切り落とす（ハードクリップ）と波形が歪むので、先読みして音量を下げる
+	m.Limiter.Process(out)
+
+	m.MasterMeter.Process(out)
+
+	// 録音（リングバッファに入れるだけ。ファイルへの書き出しは別のゴルーチン）
+	m.Recorder.Write(out)
+}
+
+// scaleRamp はステレオのバッファに、なめらかに動くゲインを掛ける
+func scaleRamp(buffer []float32, ramp *audio.Smoother) {
+	if !ramp.Ramping() {
+		gain := float32(ramp.Value())
+		if gain != 1 {
+			for k := range buffer {
+				buffer[k] *= gain
+			}
+		}
+		return
+	}
+	for k := 0; k+1 < len(buffer); k += 2 {
+		gain := float32(ramp.Next())
+		buffer[k] *= gain
+		buffer[k+1] *= gain
+	}
+}
+
+// mixRamp は src になめらかに動くゲインを掛けて dst に足す（ステレオ）
+func mixRamp(dst, src []float32, ramp *audio.Smoother) {
+	if !ramp.Ramping() {
+		gain := float32(ramp.Value())
+		if gain != 0 {
+			for k, v := range src {
+				dst[k] += v * gain
+			}
 		}
+		return
+	}
+	for k := 0; k+1 < len(src); k += 2 {
+		gain := float32(ramp.Next())
+		dst[k] += src[k] * gain
+		dst[k+1] += src[k+1] * gain
 	}
 }
 
 // 💡 追加: デコード済みのトラックを安全に入れ替えるメソッド
+// オーディオスレッド（Mix）から呼ばれる。API のスレッドは Deck で新しいトラックを読むだけなのでロックは不要
+// 💡 ログの出力もゴルーチンの起動もしない。外したトラックは retiredChan でローダーのゴルーチンに返し、そこで閉じる
+//
+//	retiredChan に空きがあることは呼び出し側で確認済み（送り手はオーディオスレッドだけなので、ここで待つことはない）
 func (m *DJMixer) swapTrack(loaded loadedTrack) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	if loaded.deckID < 0 || int(loaded.deckID) >= len(m.Decks) {
+		m.retiredChan <- retiredTrack{deckID: loaded.deckID, track: loaded.trackData, rejected: true}
+		return
+	}
+
+	deck := m.Decks[loaded.deckID]
 
-	log.Printf("🔄 [Mixer] Swapping track for Deck %d", loaded.deckID)
-	// 古いトラックの再生を停止し、リソースを解放する
-	if loaded.deckID == DeckA && m.DeckA != nil {
-		m.DeckA.Stop()
-	} else if loaded.deckID == DeckB && m.DeckB != nil {
-		m.DeckB.Stop()
+	// 💡 EQ・フィルター・エフェクトなどの設定はデッキのものなので、公開する前に新しいトラックに引き継ぐ
+	if current := deck.track.Load(); current != nil {
+		loaded.trackData.TakeOverProcessing(current)
 	}
 
-	// 新しいトラックに差し替える
-	if loaded.deckID == DeckA {
-		m.DeckA = loaded.trackData
-	} else if loaded.deckID == DeckB {
-		m.DeckB = loaded.trackData
+	// 新しいトラックに差し替え、古いトラックの再生を停止する
+	old := deck.track.Swap(loaded.trackData)
+	if old != nil {
+		old.Stop()
+		m.retiredChan <- retiredTrack{deckID: loaded.deckID, track: old}
 	}
 }
 
+// 同期エンジンの調整値
+const (
+	syncPhaseSeconds = 0.5  // 拍のずれをこの時間（秒）くらいで戻す
+	syncMaxNudge     = 0.05 // 位相合わせで速度を動かす最大量（テンポに対する割合）
+)
+
 // applySyncSpeed はBPM同期のスピード調整
-// 解説：2つのトラックのBPMを合わせる
-func (m *DJMixer) applySyncSpeed(master string) {
-	var masterBPM, slaveBPM float64
-	var slaveDeck *audio.Track
-
-	if master == "a" {
-		masterBPM = m.DeckA.BPM.GetBPM()
-		slaveBPM = m.DeckB.BPM.GetBPM()
-		slaveDeck = m.DeckB
-	} else {
-		masterBPM = m.DeckB.BPM.GetBPM()
-		slaveBPM = m.DeckA.BPM.GetBPM()
-		slaveDeck = m.DeckA
-	}
+// 解説：スレーブのテンポをマスターに合わせたうえで、拍の位置（位相）も揃え続ける
+//
+//  1. テンポ：マスターの実際のテンポ（BPM × 再生速度）÷ スレーブのBPM
+//     例: Master=120BPM, Slave=130BPM → Speed=120/130≒0.92
+//     比が2倍・半分に近い場合はハーフ/ダブルタイムとして合わせる（例: 174BPM と 87BPM）
+//  2. 位相：両方のビートグリッド上で「拍の中の位置」を比べ、ずれの分だけ速度を少し上げ下げする
+//     BPMの丸め誤差やループ・シークで生じたずれも、毎ブロック少しずつ引き戻される（ドリフト補正）
+//
+// 💡 ピッチフェーダー（Track.Speed）は書き換えず、同期用の速度として別に渡す
+//
+//	オーディオスレッドから呼ばれるので、ロックは取らない（値はすべて atomic で読む）
+func (m *DJMixer) applySyncSpeed(master, slave *audio.Track) {
+	master.SetSyncSpeed(0)
+
+	masterGrid := master.GetBeatGrid()
+	slaveGrid := slave.GetBeatGrid()
 
 	// BPMが検出されていない場合はスキップ
-	if masterBPM == 0 || slaveBPM == 0 {
+	if masterGrid == nil || slaveGrid == nil || masterGrid.BPM <= 0 || slaveGrid.BPM <= 0 {
+		slave.SetSyncSpeed(0)
 		return
 	}
 
-	// スピード比を計算
-	// 例: Master=120BPM, Slave=130BPM → Speed=120/130≒0.92
-	speedRatio := masterBPM / slaveBPM
+	masterPos, masterSpeed, masterPlaying := master.Playhead()
+	slavePos, _, slavePlaying := slave.Playhead()
+	if masterSpeed <= 0 {
+		masterSpeed = 1.0 // まだ一度も再生していない
+	}
+
+	// 1. テンポを合わせる
+	// multiple はマスター1拍あたりに進むスレーブの拍数（ハーフ/ダブルタイム）
+	speed := masterGrid.BPM * masterSpeed / slaveGrid.BPM
+	multiple := 1.0
+	for speed > math.Sqrt2 {
+		speed /= 2
+		multiple /= 2
+	}
+	for speed < 1/math.Sqrt2 {
+		speed *= 2
+		multiple *= 2
+	}
+
+	// 2. 拍の位置を合わせる（両方再生中のときだけ）
+	if masterPlaying && slavePlaying {
+		diff := slaveGrid.BeatNumber(slavePos) - masterGrid.BeatNumber(masterPos)*multiple
+		diff -= math.Round(diff) // -0.5 ～ 0.5 拍
+
+		// ずれ（スレーブの曲上の秒数）を syncPhaseSeconds かけて戻す速度
+		nudge := -diff * slaveGrid.Interval() / syncPhaseSeconds
+		limit := speed * syncMaxNudge
+		if nudge > limit {
+			nudge = limit
+		} else if nudge < -limit {
+			nudge = -limit
+		}
+		speed += nudge
+	}
 
-	// スレーブのスピードを調整
-	slaveDeck.SetSpeed(speedRatio)
+	slave.SetSyncSpeed(speed)
 }
 
 // SetCrossfader はクロスフェーダー値を設定
 func (m *DJMixer) SetCrossfader(value float64) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	if value < -1.0 {
 		value = -1.0
 	}
 	if value > 1.0 {
 		value = 1.0
 	}
-	m.Crossfader = value
+	m.crossfader.Store(math.Float64bits(value))
+}
+
+// GetCrossfader はクロスフェーダー値を返す
+func (m *DJMixer) GetCrossfader() float64 {
+	return math.Float64frombits(m.crossfader.Load())
+}
+
+// SetCrossfaderCurve はクロスフェーダーのカーブを設定
+func (m *DJMixer) SetCrossfaderCurve(curve CrossfaderCurve) {
+	m.crossfaderCurve.Store(int32(curve))
+}
+
+// GetCrossfaderCurve はクロスフェーダーのカーブを返す
+func (m *DJMixer) GetCrossfaderCurve() CrossfaderCurve {
+	return CrossfaderCurve(m.crossfaderCurve.Load())
+}
+
+// SetCrossfaderSlope はカーブのスロープを設定（0.0 ～ 1.0）
+func (m *DJMixer) SetCrossfaderSlope(slope float64) {
+	if slope < 0 {
+		slope = 0
+	}
+	if slope > 1.0 {
+		slope = 1.0
+	}
+	m.crossfaderSlope.Store(math.Float64bits(slope))
+}
+
+// GetCrossfaderSlope はカーブのスロープを返す
+func (m *DJMixer) GetCrossfaderSlope() float64 {
+	return math.Float64frombits(m.crossfaderSlope.Load())
+}
+
+// SetCrossfaderReverse はクロスフェーダーの左右反転（ハムスター）を切り替える
+func (m *DJMixer) SetCrossfaderReverse(reverse bool) {
+	m.crossfaderReverse.Store(reverse)
+}
+
+// GetCrossfaderReverse はクロスフェーダーが左右反転しているか
+func (m *DJMixer) GetCrossfaderReverse() bool {
+	return m.crossfaderReverse.Load()
+}
+
+// SetCueMix はヘッドフォンのキューとマスターの割合を設定（0.0 = キューのみ ～ 1.0 = マスターのみ）
+func (m *DJMixer) SetCueMix(value float64) {
+	if value < 0 {
+		value = 0
+	}
+	if value > 1.0 {
+		value = 1.0
+	}
+	m.cueMix.Store(math.Float64bits(value))
+}
+
+// GetCueMix はヘッドフォンのキューとマスターの割合を返す
+func (m *DJMixer) GetCueMix() float64 {
+	return math.Float64frombits(m.cueMix.Load())
+}
+
+// SetHeadphoneVolume はヘッドフォンの音量を設定
+func (m *DJMixer) SetHeadphoneVolume(volume float64) {
+	if volume < 0 {
+		volume = 0
+	}
+	if volume > 1.0 {
+		volume = 1.0
+	}
+	m.headphoneVolume.Store(math.Float64bits(volume))
+}
+
+// GetHeadphoneVolume はヘッドフォンの音量を返す
+func (m *DJMixer) GetHeadphoneVolume() float64 {
+	return math.Float64frombits(m.headphoneVolume.Load())
 }
 
 // SetMasterVolume はマスターボリュームを設定
 func (m *DJMixer) SetMasterVolume(volume float64) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	if volume < 0 {
 		volume = 0
 	}
 	if volume > 1.0 {
 		volume = 1.0
 	}
-	m.MasterVolume = volume
+	m.masterVolume.Store(math.Float64bits(volume))
 }
 
-// EnableSync はBPM同期を有効化
-func (m *DJMixer) EnableSync(enabled bool, master string) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// GetMasterVolume はマスターボリュームを返す
+func (m *DJMixer) GetMasterVolume() float64 {
+	return math.Float64frombits(m.masterVolume.Load())
+}
 
-	m.SyncEnabled = enabled
-	if master == "a" || master == "b" {
-		m.SyncMaster = master
+// EnableSync はBPM同期を有効化
+// master はマスターにするデッキ（"a", "b", ...）。空ならマスターは変えない
+func (m *DJMixer) EnableSync(enabled bool, master string) error {
+	if master != "" {
+		id, err := ParseDeckID(master)
+		if err != nil {
+			return err
+		}
+		if int(id) >= len(m.Decks) {
+			return fmt.Errorf("deck %s does not exist", id)
+		}
+		m.syncMaster.Store(int32(id))
 	}
+	m.syncEnabled.Store(enabled)
+	return nil
 }
 
 // GetStatus はミキサーの状態を取得
 // 解説：interfaceを使った柔軟なデータ構造
 func (m *DJMixer) GetStatus() map[string]interface{} {
-	// 💡 修正: ロック時間を最小化するため、必要な値を即座にコピーする
-	m.mu.RLock()
-	deckA := m.DeckA
-	deckB := m.DeckB
-	crossfader := m.Crossfader
-	masterVolume := m.MasterVolume
-	syncEnabled := m.SyncEnabled
-	syncMaster := m.SyncMaster
-	m.mu.RUnlock()
+	tracks := make([]*audio.Track, len(m.Decks))
+	for i, deck := range m.Decks {
+		tracks[i] = deck.Track()
+	}
 
 	// map[string]interface{}: キーが文字列、値が任意の型
 	// JSON変換に便利
-	// 💡 修正: コピーした値を使ってmapを構築する
-	return map[string]interface{}{
-		"DeckA":        m.getDeckStatus(deckA),
-		"DeckB":        m.getDeckStatus(deckB),
-		"Crossfader":   crossfader,
-		"MasterVolume": masterVolume,
-		"SyncEnabled":  syncEnabled,
-		"SyncMaster":   syncMaster,
+	status := map[string]interface{}{
+		"Crossfader":        m.GetCrossfader(),
+		"CrossfaderCurve":   m.GetCrossfaderCurve().String(),
+		"CrossfaderSlope":   m.GetCrossfaderSlope(),
+		"CrossfaderReverse": m.GetCrossfaderReverse(),
+		"MasterVolume":      m.GetMasterVolume(),
+		"Limiter": map[string]interface{}{
+			"Ceiling":       m.Limiter.GetCeiling(),
+			"Release":       m.Limiter.GetRelease(),
+			"GainReduction": m.Limiter.GetGainReduction(),
+		},
+		"MasterMeter":     getMeterStatus(m.MasterMeter),
+		"Recorder":        m.Recorder.GetStatus(),
+		"CueMix":          m.GetCueMix(),
+		"HeadphoneVolume": m.GetHeadphoneVolume(),
+		"SyncEnabled":     m.syncEnabled.Load(),
+		"SyncMaster":      DeckID(m.syncMaster.Load()).String(),
 	}
+
+	// 💡 全デッキを "Decks" に並べる。既存のUIのために "DeckA", "DeckB" ... のキーでも同じものを返す
+	decks := make([]map[string]interface{}, len(tracks))
+	for i, track := range tracks {
+		id := DeckID(i)
+		deckStatus := m.getDeckStatus(track)
+		deckStatus["ID"] = id.String()
+		deckStatus["Strip"] = m.getStripStatus(m.Decks[i].Strip)
+		deckStatus["Meter"] = getMeterStatus(m.Decks[i].Meter)
+		deckStatus["Volume"] = m.Decks[i].Strip.GetFader() // 既存のUIの音量スライダーはチャンネルフェーダー
+		decks[i] = deckStatus
+		status["Deck"+strings.ToUpper(id.String())] = deckStatus
+	}
+	status["Decks"] = decks
+
+	return status
 }
 
 // getDeckStatus は個別デッキの状態を取得（内部ヘルパー）
 func (m *DJMixer) getDeckStatus(deck *audio.Track) map[string]interface{} {
+	bufferedSeconds, underruns := deck.StreamStats()
+	filter := deck.Filter.Settings()
+	loop := deck.CueManager.GetLoop()
+	lowFreq, highFreq := deck.EQ.GetCrossovers()
 	return map[string]interface{}{
 		"FilePath":      deck.FilePath, // ✅ "file" -> "FilePath"
-		"IsPlaying":     deck.IsPlaying,
+		"IsPlaying":     deck.IsPlaying(),
 		"Position":      deck.GetPosition(), // ✅ ...以下同様に大文字開始へ
 		"Duration":      deck.GetDuration(),
-		"Volume":        deck.Volume,
-		"Speed":         deck.Speed,
+		"SampleRate":    deck.SourceSampleRate,
+		"Channels":      deck.SourceChannels,
+		"Streaming":     deck.IsStreaming(),
+		"Buffered":      bufferedSeconds,
+		"Underruns":     underruns,
+		"Speed":         deck.GetSpeed(),
+		"SyncSpeed":     deck.SyncSpeed(),
+		"Interpolation": deck.GetInterpolation().String(),
+		"KeyLock":       deck.GetKeyLock(),
+		"Pitch":         deck.PitchShift.GetSemitones(),
 		"BPM":           deck.BPM.GetBPM(),
 		"BPMConfidence": deck.BPM.GetConfidence(), // 💡 修正: 統一のため大文字開始に
-		"EQ": map[string]float64{
-			"Low":  deck.EQ.Low,
-			"Mid":  deck.EQ.Mid,
-			"High": deck.EQ.High,
+		"BeatGrid":      m.getBeatGridStatus(deck),
+		"EQ": map[string]interface{}{
+			"Mode":     deck.EQ.GetMode().String(),
+			"Low":      deck.EQ.GetLow(),
+			"Mid":      deck.EQ.GetMid(),
+			"High":     deck.EQ.GetHigh(),
+			"LowFreq":  lowFreq,
+			"HighFreq": highFreq,
 		},
 		"Filter": map[string]interface{}{
-			"Type":      deck.Filter.Type,
-			"Cutoff":    deck.Filter.Cutoff,
-			"Resonance": deck.Filter.Resonance,
+			"Color":     filter.Color,
+			"Resonance": filter.Resonance,
+			"Type":      filter.Type(),
+			"Frequency": filter.Frequency(), // カットオフ（Hz、素通しのときは 0）
 		},
+		"Effects":   getEffectsStatus(deck.Effects),
 		"CuePoints": m.getCuePointsStatus(deck),
 		"Loop": map[string]interface{}{
-			"Enabled":  deck.CueManager.Loop.Enabled,
-			"Start":    deck.CueManager.Loop.Start,
-			"End":      deck.CueManager.Loop.End,
-			"IsActive": deck.CueManager.Loop.IsActive,
+			"Enabled":  loop.Enabled,
+			"Start":    loop.Start,
+			"End":      loop.End,
+			"IsActive": loop.IsActive,
 		},
 	}
 }
 
+// getEffectsStatus はエフェクトラックの状態を、かける順番で取得
+func getEffectsStatus(rack *audio.EffectRack) []map[string]interface{} {
+	slots := rack.Slots()
+	effects := make([]map[string]interface{}, len(slots))
+	for i, slot := range slots {
+		params := make(map[string]interface{})
+		for _, p := range slot.Effect.Params() {
+			params[p.Name] = p.Get()
+		}
+		effects[i] = map[string]interface{}{
+			"Name":    slot.Effect.Name(),
+			"Enabled": slot.IsEnabled(),
+			"Mix":     slot.GetMix(),
+			"Params":  params,
+			"Clamped": slot.Clamped(), // つまみの値どおりにかけられず、縮めているか（エコーの遅れが長すぎるなど）
+		}
+	}
+	return effects
+}
+
+// getMeterStatus はメーターの値を取得（Peak/RMS は dBFS、Momentary/ShortTerm は LUFS）
+func getMeterStatus(meter *audio.Meter) map[string]interface{} {
+	reading := meter.Reading()
+	return map[string]interface{}{
+		"Peak":      reading.Peak,
+		"RMS":       reading.RMS,
+		"Momentary": reading.Momentary,
+		"ShortTerm": reading.ShortTerm,
+	}
+}
+
+// getStripStatus はチャンネルストリップの状態を取得
+func (m *DJMixer) getStripStatus(strip *ChannelStrip) map[string]interface{} {
+	return map[string]interface{}{
+		"Trim":       strip.GetTrim(),
+		"Fader":      strip.GetFader(),
+		"Crossfader": strip.GetAssign().String(),
+		"PFL":        strip.GetPFL(),
+	}
+}
+
+// getBeatGridStatus はビートグリッド情報を取得（解析前は nil）
+func (m *DJMixer) getBeatGridStatus(deck *audio.Track) map[string]interface{} {
+	grid := deck.GetBeatGrid()
+	if grid == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"BPM":           grid.BPM,
+		"FirstBeat":     grid.FirstBeat,
+		"FirstDownbeat": grid.FirstDownbeat,
+		"BeatsPerBar":   grid.BeatsPerBar,
+	}
+}
+
 // getCuePointsStatus はキューポイント情報を取得
 func (m *DJMixer) getCuePointsStatus(deck *audio.Track) []map[string]interface{} {
 	cuePoints := make([]map[string]interface{}, 0)
 
-	for i := 0; i < deck.CueManager.GetCuePointCount(); i++ {
-		cue := deck.CueManager.GetCuePoint(i)
-		if cue != nil {
-			// 💡 修正: JSONキーをPascalCaseに統一
-			cuePoints = append(cuePoints, map[string]interface{}{
-				"Name":     cue.Name,
-				"Position": cue.Position,
-				"Color":    cue.Color,
-			})
-		}
+	for _, cue := range deck.CueManager.GetCuePoints() {
+		// 💡 修正: JSONキーをPascalCaseに統一
+		cuePoints = append(cuePoints, map[string]interface{}{
+			"Name":     cue.Name,
+			"Position": cue.Position,
+			"Color":    cue.Color,
+		})
 	}
 
 	return cuePoints
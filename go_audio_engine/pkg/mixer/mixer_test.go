@@ -0,0 +1,554 @@
+package mixer
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"go_audio_engine/pkg/audio"
+)
+
+// testdataDir はテスト用の音声ファイルの場所（generate-test-wav.js で作成）
+const testdataDir = "../../testdata"
+
+// mixFrames は1回の Mix のフレーム数（サウンドカードの1ブロックくらい）
+const mixFrames = 512
+
+// waitForTracks はすべてのデッキに want 秒の曲が載るまで Mix を回しながら待つ
+func waitForTracks(t testing.TB, m *DJMixer, want float64, mix func()) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		done := true
+		for i := range m.Decks {
+			if math.Abs(m.Deck(DeckID(i)).GetDuration()-want) > 0.01 {
+				done = false
+			}
+		}
+		if done {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("tracks were not loaded within 10 seconds")
+		}
+		if mix != nil {
+			mix()
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestConcurrentLoadAndMix はオーディオスレッドが Mix を回している間に、
+// 曲のロードと API からの操作を並行して行っても安全なことを確認する（go test -race で実行すること）
+func TestConcurrentLoadAndMix(t *testing.T) {
+	// 💡 16秒のクリック音はストリーミング、5秒のトーンはメモリに読み込む（両方の閉じ方を通す）
+	defer func(threshold float64) { audio.StreamingThreshold = threshold }(audio.StreamingThreshold)
+	audio.StreamingThreshold = 10
+
+	m := NewDJMixer(44100, 4)
+	files := []string{"tone_261hz.wav", "tone_440hz.wav", "tone_523hz.wav", "click_128bpm.wav"}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+
+	// オーディオスレッド
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		out := make([]float32, mixFrames*2)
+		cue := make([]float32, mixFrames*2)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			m.MixWithCue(out, cue)
+			for _, v := range out {
+				if math.IsNaN(float64(v)) || math.Abs(float64(v)) > 1 {
+					t.Errorf("master output %v is out of range", v)
+					return
+				}
+			}
+		}
+	}()
+
+	// API のスレッド：ロード中のデッキを操作し続ける
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			id := DeckID(i % len(m.Decks))
+			deck := m.Deck(id)
+			deck.Play()
+			deck.SetSpeed(0.9 + float64(i%20)/100)
+			deck.Seek(float64(i%4) * 0.5)
+			deck.EQ.SetLow(float64(i%24 - 12))
+			deck.Effects.Slots()[i%len(deck.Effects.Slots())].SetEnabled(i%2 == 0)
+			_ = deck.GetPosition()
+			_, _ = deck.StreamStats()
+			m.Strip(id).SetPFL(i%3 == 0)
+			m.SetCrossfader(float64(i%21-10) / 10)
+			_ = m.GetStatus()
+			time.Sleep(100 * time.Microsecond)
+		}
+	}()
+
+	for round := 0; round < 3; round++ {
+		for i := range m.Decks {
+			m.LoadTrackAsync(DeckID(i), testdataDir+"/"+files[(i+round)%len(files)])
+		}
+	}
+	// 最後のロード（1秒の Ogg）がすべてのデッキに載るまで待つ
+	for i := range m.Decks {
+		m.LoadTrackAsync(DeckID(i), testdataDir+"/vorbis_mono.ogg")
+	}
+	waitForTracks(t, m, 1, nil)
+
+	close(stop)
+	wg.Wait()
+}
+
+// BenchmarkMix は4デッキを再生しているときの Mix の処理時間（1ブロック）
+func BenchmarkMix(b *testing.B) {
+	m := NewDJMixer(44100, 4)
+	for i := range m.Decks {
+		m.LoadTrackAsync(DeckID(i), testdataDir+"/tone_440hz.wav")
+	}
+	out := make([]float32, mixFrames*2)
+	cue := make([]float32, mixFrames*2)
+	waitForTracks(b, m, 5, func() { m.MixWithCue(out, cue) })
+	for i := range m.Decks {
+		m.Deck(DeckID(i)).Play()
+	}
+	m.MixWithCue(out, cue) // 作業バッファの確保を済ませておく
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.MixWithCue(out, cue)
+	}
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N)/(mixFrames/44100.0*1e9)*100, "%realtime")
+}
+
+// TestMixDoesNotAllocate は BPM 解析が終わって同期している最中も、MixWithCue がメモリを確保しないことを確認する
+// （オーディオスレッドで GC を起こさない）。マスターは最高速度 2.0 で、一番広い補間（sinc）の窓を読む
+// 💡 ブロックの長さは普段の 512 フレームと、受け付ける最大の audio.MaxBlockFrames の両方
+func TestMixDoesNotAllocate(t *testing.T) {
+	m := NewDJMixer(44100, 2)
+	m.LoadTrackAsync(DeckA, testdataDir+"/click_128bpm.wav")
+	m.LoadTrackAsync(DeckB, testdataDir+"/click_174bpm.wav")
+	out := make([]float32, audio.MaxBlockFrames*2)
+	cue := make([]float32, audio.MaxBlockFrames*2)
+	waitForTracks(t, m, 16, func() { m.MixWithCue(out[:mixFrames*2], cue[:mixFrames*2]) })
+	for deadline := time.Now().Add(30 * time.Second); m.Deck(DeckA).GetBeatGrid() == nil || m.Deck(DeckB).GetBeatGrid() == nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("BPM analysis did not finish within 30 seconds")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	master, slave := m.Deck(DeckA), m.Deck(DeckB)
+	if err := m.EnableSync(true, "a"); err != nil {
+		t.Fatal(err)
+	}
+	master.SetSpeed(2.0)
+	if got := master.GetInterpolation(); got != audio.InterpolationSinc {
+		t.Fatalf("interpolation = %v, want sinc", got)
+	}
+	slave.Seek(0.1) // 拍をずらして、位相を合わせる速度の調整も働かせる
+	m.Decks[DeckB].Strip.SetPFL(true)
+	master.Play()
+	slave.Play()
+	for i := 0; i < 10; i++ {
+		m.MixWithCue(out[:mixFrames*2], cue[:mixFrames*2])
+	}
+	if _, speed, _ := slave.Playhead(); speed == 1 {
+		t.Fatalf("slave speed = %v, want synced to the master", speed)
+	}
+
+	// 💡 AllocsPerRun は1回空回ししてから数えるので、最初の長いブロックは別に数える（作業バッファを後から広げていない）
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	m.MixWithCue(out, cue)
+	runtime.ReadMemStats(&after)
+	if allocs := after.Mallocs - before.Mallocs; allocs != 0 {
+		t.Errorf("first %d-frame block allocated %d times, want 0", audio.MaxBlockFrames, allocs)
+	}
+	for _, frames := range []int{mixFrames, audio.MaxBlockFrames} {
+		if allocs := testing.AllocsPerRun(20, func() { m.MixWithCue(out[:frames*2], cue[:frames*2]) }); allocs != 0 {
+			t.Errorf("%d frames: MixWithCue allocated %v times per block, want 0", frames, allocs)
+		}
+	}
+	if _, _, playing := master.Playhead(); !playing {
+		t.Error("master stopped before the end of the test")
+	}
+}
+
+// TestLoadKeepsDeckSettings は曲をロードし直しても、デッキの EQ・フィルター・ピッチシフト・エフェクトの設定が残ることを確認する
+func TestLoadKeepsDeckSettings(t *testing.T) {
+	m := NewDJMixer(44100, 1)
+	out := make([]float32, mixFrames*2)
+	mix := func() { m.Mix(out) }
+
+	deck := m.Deck(DeckA)
+	deck.PitchShift.SetSemitones(-3)
+	deck.Filter.SetColor(0.4)
+	deck.Filter.SetResonance(0.7)
+	deck.EQ.SetMode(audio.EQIsolator)
+	if err := deck.EQ.SetCrossovers(150, 3000); err != nil {
+		t.Fatal(err)
+	}
+	deck.EQ.SetLow(-1)
+	if err := deck.Effects.SetOrder([]string{"reverb", "echo", "flanger", "phaser", "bitcrusher"}); err != nil {
+		t.Fatal(err)
+	}
+	echo, err := deck.Effects.Slot("echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	echo.SetEnabled(true)
+	echo.SetMix(0.3)
+	echo.Param("beats").Set(0.5)
+
+	m.LoadTrackAsync(DeckA, testdataDir+"/tone_440hz.wav")
+	waitForTracks(t, m, 5, mix)
+	loaded := m.Deck(DeckA)
+	if loaded == deck {
+		t.Fatal("track was not replaced")
+	}
+
+	if got := loaded.PitchShift.GetSemitones(); got != -3 {
+		t.Errorf("pitch shift = %v, want -3", got)
+	}
+	if got := loaded.Filter.Settings(); got.Color != 0.4 || got.Resonance != 0.7 {
+		t.Errorf("filter = %+v, want color 0.4 resonance 0.7", got)
+	}
+	if got := loaded.EQ.GetMode(); got != audio.EQIsolator {
+		t.Errorf("EQ mode = %v, want isolator", got)
+	}
+	if low, high := loaded.EQ.GetCrossovers(); math.Abs(low-150) > 0.01 || math.Abs(high-3000) > 0.01 {
+		t.Errorf("EQ crossovers = %v, %v, want 150, 3000", low, high)
+	}
+	if got := loaded.EQ.GetLow(); got != -1 {
+		t.Errorf("EQ low = %v, want -1", got)
+	}
+	if got := loaded.Effects.Slots()[0].Effect.Name(); got != "reverb" {
+		t.Errorf("first effect = %s, want reverb", got)
+	}
+	slot, _ := loaded.Effects.Slot("echo")
+	if !slot.IsEnabled() || slot.GetMix() != 0.3 || slot.Param("beats").Get() != 0.5 {
+		t.Errorf("echo = enabled %v mix %v beats %v, want true 0.3 0.5",
+			slot.IsEnabled(), slot.GetMix(), slot.Param("beats").Get())
+	}
+}
+
+// writeClickTrack は generate-test-wav.js の generateClickTrack と同じクリック音の WAV を作る
+// （offset 秒から1拍ごとに鳴り、4拍ごとの小節頭は高く大きい音）
+// 💡 testdata のクリック音は16秒なので、30秒以上の同期の確認にはここで長いものを作る
+func writeClickTrack(t *testing.T, bpm, offset, seconds float64) string {
+	t.Helper()
+	const sampleRate = 44100
+	samples := make([]float32, int(seconds*sampleRate))
+	for beat := 0; ; beat++ {
+		start := int(math.Round((offset + float64(beat)*60/bpm) * sampleRate))
+		if start >= len(samples) {
+			break
+		}
+		freq, amplitude := 1000.0, 0.4
+		if beat%4 == 0 {
+			freq, amplitude = 1500, 0.8
+		}
+		for i := 0; i < sampleRate*3/100 && start+i < len(samples); i++ {
+			tt := float64(i) / sampleRate
+			samples[start+i] = float32(amplitude * math.Exp(-tt*150) * math.Sin(2*math.Pi*freq*tt))
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "click.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := audio.NewWAVWriter(f, sampleRate, 1, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(samples); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestSyncLocksBeatPhase は同期をオンにすると、スレーブの拍がマスターの拍に揃い、そのまま揃い続けることを確認する
+// 拍の位置はクリック音を作ったときの本当のテンポと位置で測る（BPM 解析の誤差もドリフトとして補正されるはず）
+// ハーフ/ダブルタイムで、ピッチフェーダーの範囲（0.5 ～ 2.0）を超えるテンポ比でも合うことも確認する
+func TestSyncLocksBeatPhase(t *testing.T) {
+	const (
+		sampleRate = 44100
+		settle     = 5.0   // 位相が揃うまでの猶予（秒）
+		tolerance  = 0.003 // 揃った後の拍のずれ（秒）
+	)
+
+	type click struct{ bpm, offset float64 }
+	tests := []struct {
+		name          string
+		master, slave click
+		masterSpeed   float64
+		slaveStart    float64 // スレーブの再生開始位置（秒、わざと拍をずらす）
+		multiple      float64 // マスター1拍あたりのスレーブの拍数
+		seconds       float64
+	}{
+		{"128 <- 93.5", click{128, 0.25}, click{93.5, 0.37}, 1, 0.3, 1, 30},
+		// マスター 348BPM（174 × 2.0）に 93.5BPM：比は 3.7 倍なので 1/4 拍で合わせる
+		{"174 x2.0 <- 93.5", click{174, 0.1}, click{93.5, 0.37}, 2, 1.1, 0.25, 15},
+		// マスター 64BPM（128 × 0.5）に 174BPM：比は 0.37 倍なので 2 拍で合わせる
+		{"128 x0.5 <- 174", click{128, 0.25}, click{174, 0.1}, 0.5, 0.05, 2, 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seconds := 45.0 // 1.37 倍速のスレーブでも30秒以上鳴る長さ
+			m := NewDJMixer(sampleRate, 2)
+			m.LoadTrackAsync(DeckA, writeClickTrack(t, tt.master.bpm, tt.master.offset, seconds))
+			m.LoadTrackAsync(DeckB, writeClickTrack(t, tt.slave.bpm, tt.slave.offset, seconds))
+			out := make([]float32, mixFrames*2)
+			mix := func() { m.Mix(out) }
+			waitForTracks(t, m, seconds, mix)
+			for deadline := time.Now().Add(30 * time.Second); m.Deck(DeckA).GetBeatGrid() == nil || m.Deck(DeckB).GetBeatGrid() == nil; {
+				if time.Now().After(deadline) {
+					t.Fatal("BPM analysis did not finish within 30 seconds")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			master, slave := m.Deck(DeckA), m.Deck(DeckB)
+			if err := m.EnableSync(true, "a"); err != nil {
+				t.Fatal(err)
+			}
+			master.SetSpeed(tt.masterSpeed)
+			slave.Seek(tt.slaveStart)
+			master.Play()
+			slave.Play()
+
+			initial, worst := 0.0, 0.0
+			for block := 0; block < int(tt.seconds*sampleRate/mixFrames); block++ {
+				mix()
+				masterPos, _, _ := master.Playhead()
+				slavePos, slaveSpeed, playing := slave.Playhead()
+				if !playing {
+					t.Fatalf("slave stopped at %.2f s", slavePos)
+				}
+				if slaveSpeed < 0.5 || slaveSpeed > 2 {
+					t.Fatalf("slave speed %v outside 0.5 - 2.0", slaveSpeed)
+				}
+
+				// 拍のずれ（スレーブの拍数）を、聞こえる時間（秒）に直す
+				masterBeat := (masterPos - tt.master.offset) * tt.master.bpm / 60
+				slaveBeat := (slavePos - tt.slave.offset) * tt.slave.bpm / 60
+				diff := slaveBeat - masterBeat*tt.multiple
+				diff -= math.Round(diff)
+				errSeconds := math.Abs(diff) * 60 / tt.slave.bpm / slaveSpeed
+
+				elapsed := float64(block*mixFrames) / sampleRate
+				if block == 0 {
+					initial = errSeconds
+				}
+				if elapsed >= settle {
+					worst = math.Max(worst, errSeconds)
+				}
+			}
+			if initial < 0.02 {
+				t.Errorf("beats were only %.2f ms apart at the start, want a real offset to correct", initial*1000)
+			}
+			if worst > tolerance {
+				t.Errorf("beat offset after %.0f s: up to %.2f ms, want within %.0f ms", settle, worst*1000, tolerance*1000)
+			}
+
+			// 同期の速度はスレーブのテンポをマスターのテンポ × multiple に合わせている
+			_, slaveSpeed, _ := slave.Playhead()
+			want := tt.master.bpm * tt.masterSpeed * tt.multiple / tt.slave.bpm
+			if math.Abs(slaveSpeed-want) > want*0.002 {
+				t.Errorf("slave speed = %v, want %v", slaveSpeed, want)
+			}
+			if got := slave.GetSpeed(); got != 1 {
+				t.Errorf("slave pitch fader = %v, want untouched 1.0", got)
+			}
+		})
+	}
+}
+
+// TestStatusMeters は GetStatus がデッキごととマスターのメーターの値を公開することを確認する
+func TestStatusMeters(t *testing.T) {
+	m := NewDJMixer(44100, 2)
+	out := make([]float32, mixFrames*2)
+	mix := func() { m.Mix(out) }
+	m.LoadTrackAsync(DeckA, testdataDir+"/tone_440hz.wav")
+	m.LoadTrackAsync(DeckB, testdataDir+"/tone_440hz.wav")
+	waitForTracks(t, m, 5, mix)
+
+	// A だけ再生（0.5秒：モーメンタリーの 400ms が埋まる長さ）
+	m.Deck(DeckA).Play()
+	for i := 0; i < 44100/2/mixFrames; i++ {
+		mix()
+	}
+
+	status := m.GetStatus()
+	meter := func(v interface{}) map[string]interface{} { return v.(map[string]interface{}) }
+	deckA := meter(status["Decks"].([]map[string]interface{})[0]["Meter"])
+	deckB := meter(status["DeckB"].(map[string]interface{})["Meter"])
+	master := meter(status["MasterMeter"])
+
+	// tone_440hz.wav は振幅 0.3（-10.46dBFS）。チャンネルのメーターはフェーダーの前なので、そのままの値
+	if peak := deckA["Peak"].(float64); math.Abs(peak-20*math.Log10(0.3)) > 0.1 {
+		t.Errorf("deck A peak = %.2f dBFS, want %.2f", peak, 20*math.Log10(0.3))
+	}
+	for _, key := range []string{"RMS", "Momentary", "ShortTerm"} {
+		if v := deckA[key].(float64); v <= audio.MeterFloorDB || v > 0 {
+			t.Errorf("deck A %s = %v, want a reading", key, v)
+		}
+	}
+	for _, key := range []string{"Peak", "RMS", "Momentary", "ShortTerm"} {
+		if v := deckB[key].(float64); v != audio.MeterFloorDB {
+			t.Errorf("deck B (stopped) %s = %v, want %v", key, v, audio.MeterFloorDB)
+		}
+	}
+	if peak := master["Peak"].(float64); peak <= audio.MeterFloorDB || peak > deckA["Peak"].(float64) {
+		t.Errorf("master peak = %.2f dBFS, want a reading no louder than deck A (%.2f)", peak, deckA["Peak"])
+	}
+}
+
+// toneLevel はステレオ・インターリーブの左チャンネルに含まれる freq（Hz）の成分の振幅
+func toneLevel(stereo []float32, freq float64, sampleRate int) float64 {
+	var re, im float64
+	frames := len(stereo) / 2
+	for i := 0; i < frames; i++ {
+		angle := 2 * math.Pi * freq * float64(i) / float64(sampleRate)
+		re += float64(stereo[i*2]) * math.Cos(angle)
+		im += float64(stereo[i*2]) * math.Sin(angle)
+	}
+	return 2 * math.Hypot(re, im) / float64(frames)
+}
+
+// TestCueBus はヘッドフォン（キュー）出力をオフラインでバッファに書き出して確認する
+// A に 261Hz、B に 523Hz（どちらも振幅 0.3）を載せ、B だけ PFL をオンにする
+func TestCueBus(t *testing.T) {
+	const (
+		sampleRate = 44100
+		settle     = sampleRate / 5 // つまみのなめらかな動きが終わるまで捨てる
+		measure    = sampleRate / 2
+		level      = 0.3
+		lowFreq    = 261.0
+		highFreq   = 523.0
+	)
+
+	// setup は A と B を載せたミキサーを作り、configure で設定してから再生して、マスターとキューを返す
+	setup := func(t *testing.T, configure func(m *DJMixer)) (master, cue []float32) {
+		t.Helper()
+		m := NewDJMixer(sampleRate, 2)
+		out := make([]float32, mixFrames*2)
+		cueOut := make([]float32, mixFrames*2)
+		m.LoadTrackAsync(DeckA, testdataDir+"/tone_261hz.wav")
+		m.LoadTrackAsync(DeckB, testdataDir+"/tone_523hz.wav")
+		waitForTracks(t, m, 5, func() { m.MixWithCue(out, cueOut) })
+
+		configure(m)
+		for frames := 0; frames < settle+measure; frames += mixFrames {
+			m.MixWithCue(out, cueOut)
+			if frames >= settle {
+				master = append(master, out...)
+				cue = append(cue, cueOut...)
+			}
+		}
+		return master, cue
+	}
+
+	t.Run("PFL with fader down", func(t *testing.T) {
+		master, cue := setup(t, func(m *DJMixer) {
+			m.Strip(DeckB).SetFader(0)
+			m.Strip(DeckB).SetPFL(true)
+			m.Deck(DeckB).Play()
+		})
+		for i, v := range master {
+			if v != 0 {
+				t.Fatalf("master sample %d = %v, want silence (B's fader is down)", i, v)
+			}
+		}
+		if got := toneLevel(cue, highFreq, sampleRate); math.Abs(got-level) > 0.005 {
+			t.Errorf("cue level of B = %.4f, want %.4f", got, level)
+		}
+	})
+
+	t.Run("cue mix", func(t *testing.T) {
+		for _, cueMix := range []float64{0, 1} {
+			master, cue := setup(t, func(m *DJMixer) {
+				m.Strip(DeckB).SetFader(0)
+				m.Strip(DeckB).SetPFL(true)
+				m.SetCueMix(cueMix)
+				m.Deck(DeckA).Play()
+				m.Deck(DeckB).Play()
+			})
+			a, b := toneLevel(cue, lowFreq, sampleRate), toneLevel(cue, highFreq, sampleRate)
+			switch cueMix {
+			case 0: // キューだけ（PFL の B だけが聞こえる）
+				if a > 0.001 || math.Abs(b-level) > 0.005 {
+					t.Errorf("cue mix 0: A %.4f B %.4f, want A 0 B %.4f", a, b, level)
+				}
+			case 1: // マスターだけ（ヘッドフォンの音量 1.0 ならマスター出力と同じ）
+				for i := range master {
+					if cue[i] != master[i] {
+						t.Fatalf("cue mix 1: cue sample %d = %v, want master %v", i, cue[i], master[i])
+					}
+				}
+				if a < 0.1 || b > 0.001 {
+					t.Errorf("cue mix 1: A %.4f B %.4f, want A audible and B 0", a, b)
+				}
+			}
+		}
+	})
+
+	t.Run("headphone volume", func(t *testing.T) {
+		for _, volume := range []float64{1, 0.5, 0} {
+			_, cue := setup(t, func(m *DJMixer) {
+				m.Strip(DeckB).SetPFL(true)
+				m.SetHeadphoneVolume(volume)
+				m.Deck(DeckB).Play()
+			})
+			if got := toneLevel(cue, highFreq, sampleRate); math.Abs(got-level*volume) > 0.005 {
+				t.Errorf("headphone volume %v: cue level %.4f, want %.4f", volume, got, level*volume)
+			}
+		}
+	})
+
+	t.Run("master unaffected by PFL", func(t *testing.T) {
+		play := func(pfl bool) func(m *DJMixer) {
+			return func(m *DJMixer) {
+				m.Strip(DeckA).SetPFL(pfl)
+				m.Strip(DeckB).SetPFL(pfl)
+				m.Strip(DeckB).SetFader(0.5)
+				m.Deck(DeckA).Play()
+				m.Deck(DeckB).Play()
+			}
+		}
+		without, _ := setup(t, play(false))
+		with, _ := setup(t, play(true))
+		for i := range without {
+			if with[i] != without[i] {
+				t.Fatalf("master sample %d = %v with PFL, %v without", i, with[i], without[i])
+			}
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package output
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Clock はサウンドカードの代わりにブロックごとに RenderFunc を呼ぶ
+// Realtime なら実時間に合わせて待ち、そうでなければできるだけ速く回す
+type Clock struct {
+	SampleRate      int
+	FramesPerBuffer int
+	Realtime        bool
+}
+
+// Run は render で作ったマスターを write に渡すことを繰り返す
+// ctx が終わるか、frames フレーム（0 以下なら無制限）に達するか、write がエラーを返すと終わる
+// progress には作ったフレーム数を随時書き込む（nil なら書かない）
+func (c Clock) Run(ctx context.Context, frames int64, render RenderFunc, write func(master []float32) error, progress *atomic.Int64) (int64, error) {
+	buffer := make([]float32, c.FramesPerBuffer*2)
+	start := time.Now()
+	var rendered int64
+
+	for frames <= 0 || rendered < frames {
+		select {
+		case <-ctx.Done():
+			return rendered, nil
+		default:
+		}
+
+		block := buffer
+		if frames > 0 && frames-rendered < int64(c.FramesPerBuffer) {
+			block = buffer[:(frames-rendered)*2]
+		}
+		render(block, nil)
+		if err := write(block); err != nil {
+			return rendered, err
+		}
+		rendered += int64(len(block) / 2)
+		if progress != nil {
+			progress.Store(rendered)
+		}
+
+		// 実時間モードでは、作った長さの分だけ時間が経つまで待つ
+		if c.Realtime {
+			due := start.Add(time.Duration(float64(rendered) / float64(c.SampleRate) * float64(time.Second)))
+			if wait := time.Until(due); wait > 0 {
+				select {
+				case <-ctx.Done():
+					return rendered, nil
+				case <-time.After(wait):
+				}
+			}
+		}
+	}
+	return rendered, nil
+}
+
+// clockRunner は Clock で回すバックエンドの共通部分
+type clockRunner struct {
+	clock    Clock
+	frames   int64 // 作るフレーム数（0 以下なら Stop まで）
+	rendered atomic.Int64
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// start は別のゴルーチンで Clock を回し始める
+func (r *clockRunner) start(render RenderFunc, write func(master []float32) error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	go func() {
+		defer close(r.done)
+		_, r.err = r.clock.Run(ctx, r.frames, render, write, &r.rendered)
+	}()
+}
+
+// stop は Clock を止めて、終わるまで待つ
+func (r *clockRunner) stop() error {
+	if r.cancel == nil {
+		return nil
+	}
+	r.cancel()
+	<-r.done
+	return r.err
+}
+
+// Wait は指定したフレーム数を作り終わるまで待つ（frames が 0 以下なら Stop されるまで）
+func (r *clockRunner) Wait() error {
+	if r.done == nil {
+		return nil
+	}
+	<-r.done
+	return r.err
+}
+
+// Seconds は作った音の長さ（秒）
+func (r *clockRunner) Seconds() float64 {
+	return float64(r.rendered.Load()) / float64(r.clock.SampleRate)
+}
+
+// clockStatus は Clock で回すバックエンドに共通の状態
+func (r *clockRunner) clockStatus(backend string) map[string]interface{} {
+	return map[string]interface{}{
+		"backend":         backend,
+		"sampleRate":      r.clock.SampleRate,
+		"framesPerBuffer": r.clock.FramesPerBuffer,
+		"realtime":        r.clock.Realtime,
+		"seconds":         r.Seconds(),
+	}
+}
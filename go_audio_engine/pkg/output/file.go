@@ -0,0 +1,84 @@
+package output
+
+import (
+	"fmt"
+	"os"
+
+	"go_audio_engine/pkg/audio"
+)
+
+// FileBackend はマスターを WAV ファイルに書き出す
+type FileBackend struct {
+	Path     string
+	BitDepth int // 16, 24 または 32（float）
+
+	clockRunner
+	file   *os.File
+	writer *audio.WAVWriter
+}
+
+// NewFileBackend はファイル出力を作成
+// frames は書き出すフレーム数（0 以下なら Stop まで書き続ける）
+func NewFileBackend(path string, bitDepth int, clock Clock, frames int64) *FileBackend {
+	return &FileBackend{
+		Path:        path,
+		BitDepth:    bitDepth,
+		clockRunner: clockRunner{clock: clock, frames: frames},
+	}
+}
+
+// Start はファイルを作成して書き出しを始める
+func (b *FileBackend) Start(render RenderFunc) error {
+	file, err := os.Create(b.Path)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
+	}
+	writer, err := audio.NewWAVWriter(file, b.clock.SampleRate, 2, b.BitDepth)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	b.file = file
+	b.writer = writer
+	b.start(render, writer.Write)
+	return nil
+}
+
+// Stop は書き出しを止めて、ファイルを閉じる
+func (b *FileBackend) Stop() error {
+	err := b.stop()
+	if b.file == nil {
+		return err
+	}
+	if closeErr := b.writer.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := b.file.Close(); err == nil {
+		err = closeErr
+	}
+	b.file = nil
+	return err
+}
+
+// Status は出力先の状態を返す
+func (b *FileBackend) Status() map[string]interface{} {
+	status := b.clockStatus("file")
+	status["path"] = b.Path
+	status["bitDepth"] = b.BitDepth
+	return status
+}
+
+// RenderToFile は render の音を frames フレーム分、実時間を待たずに WAV ファイルに書き出す
+// 💡 回帰テスト用：デッキを用意してから呼べば、ミックス全体を決まった結果として残せる
+func RenderToFile(path string, render RenderFunc, sampleRate, bitDepth int, frames int64) error {
+	b := NewFileBackend(path, bitDepth, Clock{SampleRate: sampleRate, FramesPerBuffer: 512}, frames)
+	if err := b.Start(render); err != nil {
+		return err
+	}
+	err := b.Wait()
+	if stopErr := b.Stop(); err == nil {
+		err = stopErr
+	}
+	return err
+}
@@ -0,0 +1,152 @@
+package output
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go_audio_engine/pkg/audio"
+)
+
+// testdataDir はテスト用の音声ファイルの場所（generate-test-wav.js で作成）
+const testdataDir = "../../testdata"
+
+// fixtureMix は 440Hz と 261Hz のトーンを2つのデッキで同時に鳴らしたミックスを返す
+// 💡 呼ぶたびに頭から鳴らし直すので、同じ長さだけ回せば毎回同じ音になる
+func fixtureMix(t *testing.T, sampleRate int) RenderFunc {
+	t.Helper()
+	var tracks []*audio.Track
+	for _, name := range []string{"tone_440hz.wav", "tone_261hz.wav"} {
+		track := audio.NewTrack(sampleRate)
+		if err := track.LoadWithMode(testdataDir+"/"+name, audio.LoadMemory); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { track.Close() })
+		track.Play()
+		tracks = append(tracks, track)
+	}
+
+	deck := make([]float32, 0, 512*2)
+	return func(master, cue []float32) {
+		clear(master)
+		for _, track := range tracks {
+			deck = deck[:len(master)]
+			track.ReadSamples(deck)
+			for i, v := range deck {
+				master[i] += 0.5 * v
+			}
+		}
+	}
+}
+
+// checksum はサンプルのビット列の CRC32
+func checksum(samples []float32) uint32 {
+	buf := make([]byte, len(samples)*4)
+	for i, v := range samples {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+	}
+	return crc32.ChecksumIEEE(buf)
+}
+
+// readWAV は WAV ファイルを読み込んで、サンプルレート・チャンネル数・サンプルを返す
+func readWAV(t *testing.T, path string) (int, int, []float32) {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	stream, _, err := audio.OpenStream(path, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := audio.ReadAll(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stream.SampleRate(), stream.Channels(), samples
+}
+
+// TestRenderToFile はミックスを実時間より速く WAV に書き出し、フレーム数と中身が
+// ミキサーを直接回した結果と一致することを確認する
+func TestRenderToFile(t *testing.T) {
+	const (
+		sampleRate = 44100
+		seconds    = 3
+		frames     = sampleRate*seconds + 100 // ブロック（512 フレーム）の途中で終わる長さ
+	)
+
+	path := filepath.Join(t.TempDir(), "mix.wav")
+	started := time.Now()
+	if err := RenderToFile(path, fixtureMix(t, sampleRate), sampleRate, 32, frames); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(started); elapsed >= seconds*time.Second {
+		t.Errorf("rendering %d seconds took %v, want faster than real time", seconds, elapsed)
+	}
+
+	rate, channels, got := readWAV(t, path)
+	if rate != sampleRate || channels != 2 {
+		t.Fatalf("file format = %d Hz %dch, want %d Hz 2ch", rate, channels, sampleRate)
+	}
+	if len(got)/2 != frames {
+		t.Fatalf("file has %d frames, want %d", len(got)/2, frames)
+	}
+
+	// 同じミックスを Clock を通さずに 512 フレームずつ回したもの（32bit float なので一致するはず）
+	want := make([]float32, frames*2)
+	render := fixtureMix(t, sampleRate)
+	for start := 0; start < len(want); start += 512 * 2 {
+		render(want[start:min(start+512*2, len(want))], nil)
+	}
+	if level := rmsLevel(want); level < 0.1 {
+		t.Fatalf("reference mix is nearly silent (RMS %v)", level)
+	}
+	if sum, wantSum := checksum(got), checksum(want); sum != wantSum {
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("checksum %08x, want %08x: sample %d = %v, want %v", sum, wantSum, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+// rmsLevel はサンプルの RMS
+func rmsLevel(samples []float32) float64 {
+	var sum float64
+	for _, v := range samples {
+		sum += float64(v) * float64(v)
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
+
+// TestFileBackendStop は長さを決めずに回したファイル出力を Stop したとき、
+// それまでに作ったフレームがすべて、読める WAV として残ることを確認する
+func TestFileBackendStop(t *testing.T) {
+	const sampleRate = 48000
+	path := filepath.Join(t.TempDir(), "mix.wav")
+	backend := NewFileBackend(path, 16, Clock{SampleRate: sampleRate, FramesPerBuffer: 256}, 0)
+	if err := backend.Start(fixtureMix(t, sampleRate)); err != nil {
+		t.Fatal(err)
+	}
+	for backend.Seconds() < 0.5 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := backend.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	status := backend.Status()
+	if status["backend"] != "file" || status["path"] != path || status["bitDepth"] != 16 {
+		t.Errorf("status = %v", status)
+	}
+	_, _, got := readWAV(t, path)
+	want := int(status["seconds"].(float64)*sampleRate + 0.5)
+	if len(got)/2 != want || want%256 != 0 {
+		t.Errorf("file has %d frames, want %d (whole blocks of 256)", len(got)/2, want)
+	}
+}
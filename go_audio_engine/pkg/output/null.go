@@ -0,0 +1,28 @@
+package output
+
+// NullBackend は音をどこにも出さない出力
+// 💡 サウンドカードのないサーバーで、API だけ動かしたいときに使う（Realtime にすれば再生位置は実時間で進む）
+type NullBackend struct {
+	clockRunner
+}
+
+// NewNullBackend は無音出力を作成
+func NewNullBackend(clock Clock) *NullBackend {
+	return &NullBackend{clockRunner: clockRunner{clock: clock}}
+}
+
+// Start はミキサーを回し始める（音は捨てる）
+func (b *NullBackend) Start(render RenderFunc) error {
+	b.start(render, func(master []float32) error { return nil })
+	return nil
+}
+
+// Stop はミキサーを止める
+func (b *NullBackend) Stop() error {
+	return b.stop()
+}
+
+// Status は出力先の状態を返す
+func (b *NullBackend) Status() map[string]interface{} {
+	return b.clockStatus("null")
+}
@@ -0,0 +1,24 @@
+package output
+
+// 出力バックエンド
+//
+// 解説：ミキサーの音をどこに出すか（サウンドカード・WAVファイル・どこにも出さない）を切り替える。
+// サウンドカードならそのコールバックが、それ以外なら Clock が、一定のブロックごとに RenderFunc を呼ぶ。
+// 💡 ファイル出力は実時間より速く回せるので、サウンドカードのない CI でもミックス全体を書き出して確認できる
+
+// RenderFunc はミキサーから1ブロック分の音を取り出す
+// master と cue はステレオ・インターリーブで同じ長さ。cue が nil ならキューは作らない
+// （mixer.DJMixer の MixWithCue をそのまま渡せる）
+type RenderFunc func(master, cue []float32)
+
+// Backend は音の出力先
+type Backend interface {
+	// Start は render を呼び始める
+	Start(render RenderFunc) error
+
+	// Stop は render の呼び出しを止めて、出力先を閉じる
+	Stop() error
+
+	// Status は出力先の状態を返す
+	Status() map[string]interface{}
+}
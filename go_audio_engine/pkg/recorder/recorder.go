@@ -0,0 +1,499 @@
+package recorder
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go_audio_engine/pkg/audio"
+)
+
+// 録音（マスター出力をファイルに書き出す）
+//
+// 解説：オーディオスレッドは Write でリングバッファに入れるだけ（ロックもディスクI/Oもしない）。
+// 別のゴルーチンが定期的にリングバッファから取り出して、WAV / FLAC に書き出す。
+// ディスクが遅れてリングバッファがいっぱいなら、そのブロックは捨てて数を数えておく
+const (
+	bufferSeconds = 4                     // リングバッファにためられる長さ
+	flushInterval = 50 * time.Millisecond // 書き出しゴルーチンがリングバッファを見る間隔
+	channels      = 2
+
+	minSplitBytes    = 1 << 20 // 分割の大きさの下限（1MB）
+	minSplitDuration = time.Second
+
+	defaultBitDepth = 24
+	defaultDir      = "recordings"
+)
+
+// Format は録音ファイルの形式
+type Format int
+
+const (
+	FormatWAV  Format = iota // 16/24bit 整数 または 32bit float
+	FormatFLAC               // 16/24bit（可逆圧縮）
+)
+
+func (f Format) String() string {
+	if f == FormatFLAC {
+		return "flac"
+	}
+	return "wav"
+}
+
+// ParseFormat は名前（"wav", "flac"）から形式を返す（空なら WAV）
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "wav", "wave":
+		return FormatWAV, nil
+	case "flac":
+		return FormatFLAC, nil
+	}
+	return 0, fmt.Errorf("unknown recording format: %q (wav or flac)", name)
+}
+
+// Options は録音の設定
+type Options struct {
+	Name        string        // ファイル名（recordings/ の下に作る。空なら set_日時.wav）。分割したら2つ目から _002, _003 … を付ける
+	Format      Format        // ファイル形式
+	BitDepth    int           // 16, 24（WAV のみ 32 = float も可）。0 なら 24
+	MaxBytes    int64         // 1ファイルの大きさの上限（0 なら分割しない）
+	MaxDuration time.Duration // 1ファイルの長さの上限（0 なら分割しない）
+}
+
+var (
+	ErrRecording    = errors.New("already recording")
+	ErrNotRecording = errors.New("not recording")
+	ErrInvalidName  = errors.New("recording name must be a file name without directories")
+)
+
+// Recorder はマスター出力の録音機
+type Recorder struct {
+	sampleRate int
+	dir        string // 録音ファイルを作るディレクトリ
+
+	mu      sync.Mutex              // Start / Stop を直列化する（オーディオスレッドは使わない）
+	active  atomic.Pointer[session] // 録音中のセッション（録音していなければ nil）
+	last    atomic.Pointer[session] // 最後に始めたセッション（停止後のステータス用）
+	writers atomic.Int32            // Write の途中のオーディオスレッドの数（Stop が待つ）
+}
+
+// NewRecorder は録音機を作成（sampleRate は Write に渡される音のレート）
+func NewRecorder(sampleRate int) *Recorder {
+	return &Recorder{sampleRate: sampleRate, dir: defaultDir}
+}
+
+// Start は録音を始める
+// 最初のファイルはここで作るので、書き出し先に問題があればすぐにエラーになる
+func (r *Recorder) Start(options Options) error {
+	options, err := normalizeOptions(options)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(r.dir, options.Name)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.active.Load() != nil {
+		return ErrRecording
+	}
+
+	s := &session{
+		options:    options,
+		path:       path,
+		sampleRate: r.sampleRate,
+		ring:       audio.NewRingBuffer(bufferSeconds * r.sampleRate * channels),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	if err := s.openNext(); err != nil {
+		return err
+	}
+
+	r.last.Store(s)
+	r.active.Store(s)
+	go s.run(r)
+
+	log.Printf("⏺️ Recording started: %s (%s, %dbit)", path, options.Format, options.BitDepth)
+	return nil
+}
+
+// Stop は録音を止め、残りを書き出してファイルを閉じる
+func (r *Recorder) Stop() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.active.Swap(nil)
+	if s == nil {
+		return ErrNotRecording
+	}
+	// 💡 Swap の前にセッションを取った Write がリングバッファに書き終わるのを待ってから、最後の書き出しをさせる
+	// （Write は短いので、すぐに 0 になる）
+	for r.writers.Load() != 0 {
+		runtime.Gosched()
+	}
+	close(s.stop)
+	<-s.done
+
+	log.Printf("⏹️ Recording stopped: %.1fs in %d file(s), %d buffer(s) dropped",
+		s.seconds(), len(s.fileList()), s.droppedBuffers.Load())
+	return s.getErr()
+}
+
+// IsRecording は録音中か
+func (r *Recorder) IsRecording() bool {
+	return r.active.Load() != nil
+}
+
+// Write はマスター出力（ステレオ・インターリーブ）を録音する（オーディオスレッドから呼ぶ）
+// 💡 ブロックしない。リングバッファに入りきらなければ、そのブロックは丸ごと捨てる
+func (r *Recorder) Write(samples []float32) {
+	r.writers.Add(1)
+	if s := r.active.Load(); s != nil {
+		if s.ring.Free() < len(samples) {
+			s.droppedBuffers.Add(1)
+			s.droppedFrames.Add(int64(len(samples) / channels))
+		} else {
+			s.ring.Write(samples)
+		}
+	}
+	r.writers.Add(-1)
+}
+
+// GetStatus は録音の状態を返す
+func (r *Recorder) GetStatus() map[string]interface{} {
+	s := r.last.Load()
+	if s == nil {
+		return map[string]interface{}{
+			"Recording": false,
+		}
+	}
+
+	files := s.fileList()
+	status := map[string]interface{}{
+		"Recording":      r.active.Load() == s,
+		"Format":         s.options.Format.String(),
+		"BitDepth":       s.options.BitDepth,
+		"Files":          files,
+		"Seconds":        s.seconds(),
+		"Bytes":          s.totalBytes(),
+		"DroppedBuffers": s.droppedBuffers.Load(),
+		"DroppedFrames":  s.droppedFrames.Load(),
+		"Error":          "",
+	}
+	if len(files) > 0 {
+		status["Path"] = files[len(files)-1]
+	}
+	if err := s.getErr(); err != nil {
+		status["Error"] = err.Error()
+	}
+	return status
+}
+
+// normalizeOptions は省略された設定を埋めて、値をチェックする
+func normalizeOptions(options Options) (Options, error) {
+	if options.BitDepth == 0 {
+		options.BitDepth = defaultBitDepth
+	}
+	switch {
+	case options.Format == FormatWAV && (options.BitDepth == 16 || options.BitDepth == 24 || options.BitDepth == 32):
+	case options.Format == FormatFLAC && (options.BitDepth == 16 || options.BitDepth == 24):
+	default:
+		return options, fmt.Errorf("unsupported bit depth for %s: %d", options.Format, options.BitDepth)
+	}
+
+	ext := "." + options.Format.String()
+	if options.Name == "" {
+		options.Name = "set_" + time.Now().Format("20060102_150405") + ext
+	} else if err := checkName(options.Name); err != nil {
+		return options, err
+	} else if filepath.Ext(options.Name) == "" {
+		options.Name += ext
+	}
+
+	if options.MaxBytes != 0 && options.MaxBytes < minSplitBytes {
+		return options, fmt.Errorf("split size must be at least %d bytes", minSplitBytes)
+	}
+	if options.MaxDuration != 0 && options.MaxDuration < minSplitDuration {
+		return options, fmt.Errorf("split duration must be at least %v", minSplitDuration)
+	}
+	return options, nil
+}
+
+// checkName は録音ファイルの名前が、ディレクトリを含まないただのファイル名かをチェックする
+// 💡 API から受け取った名前なので、絶対パスや .. で recordings/ の外に書かせない
+func checkName(name string) error {
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\:`) || filepath.Base(name) != name || filepath.IsAbs(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	return nil
+}
+
+// partPath は分割したファイルの名前（1つ目はそのまま、2つ目から set_002.wav のように番号を付ける）
+func partPath(path string, index int) string {
+	if index == 1 {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s_%03d%s", strings.TrimSuffix(path, ext), index, ext)
+}
+
+// encoder は録音ファイルの書き出し口（audio.WAVWriter / audio.FLACWriter）
+type encoder interface {
+	Write(samples []float32) error
+	Close() error
+}
+
+// countingFile は書き込んだバイト数を数えるファイル
+type countingFile struct {
+	*os.File
+	written *atomic.Int64
+}
+
+func (f countingFile) Write(p []byte) (int, error) {
+	n, err := f.File.Write(p)
+	f.written.Add(int64(n))
+	return n, err
+}
+
+// session は1回の録音（Start から Stop まで）
+type session struct {
+	options    Options
+	path       string // 1つ目のファイルのパス（recordings/ + Name）
+	sampleRate int
+	ring       *audio.RingBuffer
+	stop       chan struct{}
+	done       chan struct{}
+
+	// オーディオスレッドが数える
+	droppedBuffers atomic.Int64
+	droppedFrames  atomic.Int64
+
+	frames    atomic.Int64 // 書き出したフレーム数（全ファイルの合計）
+	fileBytes atomic.Int64 // 今のファイルに書いたバイト数
+
+	// 書き出しゴルーチンだけが触る
+	file       *os.File
+	enc        encoder
+	fileFrames int64
+
+	// ステータス用（API のスレッドからも読む）
+	mu          sync.Mutex
+	files       []string
+	closedBytes int64 // 閉じたファイルの合計サイズ
+	err         error
+}
+
+// run はリングバッファから取り出してファイルに書き出す（書き出しゴルーチン）
+func (s *session) run(r *Recorder) {
+	defer close(s.done)
+
+	buffer := make([]float32, 16384)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		stopping := false
+		select {
+		case <-s.stop:
+			stopping = true
+		case <-ticker.C:
+		}
+
+		for {
+			n := s.ring.Read(buffer)
+			if n == 0 {
+				break
+			}
+			if err := s.write(buffer[:n]); err != nil {
+				// 💡 書けなくなったら録音を止める（オーディオスレッドはもうリングバッファに入れない）
+				log.Printf("❌ Recording failed: %v", err)
+				s.setErr(err)
+				s.closeFile()
+				r.active.CompareAndSwap(s, nil)
+				return
+			}
+		}
+
+		if stopping {
+			if err := s.closeFile(); err != nil {
+				s.setErr(err)
+			}
+			return
+		}
+	}
+}
+
+// write はサンプルを書き出す（上限に達したら次のファイルに切り替える）
+func (s *session) write(samples []float32) error {
+	for len(samples) > 0 {
+		if s.enc == nil {
+			if err := s.openNext(); err != nil {
+				return err
+			}
+		}
+
+		n := min(int64(len(samples)/channels), s.framesLeft())
+		if n > 0 {
+			if err := s.enc.Write(samples[:n*channels]); err != nil {
+				return err
+			}
+			s.fileFrames += n
+			s.frames.Add(n)
+			samples = samples[n*channels:]
+		}
+
+		if s.full() {
+			if err := s.closeFile(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// maxBytes は1ファイルの大きさの上限（WAV は 4GB を超えられない）
+func (s *session) maxBytes() int64 {
+	limit := s.options.MaxBytes
+	if s.options.Format == FormatWAV && (limit == 0 || limit > audio.WAVMaxDataBytes) {
+		limit = audio.WAVMaxDataBytes
+	}
+	return limit
+}
+
+// frameBytes は1フレームのバイト数（FLAC は圧縮するので書いてみるまで分からない = 0）
+func (s *session) frameBytes() int64 {
+	if s.options.Format == FormatFLAC {
+		return 0
+	}
+	return int64(channels * s.options.BitDepth / 8)
+}
+
+// framesLeft は今のファイルにあと何フレーム書けるか
+func (s *session) framesLeft() int64 {
+	left := int64(math.MaxInt64)
+	if d := s.options.MaxDuration; d > 0 {
+		left = min(left, int64(d.Seconds()*float64(s.sampleRate))-s.fileFrames)
+	}
+	if limit, size := s.maxBytes(), s.frameBytes(); limit > 0 && size > 0 {
+		left = min(left, (limit-s.fileBytes.Load())/size)
+	}
+	return max(left, 0)
+}
+
+// full は今のファイルが上限に達したか
+func (s *session) full() bool {
+	if d := s.options.MaxDuration; d > 0 && s.fileFrames >= int64(d.Seconds()*float64(s.sampleRate)) {
+		return true
+	}
+	limit := s.maxBytes()
+	return limit > 0 && s.fileBytes.Load()+s.frameBytes() > limit
+}
+
+// openNext は次のファイルを作る
+func (s *session) openNext() error {
+	s.mu.Lock()
+	path := partPath(s.path, len(s.files)+1)
+	s.mu.Unlock()
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create recording directory: %v", err)
+		}
+	}
+	// 💡 同じ名前のファイルがあれば上書きせずにエラーにする（前の録音を消さない）
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("recording file %s: %w", path, os.ErrExist)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create recording file: %v", err)
+	}
+
+	s.fileBytes.Store(0)
+	w := countingFile{File: file, written: &s.fileBytes}
+	var enc encoder
+	if s.options.Format == FormatFLAC {
+		enc, err = audio.NewFLACWriter(w, s.sampleRate, channels, s.options.BitDepth)
+	} else {
+		enc, err = audio.NewWAVWriter(w, s.sampleRate, channels, s.options.BitDepth)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+
+	s.file = file
+	s.enc = enc
+	s.fileFrames = 0
+
+	s.mu.Lock()
+	s.files = append(s.files, path)
+	s.mu.Unlock()
+
+	log.Printf("📼 Recording to %s", path)
+	return nil
+}
+
+// closeFile は今のファイルのヘッダーを仕上げて閉じる
+func (s *session) closeFile() error {
+	if s.enc == nil {
+		return nil
+	}
+	err := s.enc.Close()
+	size := s.fileBytes.Load()
+	if info, statErr := s.file.Stat(); statErr == nil {
+		size = info.Size()
+	}
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	s.mu.Lock()
+	s.closedBytes += size
+	s.fileBytes.Store(0)
+	s.mu.Unlock()
+
+	s.file = nil
+	s.enc = nil
+	return err
+}
+
+func (s *session) seconds() float64 {
+	return float64(s.frames.Load()) / float64(s.sampleRate)
+}
+
+func (s *session) totalBytes() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closedBytes + s.fileBytes.Load()
+}
+
+func (s *session) fileList() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.files...)
+}
+
+func (s *session) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+func (s *session) getErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
@@ -0,0 +1,252 @@
+package recorder
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go_audio_engine/pkg/audio"
+)
+
+// testRate はテストの録音のレート（小さくして、分割の上限まで速く届くようにする）
+const testRate = 8000
+
+// newTestRecorder は dir に録音する録音機を作成
+func newTestRecorder(t *testing.T) *Recorder {
+	r := NewRecorder(testRate)
+	r.dir = t.TempDir()
+	return r
+}
+
+// testSignal は frames フレームのステレオの信号（左右で違う音、振幅 0.8 以下）
+func testSignal(frames int) []float32 {
+	samples := make([]float32, frames*2)
+	for i := 0; i < frames; i++ {
+		phase := 2 * math.Pi * float64(i) / testRate
+		samples[i*2] = float32(0.5*math.Sin(440*phase) + 0.3*math.Sin(3*phase))
+		samples[i*2+1] = float32(-0.6 * math.Sin(1000*phase))
+	}
+	return samples
+}
+
+// writeFromAudioThread は1つのゴルーチン（オーディオスレッドの代わり）から samples を 512 フレームずつ Write する
+// 💡 実時間を待たずに速く書くので、リングバッファに空きができるまで待ってから次のブロックを渡す（捨てさせない）
+func writeFromAudioThread(t *testing.T, r *Recorder, samples []float32) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s := r.active.Load()
+		for start := 0; start < len(samples); start += 512 * 2 {
+			block := samples[start:min(start+512*2, len(samples))]
+			for s.ring.Free() < len(block) {
+				time.Sleep(time.Millisecond)
+			}
+			r.Write(block)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("writing did not finish within 10 seconds")
+	}
+}
+
+// readFile は録音したファイルを読み込む
+func readFile(t *testing.T, path string) []float32 {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	stream, _, err := audio.OpenStream(path, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stream.SampleRate() != testRate || stream.Channels() != 2 {
+		t.Fatalf("%s: %d Hz %dch, want %d Hz 2ch", path, stream.SampleRate(), stream.Channels(), testRate)
+	}
+	samples, err := audio.ReadAll(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return samples
+}
+
+// compareSamples は量子化の誤差以内で一致するかを確認する
+// 💡 整数は 32767 倍で書いて 32768 で割って読むので、丸めと合わせて 2LSB まで許す（32bit float はそのまま）
+func compareSamples(t *testing.T, got, want []float32, bitDepth int) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%d frames, want %d", len(got)/2, len(want)/2)
+	}
+	tolerance := 0.0
+	if bitDepth < 32 {
+		tolerance = 2 / math.Pow(2, float64(bitDepth-1))
+	}
+	for i := range want {
+		if math.Abs(float64(got[i]-want[i])) > tolerance {
+			t.Fatalf("sample %d = %v, want %v (±%v)", i, got[i], want[i], tolerance)
+		}
+	}
+}
+
+// TestRecordDecodesBack は録音した WAV / FLAC を読み込むと、Write したサンプルが戻ってくることを確認する
+func TestRecordDecodesBack(t *testing.T) {
+	tests := []struct {
+		format   Format
+		bitDepth int
+	}{
+		{FormatWAV, 16},
+		{FormatWAV, 24},
+		{FormatWAV, 32},
+		{FormatFLAC, 16},
+		{FormatFLAC, 24},
+	}
+
+	want := testSignal(testRate * 3)
+	for _, tt := range tests {
+		r := newTestRecorder(t)
+		if err := r.Start(Options{Name: "set", Format: tt.format, BitDepth: tt.bitDepth}); err != nil {
+			t.Fatal(err)
+		}
+		if !r.IsRecording() {
+			t.Fatalf("%s %dbit: IsRecording = false after Start", tt.format, tt.bitDepth)
+		}
+		writeFromAudioThread(t, r, want)
+		if err := r.Stop(); err != nil {
+			t.Fatal(err)
+		}
+
+		status := r.GetStatus()
+		path := filepath.Join(r.dir, "set."+tt.format.String())
+		if files := status["Files"].([]string); len(files) != 1 || files[0] != path {
+			t.Fatalf("%s %dbit: files = %v, want [%s]", tt.format, tt.bitDepth, files, path)
+		}
+		if status["Recording"] != false || status["Seconds"] != 3.0 || status["DroppedBuffers"] != int64(0) {
+			t.Errorf("%s %dbit: status = %v", tt.format, tt.bitDepth, status)
+		}
+		compareSamples(t, readFile(t, path), want, tt.bitDepth)
+	}
+}
+
+// TestRecordSplits は長さ・大きさの上限で、_002, _003 … の決まった長さのファイルに分かれることを確認する
+func TestRecordSplits(t *testing.T) {
+	// 16bit WAV：ヘッダー 44 バイト + 1フレーム 4 バイトで MaxBytes を超えない長さ
+	bytesFrames := int((minSplitBytes - 44) / 4)
+
+	tests := []struct {
+		name    string
+		options Options
+		frames  int   // Write するフレーム数
+		want    []int // ファイルごとのフレーム数
+	}{
+		{"duration", Options{Name: "set.wav", BitDepth: 16, MaxDuration: time.Second}, testRate*2 + testRate/2, []int{testRate, testRate, testRate / 2}},
+		{"duration flac", Options{Name: "set.flac", Format: FormatFLAC, BitDepth: 24, MaxDuration: 1500 * time.Millisecond}, testRate * 3, []int{testRate * 3 / 2, testRate * 3 / 2}},
+		{"bytes", Options{Name: "set.wav", BitDepth: 16, MaxBytes: minSplitBytes}, bytesFrames*2 + 1000, []int{bytesFrames, bytesFrames, 1000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRecorder(t)
+			if err := r.Start(tt.options); err != nil {
+				t.Fatal(err)
+			}
+			want := testSignal(tt.frames)
+			writeFromAudioThread(t, r, want)
+			if err := r.Stop(); err != nil {
+				t.Fatal(err)
+			}
+
+			files := r.GetStatus()["Files"].([]string)
+			if len(files) != len(tt.want) {
+				t.Fatalf("files = %v, want %d files", files, len(tt.want))
+			}
+			var got []float32
+			for i, path := range files {
+				wantName := tt.options.Name
+				if i > 0 {
+					wantName = fmt.Sprintf("set_%03d%s", i+1, filepath.Ext(tt.options.Name))
+				}
+				if wantPath := filepath.Join(r.dir, wantName); path != wantPath {
+					t.Errorf("file %d = %s, want %s", i+1, path, wantPath)
+				}
+				samples := readFile(t, path)
+				if len(samples)/2 != tt.want[i] {
+					t.Errorf("%s: %d frames, want %d", filepath.Base(path), len(samples)/2, tt.want[i])
+				}
+				if info, err := os.Stat(path); err == nil && tt.options.MaxBytes > 0 && info.Size() > tt.options.MaxBytes {
+					t.Errorf("%s: %d bytes, want at most %d", filepath.Base(path), info.Size(), tt.options.MaxBytes)
+				}
+				got = append(got, samples...)
+			}
+			// 分かれ目で抜けたり重なったりしていない
+			compareSamples(t, got, want, tt.options.BitDepth)
+		})
+	}
+}
+
+// TestRecordDropsWhenFull は書き出しが追いつかずリングバッファがいっぱいのとき、Write がブロックせずに
+// 入りきらないブロックを捨てて、DroppedBuffers と DroppedFrames に数えることを確認する
+func TestRecordDropsWhenFull(t *testing.T) {
+	// 💡 書き出しゴルーチンを動かさないセッション（リングバッファは 1024 フレーム分で、誰も読まない）
+	r := NewRecorder(testRate)
+	s := &session{sampleRate: testRate, ring: audio.NewRingBuffer(1024 * 2)}
+	r.last.Store(s)
+	r.active.Store(s)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		block := make([]float32, 512*2)
+		for i := 0; i < 5; i++ {
+			r.Write(block)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Write blocked on a full ring buffer")
+	}
+
+	status := r.GetStatus()
+	if status["DroppedBuffers"] != int64(3) || status["DroppedFrames"] != int64(3*512) {
+		t.Errorf("dropped %v buffers / %v frames, want 3 / %d", status["DroppedBuffers"], status["DroppedFrames"], 3*512)
+	}
+	if got := s.ring.Available(); got != 1024*2 {
+		t.Errorf("ring holds %d samples, want the first 2 blocks (%d)", got, 1024*2)
+	}
+}
+
+// TestStartRejectsNames はディレクトリを含む名前と、もうあるファイルへの録音を断ることを確認する
+func TestStartRejectsNames(t *testing.T) {
+	r := newTestRecorder(t)
+	for _, name := range []string{"../set.wav", "/tmp/set.wav", "sub/set.wav", `..\set.wav`, `C:\set.wav`, "C:set.wav", "..", "."} {
+		if err := r.Start(Options{Name: name}); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("name %q: err = %v, want ErrInvalidName", name, err)
+		}
+	}
+	if r.IsRecording() {
+		t.Fatal("recording after rejected names")
+	}
+
+	// 前の録音を上書きしない
+	existing := filepath.Join(r.dir, "set.wav")
+	if err := os.WriteFile(existing, []byte("previous set"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Start(Options{Name: "set.wav"}); !errors.Is(err, os.ErrExist) {
+		t.Errorf("existing file: err = %v, want os.ErrExist", err)
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "previous set" {
+		t.Errorf("existing file was overwritten: %q", data)
+	}
+	if r.IsRecording() {
+		t.Error("recording after the existing file was rejected")
+	}
+}
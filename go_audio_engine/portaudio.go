@@ -0,0 +1,326 @@
+//go:build !headless
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"go_audio_engine/pkg/audio"
+	"go_audio_engine/pkg/output"
+
+	"github.com/gordonklaus/portaudio"
+)
+
+// portAudioBackend はサウンドカードに出す出力バックエンド
+// 💡 コールバックはこの構造体だけを見るので、設定を変えるときは丸ごと作り直す
+type portAudioBackend struct {
+	config    StreamConfig
+	mixerRate int
+
+	device    *portaudio.DeviceInfo
+	stream    *portaudio.Stream
+	channels  int               // メインのストリームのチャンネル数（2 または 4）
+	cueStream *portaudio.Stream // 別デバイスに出すときのストリーム
+	cueRing   *audio.RingBuffer // メイン → キューのストリームへ音声を渡す
+	converter *rateConverter    // デバイスのレートがミキサーと違うときの変換（同じなら nil）
+	render    output.RenderFunc
+
+	masterBuffer []float32 // 4ch のときのマスター（ステレオ）
+	cueBuffer    []float32 // キュー（ステレオ）
+}
+
+// newPortAudioBackend はサウンドカード出力を作成（Start で開く）
+// mixerRate はミキサーの処理レート（デバイスのレートと違えば出力の直前で変換する）
+func newPortAudioBackend(config StreamConfig, mixerRate int) *portAudioBackend {
+	return &portAudioBackend{
+		config:    config,
+		mixerRate: mixerRate,
+		channels:  channels,
+	}
+}
+
+// initPortAudio は PortAudio を初期化する（サウンドカードに出すときだけ）
+func initPortAudio() error {
+	if err := portaudio.Initialize(); err != nil {
+		return fmt.Errorf("failed to initialize PortAudio: %v", err)
+	}
+	return nil
+}
+
+// terminatePortAudio は PortAudio を終了する
+func terminatePortAudio() {
+	portaudio.Terminate()
+}
+
+// listDevices は /api/devices で返すデバイスの一覧
+func listDevices() ([]map[string]interface{}, error) {
+	devices, err := portaudio.Devices()
+	if err != nil {
+		return nil, err
+	}
+
+	defaultOutput, _ := portaudio.DefaultOutputDevice()
+
+	deviceList := make([]map[string]interface{}, len(devices))
+	for i, device := range devices {
+		deviceList[i] = map[string]interface{}{
+			"id":                i,
+			"name":              device.Name,
+			"maxInputChannels":  device.MaxInputChannels,
+			"maxOutputChannels": device.MaxOutputChannels,
+			"defaultSampleRate": device.DefaultSampleRate,
+			"isDefaultOutput":   device == defaultOutput,
+		}
+	}
+	return deviceList, nil
+}
+
+// outputDevice は番号から出力デバイスを探す（-1 ならデフォルトのデバイス）
+func outputDevice(index int) (*portaudio.DeviceInfo, error) {
+	if index < 0 {
+		device, err := portaudio.DefaultOutputDevice()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get default output device: %v", err)
+		}
+		return device, nil
+	}
+
+	devices, err := portaudio.Devices()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %v", err)
+	}
+	if index >= len(devices) {
+		return nil, fmt.Errorf("device %d does not exist", index)
+	}
+	device := devices[index]
+	if device.MaxOutputChannels < channels {
+		return nil, fmt.Errorf("device %q has no stereo output", device.Name)
+	}
+	return device, nil
+}
+
+// streamParameters は出力ストリームのパラメーターを作る
+// 💡 以前の OpenDefaultStream と同じく、余裕のある（High）レイテンシーで開く
+func streamParameters(device *portaudio.DeviceInfo, numChannels int, config StreamConfig) portaudio.StreamParameters {
+	p := portaudio.HighLatencyParameters(nil, device)
+	p.Output.Channels = numChannels
+	p.SampleRate = float64(config.SampleRate)
+	p.FramesPerBuffer = config.FramesPerBuffer
+	return p
+}
+
+// Start は設定どおりにストリームを開いて再生を始める
+func (b *portAudioBackend) Start(render output.RenderFunc) error {
+	config := b.config
+	if err := validateStreamConfig(config); err != nil {
+		return err
+	}
+	device, err := outputDevice(config.Device)
+	if err != nil {
+		return err
+	}
+
+	b.device = device
+	b.render = render
+	b.masterBuffer = make([]float32, config.FramesPerBuffer*2)
+	b.cueBuffer = make([]float32, config.FramesPerBuffer*2)
+
+	// 💡 ヘッドフォンは「同じデバイスの 3/4ch」か「別のデバイス」のどちらか
+	if config.CueQuad {
+		if device.MaxOutputChannels < 4 {
+			return fmt.Errorf("device %q has only %d output channels (4 required for cue on 3/4)", device.Name, device.MaxOutputChannels)
+		}
+		b.channels = 4
+	}
+
+	if config.SampleRate != b.mixerRate {
+		b.converter = newRateConverter(b.mixerRate, config.SampleRate, config.FramesPerBuffer)
+	}
+
+	stream, err := portaudio.OpenStream(streamParameters(device, b.channels, config), b.process)
+	if err != nil {
+		return fmt.Errorf("failed to open stream on %q: %v", device.Name, err)
+	}
+	b.stream = stream
+
+	if config.CueDevice >= 0 {
+		if err := b.openCueDevice(config.CueDevice); err != nil {
+			stream.Close()
+			b.stream = nil
+			return err
+		}
+	}
+
+	if err := stream.Start(); err != nil {
+		b.Stop()
+		return fmt.Errorf("failed to start stream: %v", err)
+	}
+	if b.cueStream != nil {
+		if err := b.cueStream.Start(); err != nil {
+			b.Stop()
+			return fmt.Errorf("failed to start cue stream: %v", err)
+		}
+	}
+
+	log.Printf("🔊 Output: %s (%d Hz, %d frames, %dch)", device.Name, config.SampleRate, config.FramesPerBuffer, b.channels)
+	return nil
+}
+
+// openCueDevice はヘッドフォン用に別のデバイスのストリームを開く
+// 解説：デバイスごとにコールバックが別々に呼ばれるので、メインのコールバックで作ったキューを
+// リングバッファで渡す（足りなければ無音、あふれたら捨てる）
+func (b *portAudioBackend) openCueDevice(index int) error {
+	device, err := outputDevice(index)
+	if err != nil {
+		return fmt.Errorf("cue device: %v", err)
+	}
+
+	// 💡 バッファ4つ分の余裕（デバイス間のタイミングのずれを吸収する）
+	b.cueRing = audio.NewRingBuffer(b.config.FramesPerBuffer * 2 * 4)
+
+	stream, err := portaudio.OpenStream(streamParameters(device, channels, b.config), func(out []float32) {
+		n := b.cueRing.Read(out)
+		for i := n; i < len(out); i++ {
+			out[i] = 0
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open cue stream on %q: %v", device.Name, err)
+	}
+
+	b.cueStream = stream
+	log.Printf("🎧 Cue output on device %d: %s", index, device.Name)
+	return nil
+}
+
+// Stop はストリームを止めて閉じる
+func (b *portAudioBackend) Stop() error {
+	var err error
+	if b.stream != nil {
+		b.stream.Stop()
+		err = b.stream.Close()
+		b.stream = nil
+	}
+	if b.cueStream != nil {
+		b.cueStream.Stop()
+		b.cueStream.Close()
+		b.cueStream = nil
+	}
+	return err
+}
+
+// Status は出力ストリームの状態を返す
+func (b *portAudioBackend) Status() map[string]interface{} {
+	status := map[string]interface{}{
+		"backend":         "portaudio",
+		"device":          b.config.Device,
+		"sampleRate":      b.config.SampleRate,
+		"framesPerBuffer": b.config.FramesPerBuffer,
+		"channels":        b.channels,
+		"cueQuad":         b.config.CueQuad,
+		"cueDevice":       b.config.CueDevice,
+		"resampling":      b.converter != nil,
+	}
+	if b.device != nil {
+		status["deviceName"] = b.device.Name
+	}
+	if b.stream != nil {
+		if info := b.stream.Info(); info != nil {
+			status["latencyMs"] = float64(info.OutputLatency) / float64(time.Millisecond)
+		}
+	}
+	return status
+}
+
+// process はメインのストリームのコールバック
+func (b *portAudioBackend) process(out []float32) {
+	switch {
+	case b.channels == 4:
+		// 1/2ch にマスター、3/4ch にキュー
+		frames := len(out) / 4
+		master, cue := b.buffers(frames)
+		b.renderBlock(master, cue)
+		for i := 0; i < frames; i++ {
+			out[4*i], out[4*i+1] = master[2*i], master[2*i+1]
+			out[4*i+2], out[4*i+3] = cue[2*i], cue[2*i+1]
+		}
+	case b.cueRing != nil:
+		_, cue := b.buffers(len(out) / 2)
+		b.renderBlock(out, cue)
+		b.cueRing.Write(cue)
+	default:
+		b.renderBlock(out, nil)
+	}
+}
+
+// renderBlock はミキサーから master と cue（nil なら作らない）を取り出す
+func (b *portAudioBackend) renderBlock(master, cue []float32) {
+	if b.converter != nil {
+		b.converter.render(b.render, master, cue)
+		return
+	}
+	b.render(master, cue)
+}
+
+// buffers は frames フレーム分のマスター・キュー用バッファを返す
+// 💡 通常は最初に確保した分で足りる（コールバックの中で毎回確保しない）
+func (b *portAudioBackend) buffers(frames int) (master, cue []float32) {
+	if cap(b.masterBuffer) < frames*2 {
+		b.masterBuffer = make([]float32, frames*2)
+		b.cueBuffer = make([]float32, frames*2)
+	}
+	return b.masterBuffer[:frames*2], b.cueBuffer[:frames*2]
+}
+
+// rateConverter はミキサーのレートで作った音をデバイスのレートに変換する
+// 解説：デッキの曲はミキサーのレートでメモリに載っているので、デバイスのレートを変えても
+// 曲を読み込み直さずに済むよう、出力の直前で変換する
+type rateConverter struct {
+	master, cue *audio.Resampler
+
+	mixMaster, mixCue         []float32 // ミキサーから取り出す1ブロック
+	pendingMaster, pendingCue []float32 // 変換済みでまだ出していない分
+}
+
+// newRateConverter は変換器を作成
+// 💡 コールバックの中でメモリを確保しないよう、変換器の履歴と変換済みの分の容量はここで確保しておく
+func newRateConverter(fromRate, toRate, framesPerBuffer int) *rateConverter {
+	block := min(framesPerBuffer*fromRate/toRate+1, audio.MaxBlockFrames)
+	c := &rateConverter{
+		master:    audio.NewResampler(fromRate, toRate, channels),
+		cue:       audio.NewResampler(fromRate, toRate, channels),
+		mixMaster: make([]float32, block*2),
+		mixCue:    make([]float32, block*2),
+	}
+	c.master.Reserve(block)
+	c.cue.Reserve(block)
+
+	// 足りない間だけミキサーを回すので、残りは「1ブロック弱 + 1回の変換で出る分」を超えない
+	pending := (framesPerBuffer + c.master.MaxOutput(block)) * channels
+	c.pendingMaster = make([]float32, 0, pending)
+	c.pendingCue = make([]float32, 0, pending)
+	return c
+}
+
+// render は master（と cue）が埋まるまでミキサーを回して変換する
+func (c *rateConverter) render(render output.RenderFunc, master, cue []float32) {
+	for len(c.pendingMaster) < len(master) {
+		if cue != nil {
+			render(c.mixMaster, c.mixCue)
+			c.pendingCue = c.cue.Process(c.mixCue, c.pendingCue)
+		} else {
+			render(c.mixMaster, nil)
+		}
+		c.pendingMaster = c.master.Process(c.mixMaster, c.pendingMaster)
+	}
+
+	// 💡 マスターとキューは同じ長さずつ変換しているので、残りの長さも常に同じ
+	copy(master, c.pendingMaster)
+	c.pendingMaster = c.pendingMaster[:copy(c.pendingMaster, c.pendingMaster[len(master):])]
+	if cue != nil {
+		copy(cue, c.pendingCue)
+		c.pendingCue = c.pendingCue[:copy(c.pendingCue, c.pendingCue[len(cue):])]
+	}
+}
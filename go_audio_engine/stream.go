@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+
+	"go_audio_engine/pkg/audio"
+)
+
+// 出力ストリームに指定できる範囲
+const (
+	minSampleRate      = 8000
+	maxSampleRate      = 192000
+	minFramesPerBuffer = 16
+	maxFramesPerBuffer = audio.MaxBlockFrames // これより長いとミキサーの作業バッファに入りきらない
+)
+
+// StreamConfig は出力ストリームの設定（実行中に /api/output で変更できる）
+type StreamConfig struct {
+	Device          int  `json:"device"`          // 出力デバイスの番号（/api/devices の id、-1 ならデフォルト）
+	SampleRate      int  `json:"sampleRate"`      // デバイスのサンプルレート
+	FramesPerBuffer int  `json:"framesPerBuffer"` // 1回のコールバックのフレーム数
+	CueQuad         bool `json:"cueQuad"`         // 4ch で開き、3/4ch にヘッドフォン（キュー）を出す
+	CueDevice       int  `json:"cueDevice"`       // ヘッドフォン用の別デバイスの番号（-1 なら使わない）
+}
+
+// validateStreamConfig は出力ストリームの設定をチェックする
+func validateStreamConfig(config StreamConfig) error {
+	if config.SampleRate < minSampleRate || config.SampleRate > maxSampleRate {
+		return fmt.Errorf("sample rate %d out of range (%d-%d)", config.SampleRate, minSampleRate, maxSampleRate)
+	}
+	if config.FramesPerBuffer < minFramesPerBuffer || config.FramesPerBuffer > maxFramesPerBuffer {
+		return fmt.Errorf("frames per buffer %d out of range (%d-%d)", config.FramesPerBuffer, minFramesPerBuffer, maxFramesPerBuffer)
+	}
+	if config.CueQuad && config.CueDevice >= 0 {
+		return fmt.Errorf("cueQuad and cueDevice cannot be used together")
+	}
+	return nil
+}